@@ -297,16 +297,6 @@ type PairX3 = struct { // unnamed!
 	Second interface{}
 }
 
-type ListX2 = struct { // unnamed!
-	First error
-	Rest  xr.Forward
-}
-
-type ListX3 = struct { // unnamed!
-	First interface{}
-	Rest  xr.Forward
-}
-
 type TagPair = struct { // unnamed!
 	A rune   `json:"foo"`
 	B string `json:"bar"`
@@ -340,7 +330,8 @@ var testcases = []TestCase{
 	TestCase{A, "1+1", "1+1", 1 + 1, nil},
 	TestCase{A, "1+'A'", "1+'A'", 'B', nil}, // rune i.e. int32 should win over untyped constant (or int)
 	TestCase{A, "int8+1", "int8(1)+1", int8(1) + 1, nil},
-	TestCase{A, "int8_overflow", "int8(64)+64", int8(-128), nil},
+	TestCase{C, "int8_overflow", "int8(64)+64", int8(-128), nil}, // classic interpreter does not detect constant overflow
+	TestCase{F, "int8_overflow", "int8(64)+64", panics, nil},
 	TestCase{A, "string", "\"foobar\"", "foobar", nil},
 	TestCase{A, "expr_and", "3 & 6", 3 & 6, nil},
 	TestCase{A, "expr_or", "7 | 8", 7 | 8, nil},
@@ -480,9 +471,7 @@ var testcases = []TestCase{
 	TestCase{A, "field_get_1", "pair.A", rune(0), nil},
 	TestCase{A, "field_get_2", "pair.B", "", nil},
 	TestCase{F, "field_anonymous_1", "triple.Pair", Pair{}, nil},
-	TestCase{F, "field_anonymous_2", "type Z struct { *Z }; Z{}", struct {
-		Z xr.Forward
-	}{}, nil},
+	TestCase{F, "field_anonymous_2", "type Z struct { *Z }; Z{}.Z == nil", true, nil},
 	TestCase{F, "field_embedded_1", "triple.A", rune(0), nil},
 	TestCase{F, "field_embedded_2", "triple.B", "", nil},
 	TestCase{F, "field_embedded_3", "triple.Pair.A", rune(0), nil},
@@ -490,7 +479,7 @@ var testcases = []TestCase{
 	TestCase{F, "field_embedded_5", "tp.A", panics, nil},
 	TestCase{F, "field_embedded_6", "tp.Pair = &triple.Pair; tp.B", "", nil},
 
-	TestCase{F, "self_embedded_1", "type X struct { *X }; X{}.X", (xr.Forward)(nil), nil},
+	TestCase{F, "self_embedded_1", "type X struct { *X }; x1 := X{&X{}}; x1.X.X == nil", true, nil},
 	TestCase{F, "self_embedded_2", "var x X; x.X = &x; x.X.X.X.X.X.X.X.X == &x", true, nil},
 	TestCase{F, "self_embedded_3", "x.X.X.X == x.X.X.X.X.X", true, nil},
 
@@ -1190,8 +1179,8 @@ var testcases = []TestCase{
 
 	TestCase{F, "recursive_template_type_1", `
 		template[T] type ListX struct { First T; Rest *ListX#[T] }
-		var lx ListX#[error]; lx`, ListX2{}, nil},
-	TestCase{F, "recursive_template_type_2", `ListX#[interface{}]{}`, ListX3{}, nil},
+		var lx ListX#[error]; lx.Rest == nil`, true, nil},
+	TestCase{F, "recursive_template_type_2", `ListX#[interface{}]{Rest: &ListX#[interface{}]{First: 7}}.Rest.First`, 7, nil},
 
 	TestCase{F, "specialized_template_type_1", `
 		template[] for[struct{}] type ListX struct { }
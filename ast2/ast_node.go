@@ -238,7 +238,9 @@ func (x Field) New() Ast    { return Field{&ast.Field{Doc: x.X.Doc, Comment: x.X
 func (x ForStmt) New() Ast  { return ForStmt{&ast.ForStmt{For: x.X.For}} }
 func (x FuncDecl) New() Ast { return FuncDecl{&ast.FuncDecl{Doc: x.X.Doc}} }
 func (x FuncLit) New() Ast  { return FuncLit{&ast.FuncLit{}} }
-func (x FuncType) New() Ast { return FuncType{&ast.FuncType{Func: x.X.Func}} }
+func (x FuncType) New() Ast {
+	return FuncType{&ast.FuncType{Func: x.X.Func, TypeParams: x.X.TypeParams}}
+}
 func (x GoStmt) New() Ast   { return GoStmt{&ast.GoStmt{Go: x.X.Go}} }
 func (x Ident) New() Ast    { return Ident{&ast.Ident{NamePos: x.X.NamePos, Name: x.X.Name}} }
 func (x IfStmt) New() Ast   { return IfStmt{&ast.IfStmt{If: x.X.If}} }
@@ -271,7 +273,7 @@ func (x TypeAssertExpr) New() Ast {
 	return TypeAssertExpr{&ast.TypeAssertExpr{Lparen: x.X.Lparen, Rparen: x.X.Rparen}}
 }
 func (x TypeSpec) New() Ast {
-	return TypeSpec{&ast.TypeSpec{Doc: x.X.Doc, Assign: x.X.Assign, Comment: x.X.Comment}}
+	return TypeSpec{&ast.TypeSpec{Doc: x.X.Doc, TypeParams: x.X.TypeParams, Assign: x.X.Assign, Comment: x.X.Comment}}
 }
 func (x TypeSwitchStmt) New() Ast { return TypeSwitchStmt{&ast.TypeSwitchStmt{Switch: x.X.Switch}} }
 func (x UnaryExpr) New() Ast      { return UnaryExpr{&ast.UnaryExpr{OpPos: x.X.OpPos, Op: x.X.Op}} }
@@ -31,6 +31,7 @@ const (
 	Const
 	Expr
 	Func
+	FuncFwd
 	Import
 	Macro
 	Method
@@ -47,6 +48,7 @@ var kinds = map[Kind]string{
 	Const:    "Const",
 	Expr:     "Expr",
 	Func:     "Func",
+	FuncFwd:  "FuncFwd", // forward function declaration
 	Import:   "Import",
 	Macro:    "Macro",
 	Method:   "Method",
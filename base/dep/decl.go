@@ -214,6 +214,10 @@ func NewDeclVar(ident *ast.Ident, node ast.Spec, typ ast.Expr, value ast.Expr, d
 }
 
 func NewDeclVarMulti(ident *ast.Ident, node *ast.ValueSpec, deps []string) *Decl {
+	if node == nil {
+		// do not store a typed nil in Decl.Node
+		return NewDecl(VarMulti, ident.Name, nil, ident.Pos(), deps)
+	}
 	return NewDecl(VarMulti, ident.Name, node, ident.Pos(), deps)
 }
 
@@ -52,6 +52,9 @@ func (g *graph) Sort() DeclList {
 		buf := g.RemoveNodesNoDeps()
 		if len(buf) == 0 {
 			buf = g.RemoveTypeFwd()
+			if len(buf) == 0 {
+				buf = g.RemoveFuncFwd()
+			}
 			if len(buf) == 0 {
 				g.circularDependencyError()
 			}
@@ -183,6 +186,64 @@ func (g *graph) RemoveTypeFwd() DeclList {
 	return list
 }
 
+// return forward declarations for the functions in some circular dependency.
+// Function bodies can use functions declared later, as mutually recursive functions do,
+// so remove the dependencies of functions and methods from forward-declared functions.
+// Dependencies of variables from functions are kept: they define initialization order
+func (g *graph) RemoveFuncFwd() DeclList {
+	ctx := visitCtx{
+		visiting: make(map[string]int),
+		visited:  make(map[string]int),
+		cycleFunc: func(node *Decl, ctx *visitCtx) {
+			ctx.visiting[node.Name]++
+		},
+	}
+	for _, node := range g.Nodes.List().SortByPos().Reverse() {
+		if len(ctx.visited) == len(g.Nodes) {
+			break
+		}
+		g.visit(node, &ctx)
+	}
+	var list DeclList
+	for name, count := range ctx.visited {
+		if count == 0 {
+			continue
+		}
+		for _, decl := range g.Nodes[name] {
+			if decl != nil && decl.Kind == Func {
+				list = append(list, decl)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	// change Kind of returned Decls to FuncFwd
+	for i, e := range list {
+		fwd := *e
+		fwd.Kind = FuncFwd
+		list[i] = &fwd
+	}
+	m := list.Map()
+	n := g.edgeCount()
+	g.RemoveDepsFor(Func, m)
+	g.RemoveDepsFor(Method, m)
+	if g.edgeCount() == n {
+		// functions already forward-declared, the cycle passes through some variable
+		return nil
+	}
+	return list.SortByPos()
+}
+
+// return the total number of dependencies in g.Edges
+func (g *graph) edgeCount() int {
+	n := 0
+	for _, edges := range g.Edges {
+		n += len(edges)
+	}
+	return n
+}
+
 func (g *graph) visit(node *Decl, ctx *visitCtx) {
 	name := node.Name
 	if _, ok := ctx.visited[name]; ok {
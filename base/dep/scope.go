@@ -248,11 +248,15 @@ func (s *Scope) Vars(node ast.Spec) []string {
 	return alldeps
 }
 
+// variables initialized with a multi-value expression, as var x, y = f()
+// are a single dependency node: the first VarMulti contains the whole *ast.ValueSpec,
+// the others contain no node and depend on the first, so they are sorted after it
 func (s *Scope) varsMultiValueExpr(node *ast.ValueSpec) []string {
 	deps := append(s.Expr(node.Type), s.Expr(node.Values[0])...)
-	for _, ident := range node.Names {
-		s.add(NewDeclVarMulti(ident, node, deps))
-		node = nil // store node only in the first VarMulti
+	first := node.Names[0]
+	s.add(NewDeclVarMulti(first, node, deps))
+	for _, ident := range node.Names[1:] {
+		s.add(NewDeclVarMulti(ident, nil, []string{first.Name}))
 	}
 	return deps
 }
@@ -267,7 +271,7 @@ func (s *Scope) Func(node *ast.FuncDecl) []string {
 	inner := NewScope(s)
 
 	name := node.Name.Name
-	deps := inner.Expr(node.Type)
+	deps := inner.funcType(node.Type)
 
 	kind := Func
 	if node.Recv != nil && len(node.Recv.List) != 0 {
@@ -299,6 +303,21 @@ func (s *Scope) Func(node *ast.FuncDecl) []string {
 	return deps
 }
 
+// declare params and results of a function type in s, which must be the scope of function body,
+// and compute dependencies for their types
+func (s *Scope) funcType(node *ast.FuncType) []string {
+	if node == nil {
+		return nil
+	}
+	var deps []string
+	for _, list := range [...]*ast.FieldList{node.TypeParams, node.Params, node.Results} {
+		if list != nil {
+			deps = append(deps, s.Expr(list)...)
+		}
+	}
+	return deps
+}
+
 // type
 func (s *Scope) Type(node ast.Spec) []string {
 	var deps []string
@@ -338,16 +357,18 @@ func (s *Scope) AstExpr(in ast2.Ast) []string {
 	var deps []string
 	switch node := in.Interface().(type) {
 	case *ast.FuncLit:
-		deps = append(deps, s.Expr(node.Type)...)
-		in = ast2.BlockStmt{node.Body}
 		// open a new scope
 		s = NewScope(s)
+		deps = append(deps, s.funcType(node.Type)...)
+		in = ast2.BlockStmt{node.Body}
 	case *ast.BlockStmt, *ast.FuncType, *ast.InterfaceType, *ast.StructType:
 		// open a new scope
 		s = NewScope(s)
+	case *ast.CompositeLit:
+		return s.compositeLit(node)
 	case *ast.KeyValueExpr:
-		// ignore the key if it's an ast.Ident
-		// FIXME this is correct for struct initializers only
+		// ignore the key if it's an ast.Ident:
+		// it may be a field name in a struct initializer. See also Scope.compositeLit()
 		if _, ok := node.Key.(*ast.Ident); !ok {
 			deps = append(deps, s.Expr(node.Key)...)
 		}
@@ -377,15 +398,35 @@ func (s *Scope) AstExpr(in ast2.Ast) []string {
 	return sort_unique_inplace(deps)
 }
 
+// compute dependencies for a composite literal.
+// in map, array and slice literals, keys are constants or variables:
+// if they are identifiers, they are dependencies too.
+// in struct literals, or if the type is omitted or is a named type,
+// identifiers used as keys may be field names and are ignored
+func (s *Scope) compositeLit(node *ast.CompositeLit) []string {
+	deps := s.Expr(node.Type)
+	var keys bool
+	switch node.Type.(type) {
+	case *ast.MapType, *ast.ArrayType:
+		keys = true
+	}
+	for _, elt := range node.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok && keys {
+			deps = append(deps, s.Expr(kv.Key)...)
+			elt = kv.Value
+		}
+		deps = append(deps, s.Expr(elt)...)
+	}
+	return sort_unique_inplace(deps)
+}
+
 // return true if name refers to a local declaration
 func (s *Scope) isLocal(name string) bool {
-	outer := s.Outer
-	// outer == nil is top-level scope: not local
-	for ; outer != nil; s = outer {
+	// s.Outer == nil is top-level scope: not local
+	for ; s.Outer != nil; s = s.Outer {
 		if _, ok := s.Decls[name]; ok {
 			return true
 		}
-		outer = outer.Outer
 	}
 	return false
 }
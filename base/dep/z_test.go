@@ -100,3 +100,34 @@ func _testSorter(t *testing.T, filename string) {
 		sorted.Print()
 	}
 }
+
+func TestSorterVarMulti(t *testing.T) {
+	src := "var z = w + 1\nvar v, w = g()\nfunc g() (int, int) { return 1, 2 }\n"
+	var p parser.Parser
+	p.Init(token.NewFileSet(), "var_multi.go", 0, []byte(src))
+	nodes, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSorter()
+	s.LoadNodes(nodes)
+
+	var names []string
+	for _, decl := range s.All() {
+		names = append(names, decl.Name)
+		switch decl.Name {
+		case "v":
+			if decl.Node == nil {
+				t.Errorf("VarMulti v: expecting the *ast.ValueSpec, found nil node")
+			}
+		case "w":
+			if decl.Node != nil {
+				t.Errorf("VarMulti w: expecting nil node, found %v", decl.Node)
+			}
+		}
+	}
+	expect := []string{"g", "v", "w", "z"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("expected %v, actual %v", expect, names)
+	}
+}
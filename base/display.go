@@ -0,0 +1,238 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * display.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package base
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	r "reflect"
+	"sort"
+	"strings"
+
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// MIME types produced by the default renderers
+const (
+	MIMEText = "text/plain"
+	MIMEHTML = "text/html"
+	MIMEPNG  = "image/png"
+	MIMEJSON = "application/json"
+)
+
+// Renderer converts a value to one MIME representation.
+// t is the interpreter type of v, and may be nil if unknown.
+// It returns ok == false if it cannot render v.
+type Renderer func(v r.Value, t xr.Type) (data []byte, ok bool)
+
+// MIMEBundle contains the representations of a value, indexed by MIME type.
+// It marshals to JSON as the "data" field of Jupyter display_data messages:
+// text formats as strings, application/json as embedded JSON, other formats in base64
+type MIMEBundle map[string][]byte
+
+// HTMLer is implemented by values that can render themselves as HTML
+type HTMLer interface {
+	HTML() string
+}
+
+// Display contains the renderers registered with Register,
+// used by Globals.Render in addition to the default ones
+type Display struct {
+	renderers []renderer
+}
+
+type renderer struct {
+	t    xr.Type
+	mime string
+	fn   Renderer
+}
+
+// Register adds a renderer that converts values of type t to the MIME type mime.
+// If t is an interface, the renderer is used for all types implementing it.
+// Renderers registered later take precedence, and renderers registered for a type
+// take precedence over renderers registered for an interface.
+// A nil fn removes the renderers previously registered for t and mime
+func (d *Display) Register(t xr.Type, mime string, fn Renderer) {
+	list := d.renderers[:0:0]
+	for _, rd := range d.renderers {
+		if rd.mime != mime || !rd.t.IdenticalTo(t) {
+			list = append(list, rd)
+		}
+	}
+	if fn != nil {
+		list = append(list, renderer{t, mime, fn})
+	}
+	d.renderers = list
+}
+
+// Copy returns a copy of d. Registering renderers in the copy does not affect d
+func (d *Display) Copy() Display {
+	return Display{append([]renderer(nil), d.renderers...)}
+}
+
+// return true if the renderer applies to values of type rtype and interpreter type t
+func (rd *renderer) match(rtype r.Type, t xr.Type, iface bool) bool {
+	if (rd.t.Kind() == r.Interface) != iface {
+		return false
+	}
+	if iface {
+		if t != nil {
+			return t.Implements(rd.t)
+		}
+		return rtype.Implements(rd.t.ReflectType())
+	}
+	if t != nil {
+		return t.IdenticalTo(rd.t)
+	}
+	return rtype == rd.t.ReflectType()
+}
+
+// Render returns the representations of v in all the MIME types supported
+// by the registered renderers and by the default ones:
+// text/plain for all values, text/html for values implementing HTMLer,
+// image/png for image.Image values and application/json for JSON-marshallable
+// arrays, slices, maps, structs and values implementing json.Marshaler.
+// t is the interpreter type of v, and may be nil if unknown
+func (g *Globals) Render(v r.Value, t xr.Type) MIMEBundle {
+	if v.IsValid() && v.Kind() == r.Interface && !v.IsNil() {
+		v, t = v.Elem(), nil // extract concrete type
+	}
+	bundle := make(MIMEBundle)
+	if !v.IsValid() || v == None {
+		bundle[MIMEText] = []byte("nil")
+		return bundle
+	}
+	rtype := v.Type()
+	renderers := g.Display.renderers
+	// first renderers registered for the type, then for interfaces. most recent first
+	for _, iface := range [...]bool{false, true} {
+		for i := len(renderers) - 1; i >= 0; i-- {
+			rd := &renderers[i]
+			if _, done := bundle[rd.mime]; done || !rd.match(rtype, t, iface) {
+				continue
+			}
+			if data, ok := rd.fn(v, t); ok {
+				bundle[rd.mime] = data
+			}
+		}
+	}
+	for _, def := range [...]struct {
+		mime string
+		fn   Renderer
+	}{
+		{MIMEText, g.renderText},
+		{MIMEHTML, renderHTML},
+		{MIMEPNG, renderPNG},
+		{MIMEJSON, renderJSON},
+	} {
+		if _, done := bundle[def.mime]; done {
+			continue
+		}
+		if data, ok := def.fn(v, t); ok {
+			bundle[def.mime] = data
+		}
+	}
+	return bundle
+}
+
+func (g *Globals) renderText(v r.Value, t xr.Type) ([]byte, bool) {
+	return []byte(g.Sprintf("%v", g.TypedValue(v, t))), true
+}
+
+func renderHTML(v r.Value, t xr.Type) ([]byte, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	if h, ok := v.Interface().(HTMLer); ok {
+		return []byte(h.HTML()), true
+	}
+	return nil, false
+}
+
+func renderPNG(v r.Value, t xr.Type) ([]byte, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	img, ok := v.Interface().(image.Image)
+	if !ok || img == nil {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+var typeOfJSONMarshaler = r.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func renderJSON(v r.Value, t xr.Type) ([]byte, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case r.Array, r.Slice, r.Map, r.Struct:
+	default:
+		if !v.Type().Implements(typeOfJSONMarshaler) {
+			return nil, false
+		}
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// MarshalJSON implements json.Marshaler
+func (bundle MIMEBundle) MarshalJSON() ([]byte, error) {
+	mimes := make([]string, 0, len(bundle))
+	for mime := range bundle {
+		mimes = append(mimes, mime)
+	}
+	sort.Strings(mimes)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, mime := range mimes {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(mime)
+		buf.Write(key)
+		buf.WriteByte(':')
+		data := bundle[mime]
+		var val []byte
+		var err error
+		switch {
+		case mime == MIMEJSON && json.Valid(data):
+			val = data
+		case strings.HasPrefix(mime, "text/"):
+			val, err = json.Marshal(string(data))
+		default:
+			val, err = json.Marshal(base64.StdEncoding.EncodeToString(data))
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -66,6 +66,7 @@ type Globals struct {
 	MacroChar    rune // prefix for macro-related keywords macro, quote, quasiquote, splice... The default is '~'
 	ReplCmdChar  byte // prefix for special REPL commands env, help, inspect, quit, unload... The default is ':'
 	Inspector    Inspector
+	Display      Display // renderers used by Render(), in addition to the default ones
 }
 
 func NewGlobals() *Globals {
@@ -97,6 +98,23 @@ func NewGlobals() *Globals {
 
 }
 
+// Fork returns a copy of g that can be modified without affecting g.
+// The Fileset, Readline and Inspector are shared
+func (g *Globals) Fork() *Globals {
+	fork := *g
+	fork.NamedTypes = make(map[r.Type]string, len(g.NamedTypes))
+	for k, v := range g.NamedTypes {
+		fork.NamedTypes[k] = v
+	}
+	fork.Imports = append([]*ast.GenDecl(nil), g.Imports...)
+	fork.Declarations = append([]ast.Decl(nil), g.Declarations...)
+	fork.Statements = append([]ast.Stmt(nil), g.Statements...)
+	fork.Display = g.Display.Copy()
+	fork.Importer = genimport.DefaultImporter(&fork.Output)
+	fork.Importer.PluginOpen = g.Importer.PluginOpen
+	return &fork
+}
+
 func (g *Globals) Gensym() string {
 	n := g.GensymN
 	g.GensymN++
@@ -181,16 +199,17 @@ func (g *Globals) Print(values []r.Value, types []xr.Type) {
 		if opts&OptShowEvalType != 0 {
 			for i, vi := range values {
 				var ti interface{}
+				var xt xr.Type
 				if types != nil && i < len(types) {
-					ti = types[i]
+					ti, xt = types[i], types[i]
 				} else {
 					ti = reflect.Type(vi)
 				}
-				g.Fprintf(g.Stdout, "%v\t// %v\n", vi, ti)
+				g.Fprintf(g.Stdout, "%v\t// %v\n", g.TypedValue(vi, xt), ti)
 			}
 		} else {
 			for _, vi := range values {
-				g.Fprintf(g.Stdout, "%v\n", vi)
+				g.Fprintf(g.Stdout, "%v\n", g.TypedValue(vi, nil))
 			}
 		}
 	}
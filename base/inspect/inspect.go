@@ -33,7 +33,20 @@ type Inspector struct {
 	vals    []r.Value
 	types   []r.Type
 	xtypes  []xr.Type
+	offsets []int // first element shown, for each level. used by commands next and prev
 	globals *base.Globals
+	eval    func(src string, t r.Type) (r.Value, error)
+}
+
+// PageSize is the maximum number of elements
+// of arrays, slices, strings and maps shown at once
+const PageSize = 20
+
+// SetEval sets the function used by the command 'set' and by [KEY-EXPR] to evaluate expressions.
+// eval must compile and execute src in the current interpreter,
+// and return a value assignable to t
+func (ip *Inspector) SetEval(eval func(src string, t r.Type) (r.Value, error)) {
+	ip.eval = eval
 }
 
 func (ip *Inspector) Inspect(name string, val r.Value, typ r.Type, xtyp xr.Type, globals *base.Globals) {
@@ -47,6 +60,7 @@ func (ip *Inspector) Init(name string, val r.Value, typ r.Type, xtyp xr.Type, gl
 	ip.vals = []r.Value{val}
 	ip.types = []r.Type{typ}
 	ip.xtypes = []xr.Type{xtyp}
+	ip.offsets = []int{0}
 	ip.globals = globals
 }
 
@@ -54,14 +68,21 @@ func (ip *Inspector) ShowHelp() {
 	g := ip.globals
 	g.Fprintf(g.Stdout, "%s", `
 // inspector commands:
-NUMBER      enter n-th struct field, or n-th element of array, slice or string
-.           show current expression
-?           show this help
-help        show this help
-methods     show methods
-quit        exit inspector
-top         return to top-level expression
-up          return to outer expression
+NUMBER            enter n-th struct field, or n-th element of array, slice, string or map
+FIELD             enter struct field
+[KEY-EXPR]        enter map element with given key, or array, slice or string element with given index
+.                 show current expression
+?                 show this help
+help              show this help
+methods           show methods
+next              show next elements of array, slice, string or map
+prev              show previous elements of array, slice, string or map
+quit              exit inspector
+set FIELD = EXPR  set struct field, by name or number
+set [N] = EXPR    set n-th element of array or slice
+set [KEY] = EXPR  set map element with given key
+top               return to top-level expression
+up                return to outer expression
 // abbreviations are allowed if unambiguous.
 `)
 }
@@ -76,9 +97,17 @@ func (ip *Inspector) Show() {
 	v = dereferenceValue(v) // dereference pointers on-the-fly
 	switch v.Kind() {
 	case r.Array, r.Slice, r.String:
-		ip.showIndexes(v)
+		ip.showIndexes(v, ip.offsets[depth-1])
+	case r.Map:
+		ip.showMapEntries(v, ip.offsets[depth-1])
 	case r.Struct:
 		ip.showFields(v)
+	case r.Chan, r.Func:
+		var xt xr.Type
+		if depth == 1 {
+			xt = ip.xtypes[0]
+		}
+		ip.showDetails(v, xt)
 	}
 }
 
@@ -108,8 +137,14 @@ func (ip *Inspector) Eval(cmd string) error {
 		t := ip.types[len(ip.types)-1]
 		xt := ip.xtypes[len(ip.xtypes)-1]
 		ip.showMethods(t, xt)
+	case strings.HasPrefix("next", cmd):
+		ip.Page(+PageSize)
+	case strings.HasPrefix("prev", cmd):
+		ip.Page(-PageSize)
 	case strings.HasPrefix("quit", cmd):
 		return errors.New("user quit")
+	case cmd == "set", strings.HasPrefix(cmd, "set "):
+		ip.Set(strings.TrimSpace(cmd[3:]))
 	case strings.HasPrefix("top", cmd):
 		ip.Top()
 		ip.Show()
@@ -131,6 +166,7 @@ func (ip *Inspector) Top() {
 	ip.names = ip.names[0:1]
 	ip.vals = ip.vals[0:1]
 	ip.types = ip.types[0:1]
+	ip.offsets = ip.offsets[0:1]
 }
 
 func (ip *Inspector) Leave() {
@@ -142,13 +178,56 @@ func (ip *Inspector) Leave() {
 	ip.names = ip.names[:depth]
 	ip.vals = ip.vals[:depth]
 	ip.types = ip.types[:depth]
+	ip.offsets = ip.offsets[:depth]
 	if depth > 0 {
 		ip.Show()
 	}
 }
 
+// Page moves the elements shown for arrays, slices, strings and maps
+// forward or backward by delta, then shows them
+func (ip *Inspector) Page(delta int) {
+	depth := len(ip.names)
+	v := dereferenceValue(ip.vals[depth-1])
+	switch v.Kind() {
+	case r.Array, r.Slice, r.String, r.Map:
+		break
+	default:
+		g := ip.globals
+		g.Fprintf(g.Stdout, "cannot show more elements of <%v>: expecting array, slice, string or map\n", reflect.Type(v))
+		return
+	}
+	offset := ip.offsets[depth-1] + delta
+	if offset >= v.Len() {
+		offset -= delta
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	ip.offsets[depth-1] = offset
+	ip.Show()
+}
+
 func (ip *Inspector) showVar(str string, v r.Value, t r.Type) {
-	ip.globals.Fprintf(ip.globals.Stdout, "%s\t= %v\t// %v\n", str, v, t)
+	g := ip.globals
+	if n, ok := largeCollection(v); ok {
+		// do not print all the elements
+		g.Fprintf(g.Stdout, "%s\t= {%d elements}\t// %v\n", str, n, t)
+		return
+	}
+	g.Fprintf(g.Stdout, "%s\t= %v\t// %v\n", str, v, t)
+}
+
+// return the number of elements of v, if it's an array, slice or map with more than PageSize elements
+func largeCollection(v r.Value) (int, bool) {
+	v = dereferenceValue(v)
+	switch v.Kind() {
+	case r.Array, r.Slice, r.Map:
+		if n := v.Len(); n > PageSize {
+			return n, true
+		}
+	}
+	return 0, false
 }
 
 func (ip *Inspector) showFields(v r.Value) {
@@ -159,20 +238,79 @@ func (ip *Inspector) showFields(v r.Value) {
 		t := reflect.Type(f)
 		f = dereferenceValue(f)
 		g.Fprintf(g.Stdout, "    %d. ", i)
-		ip.showVar(v.Type().Field(i).Name, f, t)
+		ip.showVar(fieldName(v.Type().Field(i)), f, t)
 	}
 }
 
-func (ip *Inspector) showIndexes(v r.Value) {
+func (ip *Inspector) showIndexes(v r.Value, offset int) {
 	g := ip.globals
 	n := v.Len()
-	for i := 0; i < n; i++ {
+	end := pageEnd(offset, n)
+	for i := offset; i < end; i++ {
 		f := v.Index(i)
 		t := reflect.Type(f)
 		f = dereferenceValue(f)
 		g.Fprintf(g.Stdout, "    %d. ", i)
 		ip.showVar("", f, t)
 	}
+	ip.showPage(offset, end, n)
+}
+
+func (ip *Inspector) showMapEntries(v r.Value, offset int) {
+	g := ip.globals
+	keys := reflect.MapKeys(v)
+	n := len(keys)
+	end := pageEnd(offset, n)
+	for i := offset; i < end; i++ {
+		f := v.MapIndex(keys[i])
+		t := reflect.Type(f)
+		f = dereferenceValue(f)
+		g.Fprintf(g.Stdout, "    %d. ", i)
+		ip.showVar(ip.keyName(keys[i]), f, t)
+	}
+	ip.showPage(offset, end, n)
+}
+
+func pageEnd(offset, n int) int {
+	if end := offset + PageSize; end < n {
+		return end
+	}
+	return n
+}
+
+func (ip *Inspector) showPage(start, end, n int) {
+	if start == 0 && end == n {
+		return
+	}
+	g := ip.globals
+	g.Fprintf(g.Stdout, "    // showing elements %d...%d of %d. type next or prev to show other elements\n", start, end-1, n)
+}
+
+// show length, capacity and direction of channels, and signature of functions
+func (ip *Inspector) showDetails(v r.Value, xt xr.Type) {
+	g := ip.globals
+	switch v.Kind() {
+	case r.Chan:
+		g.Fprintf(g.Stdout, "    len = %d, cap = %d, direction = %v\n", v.Len(), v.Cap(), v.Type().ChanDir())
+	case r.Func:
+		var sig interface{} = v.Type()
+		if xt != nil && xt.Kind() == r.Func {
+			sig = xt
+		}
+		if v.IsNil() {
+			g.Fprintf(g.Stdout, "    signature: %v, value is nil\n", sig)
+		} else {
+			g.Fprintf(g.Stdout, "    signature: %v\n", sig)
+		}
+	}
+}
+
+// return the name shown for map key
+func (ip *Inspector) keyName(key r.Value) string {
+	if k := dereferenceValue(key); k.Kind() == r.String {
+		return "[" + strconv.Quote(k.String()) + "]"
+	}
+	return ip.globals.Sprintf("[%v]", key)
 }
 
 func (ip *Inspector) showMethods(t r.Type, xt xr.Type) {
@@ -206,37 +344,112 @@ func (ip *Inspector) showMethods(t r.Type, xt xr.Type) {
 	}
 }
 
-func (ip *Inspector) Enter(cmd string) {
+// an element of the current expression, selected by a number, a field name or a [KEY-EXPR]
+type element struct {
+	name  string
+	val   r.Value
+	field *r.StructField // for struct fields
+	key   r.Value        // for map elements
+}
+
+// return the element of current expression selected by sel.
+// on error, show a message starting with prefix and return false
+func (ip *Inspector) element(sel string, prefix string) (element, bool) {
 	g := ip.globals
-	i, err := strconv.Atoi(cmd)
-	if err != nil {
-		g.Fprintf(g.Stdout, "unknown inspector command \"%s\". Type ? for help\n", cmd)
-		return
-	}
 	depth := len(ip.names)
 	v := dereferenceValue(ip.vals[depth-1])
-	var n int
-	var fname string
-	var f r.Value
+
+	var src string // KEY-EXPR
+	if n := len(sel); n > 2 && sel[0] == '[' && sel[n-1] == ']' {
+		src = strings.TrimSpace(sel[1 : n-1])
+	}
+	i, err := strconv.Atoi(sel)
+	isnum := err == nil
+	if len(src) != 0 {
+		i, err = strconv.Atoi(src)
+		isnum = err == nil
+	}
+
+	var e element
 	switch v.Kind() {
 	case r.Array, r.Slice, r.String:
-		n = v.Len()
-		if !ip.validRange(i, n) {
-			return
+		if !isnum && len(src) != 0 && ip.eval != nil {
+			// index is an expression
+			index, err := ip.eval(src, r.TypeOf(int(0)))
+			if err != nil {
+				g.Fprintf(g.Stdout, "%s%v\n", prefix, err)
+				return e, false
+			}
+			i, isnum = int(index.Int()), true
 		}
-		fname = "[" + cmd + "]"
-		f = v.Index(i)
+		if !isnum {
+			break
+		} else if !ip.validRange(i, v.Len()) {
+			return e, false
+		}
+		e.name = "[" + strconv.Itoa(i) + "]"
+		e.val = v.Index(i)
+		return e, true
+	case r.Map:
+		if len(src) != 0 {
+			if ip.eval == nil {
+				g.Fprintf(g.Stdout, "%sevaluating map keys is not supported by this interpreter\n", prefix)
+				return e, false
+			}
+			key, err := ip.eval(src, v.Type().Key())
+			if err != nil {
+				g.Fprintf(g.Stdout, "%s%v\n", prefix, err)
+				return e, false
+			}
+			e.key = key
+		} else if isnum {
+			keys := reflect.MapKeys(v)
+			if !ip.validRange(i, len(keys)) {
+				return e, false
+			}
+			e.key = keys[i]
+		} else {
+			break
+		}
+		e.name = ip.keyName(e.key)
+		e.val = v.MapIndex(e.key)
+		if !e.val.IsValid() {
+			// key not present
+			e.val = r.Zero(v.Type().Elem())
+		}
+		return e, true
 	case r.Struct:
-		n = v.NumField()
-		if !ip.validRange(i, n) {
-			return
+		if len(src) != 0 {
+			break
+		} else if !isnum {
+			if i = fieldIndex(v.Type(), sel); i < 0 {
+				break
+			}
+		} else if !ip.validRange(i, v.NumField()) {
+			return e, false
 		}
-		fname = v.Type().Field(i).Name
-		f = v.Field(i)
-	default:
-		g.Fprintf(g.Stdout, "cannot enter <%v>: expecting array, slice, string or struct\n", reflect.Type(v))
+		field := v.Type().Field(i)
+		e.name = fieldName(field)
+		e.val = v.Field(i)
+		e.field = &field
+		return e, true
+	}
+	if len(prefix) != 0 {
+		g.Fprintf(g.Stdout, "%s<%v> has no element %s\n", prefix, reflect.Type(v), sel)
+	} else if isnum || len(src) != 0 {
+		g.Fprintf(g.Stdout, "cannot enter <%v>: expecting array, slice, string, map or struct\n", reflect.Type(v))
+	} else {
+		g.Fprintf(g.Stdout, "unknown inspector command \"%s\". Type ? for help\n", sel)
+	}
+	return e, false
+}
+
+func (ip *Inspector) Enter(cmd string) {
+	e, ok := ip.element(cmd, "")
+	if !ok {
 		return
 	}
+	fname, f := e.name, e.val
 	var t r.Type
 	if f.IsValid() && f != base.None {
 		if f.Kind() == r.Interface {
@@ -246,14 +459,105 @@ func (ip *Inspector) Enter(cmd string) {
 	}
 
 	switch dereferenceValue(f).Kind() { // dereference pointers on-the-fly
-	case r.Array, r.Slice, r.String, r.Struct:
+	case r.Array, r.Slice, r.String, r.Map, r.Struct:
 		ip.names = append(ip.names, fname)
 		ip.vals = append(ip.vals, f)
 		ip.types = append(ip.types, t)
+		ip.offsets = append(ip.offsets, 0)
 		ip.Show()
 	default:
 		ip.showVar(fname, f, t)
+		ip.showDetails(dereferenceValue(f), nil)
+	}
+}
+
+// Set implements the command 'set LHS = EXPR',
+// where LHS is a struct field name or number, an index [N] or a map key [KEY-EXPR]
+func (ip *Inspector) Set(arg string) {
+	g := ip.globals
+	eq := strings.IndexByte(arg, '=')
+	if eq < 0 {
+		g.Fprintf(g.Stdout, "set: expecting FIELD = EXPR or [N] = EXPR, found \"%s\"\n", arg)
+		return
+	}
+	lhs, src := strings.TrimSpace(arg[:eq]), strings.TrimSpace(arg[eq+1:])
+	if len(lhs) == 0 || len(src) == 0 {
+		g.Fprintf(g.Stdout, "set: expecting FIELD = EXPR or [N] = EXPR, found \"%s\"\n", arg)
+		return
+	}
+	if ip.eval == nil {
+		g.Fprintf(g.Stdout, "set: not supported by this interpreter\n")
+		return
+	}
+	e, ok := ip.element(lhs, "set: ")
+	if !ok {
+		return
+	}
+	f := e.val
+	if e.field != nil && isCompiledUnexported(*e.field) {
+		g.Fprintf(g.Stdout, "set: cannot set %s: unexported field of compiled type <%v>\n", e.name, reflect.Type(dereferenceValue(ip.vals[len(ip.vals)-1])))
+		return
+	}
+	if e.key.IsValid() {
+		// map element
+		m := dereferenceValue(ip.vals[len(ip.vals)-1])
+		if m.IsNil() {
+			g.Fprintf(g.Stdout, "set: cannot set %s: map is nil\n", e.name)
+			return
+		}
+		val, err := ip.eval(src, m.Type().Elem())
+		if err != nil {
+			g.Fprintf(g.Stdout, "set: %v\n", err)
+			return
+		}
+		m.SetMapIndex(e.key, val)
+		ip.showVar(e.name, val, m.Type().Elem())
+		return
+	}
+	if !f.CanSet() {
+		g.Fprintf(g.Stdout, "set: cannot set %s: value is not addressable\n", e.name)
+		return
+	}
+	val, err := ip.eval(src, f.Type())
+	if err != nil {
+		g.Fprintf(g.Stdout, "set: %v\n", err)
+		return
+	}
+	f.Set(val)
+	ip.showVar(e.name, f, f.Type())
+}
+
+// return the index of struct field with given name, or -1 if not found.
+// name is the field name in source code: for interpreted types,
+// unexported and anonymous fields are matched through their gensym prefix
+func fieldIndex(t r.Type, name string) int {
+	for i, n := 0, t.NumField(); i < n; i++ {
+		if fieldName(t.Field(i)) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// return the name of struct field as written in source code,
+// i.e. without the prefix added by the interpreter to unexported and anonymous fields
+func fieldName(field r.StructField) string {
+	name := field.Name
+	if base.IsGensymAnonymous(name) {
+		name = name[len(base.StrGensymAnonymous):]
+		if len(name) == 0 || name[0] >= '0' && name[0] <= '9' {
+			name = dereferenceType(field.Type).Name()
+		}
+	} else if base.IsGensymPrivate(name) {
+		name = name[len(base.StrGensymPrivate):]
 	}
+	return name
+}
+
+// return true if field is an unexported field of a compiled type.
+// unexported fields of interpreted types are exported with a gensym prefix
+func isCompiledUnexported(field r.StructField) bool {
+	return len(field.PkgPath) != 0 && !base.IsGensymPrivate(field.Name) && !base.IsGensymAnonymous(field.Name)
 }
 
 func dereferenceValue(v r.Value) r.Value {
@@ -268,6 +572,13 @@ func dereferenceValue(v r.Value) r.Value {
 	return v
 }
 
+func dereferenceType(t r.Type) r.Type {
+	for t.Kind() == r.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
 func (ip *Inspector) validRange(i, n int) bool {
 	if i < 0 || i >= n {
 		g := ip.globals
@@ -34,10 +34,11 @@ import (
 )
 
 type Stringer struct {
-	Fileset    *mt.FileSet
-	Pos        token.Pos
-	Line       int
-	NamedTypes map[r.Type]string
+	Fileset      *mt.FileSet
+	Pos          token.Pos
+	Line         int
+	NamedTypes   map[r.Type]string
+	PrintOptions PrintOptions // limits and layout used to format arrays, slices, maps, structs and pointers
 }
 
 type Output struct {
@@ -56,6 +57,7 @@ func (st *Stringer) Copy(other *Stringer) {
 	st.Fileset = other.Fileset
 	st.Pos = other.Pos
 	st.Line = other.Line
+	st.PrintOptions = other.PrintOptions
 }
 
 func (err RuntimeError) Error() string {
@@ -251,7 +253,7 @@ func (st *Stringer) toPrintable(format string, value interface{}) (ret interface
 		}
 	}
 
-	usual := len(format) == 0 || strings.HasPrefix(format, "%v") || strings.HasPrefix(format, "%s")
+	usual := isUsualFormat(format)
 	if usual {
 		switch v := value.(type) {
 		case AstWithNode:
@@ -270,6 +272,14 @@ func (st *Stringer) toPrintable(format string, value interface{}) (ret interface
 	}
 
 	v := r.ValueOf(value)
+	if usual && st != nil {
+		switch v.Kind() {
+		case r.Array, r.Slice, r.Map, r.Ptr, r.Struct:
+			if st.PrintOptions.enabled() || HasCycle(v) {
+				return st.FormatValue(v, nil)
+			}
+		}
+	}
 	switch k := v.Kind(); k {
 	case r.Array, r.Slice:
 		n := v.Len()
@@ -303,6 +313,10 @@ func (st *Stringer) toPrintable(format string, value interface{}) (ret interface
 	return value
 }
 
+func isUsualFormat(format string) bool {
+	return len(format) == 0 || strings.HasPrefix(format, "%v") || strings.HasPrefix(format, "%s")
+}
+
 var config = printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
 
 func (st *Stringer) nodeToPrintable(node ast.Node) interface{} {
@@ -330,6 +344,8 @@ func (st *Stringer) rvalueToPrintable(format string, value r.Value) interface{}
 		i = nil
 	} else if value == reflect.None {
 		i = "/*no value*/"
+	} else if st != nil && isUsualFormat(format) && (st.PrintOptions.enabled() || HasCycle(value)) {
+		i = st.FormatValue(value, nil)
 	} else if value.CanInterface() {
 		i = st.toPrintable(format, value.Interface())
 	} else {
@@ -0,0 +1,491 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * print.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	r "reflect"
+	"strconv"
+	"strings"
+
+	. "github.com/steele232/zoumacro/ast2"
+	"github.com/steele232/zoumacro/base/reflect"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// PrintOptions configures how Stringer formats arrays, slices, maps, structs, pointers and strings.
+// The zero value means no limits, single-line layout and no cycle detection, as fmt.Printf("%v")
+type PrintOptions struct {
+	MaxDepth     int  // maximum nesting of arrays, slices, maps, structs and pointers shown. 0 = unlimited
+	MaxElems     int  // maximum number of elements shown for each array, slice, map or struct. 0 = unlimited
+	MaxStringLen int  // maximum number of bytes shown for each string. 0 = unlimited
+	MultiLine    bool // show one element per line, indented
+}
+
+var printOptionNames = []string{"MaxDepth", "MaxElems", "MaxStringLen", "MultiLine"}
+
+func (o *PrintOptions) enabled() bool {
+	return *o != PrintOptions{}
+}
+
+func (o PrintOptions) String() string {
+	return fmt.Sprintf("MaxDepth=%d MaxElems=%d MaxStringLen=%d MultiLine=%v",
+		o.MaxDepth, o.MaxElems, o.MaxStringLen, o.MultiLine)
+}
+
+// IsPrintOption returns true if str has the form NAME=VALUE and NAME is the name,
+// or an unambiguous prefix of the name, of a PrintOptions field
+func IsPrintOption(str string) bool {
+	eq := strings.IndexByte(str, '=')
+	if eq <= 0 {
+		return false
+	}
+	_, err := printOptionName(str[:eq])
+	return err == nil
+}
+
+func printOptionName(prefix string) (string, error) {
+	var found []string
+	for _, name := range printOptionNames {
+		if name == prefix {
+			return name, nil
+		} else if strings.HasPrefix(name, prefix) {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unknown print option %q, expecting one of: %s", prefix, strings.Join(printOptionNames, " "))
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous print option %q matches: %s", prefix, strings.Join(found, " "))
+	}
+}
+
+// Set parses str, which must have the form NAME=VALUE, and sets the corresponding option
+func (o *PrintOptions) Set(str string) error {
+	eq := strings.IndexByte(str, '=')
+	if eq <= 0 {
+		return fmt.Errorf("invalid print option %q, expecting NAME=VALUE", str)
+	}
+	name, err := printOptionName(str[:eq])
+	if err != nil {
+		return err
+	}
+	value := str[eq+1:]
+	if name == "MultiLine" {
+		o.MultiLine, err = strconv.ParseBool(value)
+		return err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for print option %s: %q", name, value)
+	} else if n < 0 {
+		n = 0
+	}
+	switch name {
+	case "MaxDepth":
+		o.MaxDepth = n
+	case "MaxElems":
+		o.MaxElems = n
+	case "MaxStringLen":
+		o.MaxStringLen = n
+	}
+	return nil
+}
+
+// TypedValue returns a value that, when formatted by Stringer.Fprintf or Stringer.Sprintf,
+// honours Stringer.PrintOptions and shows the names of interpreted named types, as described by t
+func (st *Stringer) TypedValue(v r.Value, t xr.Type) fmt.Formatter {
+	return typedValue{st, v, t}
+}
+
+type typedValue struct {
+	st *Stringer
+	v  r.Value
+	t  xr.Type
+}
+
+func (tv typedValue) Format(f fmt.State, verb rune) {
+	// cyclic values are always formatted by FormatValue, which detects cycles:
+	// fmt.Printf("%v") would recurse until the stack overflows
+	if !tv.st.PrintOptions.enabled() && !HasCycle(tv.v) {
+		fmt.Fprint(f, tv.st.rvalueToPrintable("%v", tv.v))
+		return
+	}
+	f.Write([]byte(tv.st.FormatValue(tv.v, tv.t)))
+}
+
+// HasCycle returns true if v contains a pointer, slice or map that refers back to itself.
+// Such values must be formatted by Stringer.FormatValue, which detects cycles
+func HasCycle(v r.Value) bool {
+	return hasCycle(v, make(map[visit]bool))
+}
+
+// visiting contains the pointers, slices and maps currently being walked
+func hasCycle(v r.Value, visiting map[visit]bool) bool {
+	if !v.IsValid() {
+		return false
+	}
+	var key visit
+	switch v.Kind() {
+	case r.Interface:
+		return !v.IsNil() && hasCycle(v.Elem(), visiting)
+	case r.Ptr, r.Map, r.Slice:
+		if v.IsNil() {
+			return false
+		}
+		key = visit{v.Pointer(), v.Type(), 0}
+		if v.Kind() != r.Ptr {
+			key.len = v.Len()
+		}
+		if visiting[key] {
+			return true
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+	case r.Array, r.Struct:
+	default:
+		return false
+	}
+	switch v.Kind() {
+	case r.Ptr:
+		return hasCycle(v.Elem(), visiting)
+	case r.Map:
+		if !mayContainCycle(v.Type().Elem()) && !mayContainCycle(v.Type().Key()) {
+			return false
+		}
+		for _, k := range v.MapKeys() {
+			if hasCycle(k, visiting) || hasCycle(v.MapIndex(k), visiting) {
+				return true
+			}
+		}
+	case r.Array, r.Slice:
+		if !mayContainCycle(v.Type().Elem()) {
+			return false
+		}
+		for i, n := 0, v.Len(); i < n; i++ {
+			if hasCycle(v.Index(i), visiting) {
+				return true
+			}
+		}
+	case r.Struct:
+		for i, n := 0, v.NumField(); i < n; i++ {
+			if hasCycle(v.Field(i), visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// return false if values of type t cannot contain pointers, slices, maps or interfaces
+func mayContainCycle(t r.Type) bool {
+	switch t.Kind() {
+	case r.Interface, r.Ptr, r.Map, r.Slice, r.Struct:
+		return true
+	case r.Array:
+		return mayContainCycle(t.Elem())
+	}
+	return false
+}
+
+// FormatValue converts v to string, honouring Stringer.PrintOptions.
+// t is optional: if not nil, it is used to show the names of interpreted named types
+func (st *Stringer) FormatValue(v r.Value, t xr.Type) string {
+	p := valuePrinter{st: st, opts: st.PrintOptions, visiting: make(map[visit]bool)}
+	p.value(v, t, 0)
+	return p.buf.String()
+}
+
+type visit struct {
+	ptr uintptr
+	typ r.Type
+	len int
+}
+
+type valuePrinter struct {
+	st       *Stringer
+	opts     PrintOptions
+	buf      bytes.Buffer
+	visiting map[visit]bool // pointers, slices and maps currently being printed. used to detect cycles
+}
+
+func (p *valuePrinter) value(v r.Value, t xr.Type, depth int) {
+	if !v.IsValid() || v == reflect.None {
+		p.buf.WriteString("<nil>")
+		return
+	}
+	switch v.Kind() {
+	case r.Chan, r.Func, r.Interface, r.Map, r.Ptr, r.Slice:
+		if v.IsNil() {
+			if v.Kind() == r.Slice {
+				p.buf.WriteString("[]")
+			} else if v.Kind() == r.Map {
+				p.buf.WriteString("map[]")
+			} else {
+				p.buf.WriteString("<nil>")
+			}
+			return
+		}
+	}
+	if p.special(v) {
+		return
+	}
+	switch v.Kind() {
+	case r.Interface:
+		p.value(v.Elem(), nil, depth)
+	case r.Ptr:
+		key := visit{v.Pointer(), v.Type(), 0}
+		if p.visiting[key] {
+			fmt.Fprintf(&p.buf, "<cycle %#x>", v.Pointer())
+			return
+		}
+		switch v.Elem().Kind() {
+		case r.Array, r.Slice, r.Map, r.Struct:
+			if p.tooDeep(depth, "&{...}") {
+				return
+			}
+			p.visiting[key] = true
+			p.buf.WriteByte('&')
+			var elem xr.Type
+			if t != nil && t.Kind() == r.Ptr {
+				elem = t.Elem()
+			}
+			p.value(v.Elem(), elem, depth)
+			delete(p.visiting, key)
+		default:
+			fmt.Fprintf(&p.buf, "%#x", v.Pointer())
+		}
+	case r.Array, r.Slice:
+		p.list(v, t, depth)
+	case r.Map:
+		p.mapEntries(v, t, depth)
+	case r.Struct:
+		p.structFields(v, t, depth)
+	case r.String:
+		p.str(v.String())
+	case r.Chan, r.Func, r.UnsafePointer:
+		fmt.Fprintf(&p.buf, "%#x", v.Pointer())
+	default:
+		if v.CanInterface() {
+			fmt.Fprint(&p.buf, v.Interface())
+		} else {
+			fmt.Fprint(&p.buf, v)
+		}
+	}
+}
+
+// format values that need special treatment: source code, types, errors and fmt.Stringer.
+// return false if v is not one of them
+func (p *valuePrinter) special(v r.Value) bool {
+	if !v.CanInterface() || v.Kind() == r.Interface {
+		return false
+	}
+	switch x := v.Interface().(type) {
+	case Ast, ast.Node, r.Type, error, fmt.Stringer, fmt.Formatter:
+		s := fmt.Sprint(p.st.toPrintable("%v", x))
+		if v.Kind() == r.String {
+			p.str(s)
+		} else {
+			p.buf.WriteString(s)
+		}
+		return true
+	}
+	return false
+}
+
+func (p *valuePrinter) str(s string) {
+	if max := p.opts.MaxStringLen; max > 0 && len(s) > max {
+		fmt.Fprintf(&p.buf, "%s...(%d more bytes)", s[:max], len(s)-max)
+		return
+	}
+	p.buf.WriteString(s)
+}
+
+// if depth exceeds MaxDepth, write placeholder and return true
+func (p *valuePrinter) tooDeep(depth int, placeholder string) bool {
+	if max := p.opts.MaxDepth; max > 0 && depth >= max {
+		p.buf.WriteString(placeholder)
+		return true
+	}
+	return false
+}
+
+// write the name of v type if it's a named type
+func (p *valuePrinter) typeName(v r.Value, t xr.Type) {
+	if !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case r.Array, r.Slice, r.Map, r.Struct:
+	default:
+		return
+	}
+	if t != nil && t.Named() {
+		p.buf.WriteString(t.String())
+	} else if name, ok := p.st.NamedTypes[v.Type()]; ok {
+		p.buf.WriteString(name)
+	} else if rt := v.Type(); len(rt.Name()) != 0 {
+		p.buf.WriteString(rt.String())
+	}
+}
+
+// write the separator before the i-th element of a list, map or struct
+func (p *valuePrinter) separator(i int, depth int) {
+	if p.opts.MultiLine {
+		p.newline(depth + 1)
+	} else if i != 0 {
+		p.buf.WriteByte(' ')
+	}
+}
+
+func (p *valuePrinter) newline(depth int) {
+	p.buf.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		p.buf.WriteString("    ")
+	}
+}
+
+// return the number of elements to show, out of n
+func (p *valuePrinter) shown(n int) int {
+	if max := p.opts.MaxElems; max > 0 && n > max {
+		return max
+	}
+	return n
+}
+
+// write a marker for the elements not shown, then the closing bracket
+func (p *valuePrinter) close(shown int, n int, depth int, bracket byte) {
+	if shown < n {
+		p.separator(shown, depth)
+		fmt.Fprintf(&p.buf, "...(%d more)", n-shown)
+	}
+	if p.opts.MultiLine && n != 0 {
+		p.newline(depth)
+	}
+	p.buf.WriteByte(bracket)
+}
+
+func (p *valuePrinter) enter(v r.Value) (visit, bool) {
+	var key visit
+	if v.Kind() == r.Slice || v.Kind() == r.Map {
+		key = visit{v.Pointer(), v.Type(), v.Len()}
+		if p.visiting[key] {
+			fmt.Fprintf(&p.buf, "<cycle %#x>", v.Pointer())
+			return key, false
+		}
+		p.visiting[key] = true
+	}
+	return key, true
+}
+
+func (p *valuePrinter) leave(v r.Value, key visit) {
+	if v.Kind() == r.Slice || v.Kind() == r.Map {
+		delete(p.visiting, key)
+	}
+}
+
+func (p *valuePrinter) list(v r.Value, t xr.Type, depth int) {
+	if p.tooDeep(depth, "[...]") {
+		return
+	}
+	key, ok := p.enter(v)
+	if !ok {
+		return
+	}
+	defer p.leave(v, key)
+
+	var elem xr.Type
+	if t != nil && (t.Kind() == r.Array || t.Kind() == r.Slice) {
+		elem = t.Elem()
+	}
+	n := v.Len()
+	shown := p.shown(n)
+	p.typeName(v, t)
+	p.buf.WriteByte('[')
+	for i := 0; i < shown; i++ {
+		p.separator(i, depth)
+		p.value(v.Index(i), elem, depth+1)
+	}
+	p.close(shown, n, depth, ']')
+}
+
+func (p *valuePrinter) mapEntries(v r.Value, t xr.Type, depth int) {
+	if p.tooDeep(depth, "map[...]") {
+		return
+	}
+	key, ok := p.enter(v)
+	if !ok {
+		return
+	}
+	defer p.leave(v, key)
+
+	var tkey, telem xr.Type
+	if t != nil && t.Kind() == r.Map {
+		tkey, telem = t.Key(), t.Elem()
+	}
+	keys := reflect.MapKeys(v)
+	n := len(keys)
+	shown := p.shown(n)
+	p.typeName(v, t)
+	p.buf.WriteString("map[")
+	for i := 0; i < shown; i++ {
+		p.separator(i, depth)
+		p.value(keys[i], tkey, depth+1)
+		p.buf.WriteByte(':')
+		if p.opts.MultiLine {
+			p.buf.WriteByte(' ')
+		}
+		p.value(v.MapIndex(keys[i]), telem, depth+1)
+	}
+	p.close(shown, n, depth, ']')
+}
+
+func (p *valuePrinter) structFields(v r.Value, t xr.Type, depth int) {
+	if t != nil && (t.Kind() != r.Struct || t.NumField() != v.NumField()) {
+		t = nil
+	}
+	p.typeName(v, t)
+	if p.tooDeep(depth, "{...}") {
+		return
+	}
+	rt := v.Type()
+	n := v.NumField()
+	shown := p.shown(n)
+	p.buf.WriteByte('{')
+	for i := 0; i < shown; i++ {
+		p.separator(i, depth)
+		// interpreted types know the original name of unexported fields
+		name := rt.Field(i).Name
+		var tfield xr.Type
+		if t != nil {
+			field := t.Field(i)
+			name, tfield = field.Name, field.Type
+		}
+		p.buf.WriteString(name)
+		p.buf.WriteByte(':')
+		if p.opts.MultiLine {
+			p.buf.WriteByte(' ')
+		}
+		p.value(v.Field(i), tfield, depth+1)
+	}
+	p.close(shown, n, depth, '}')
+}
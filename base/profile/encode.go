@@ -0,0 +1,179 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * encode.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package profile
+
+// minimal encoder for github.com/google/pprof/proto/profile.proto
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) varint(x uint64) {
+	for x >= 0x80 {
+		e.buf = append(e.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	e.buf = append(e.buf, byte(x))
+}
+
+func (e *encoder) key(field int, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (e *encoder) uint64(field int, x uint64) {
+	if x != 0 {
+		e.key(field, wireVarint)
+		e.varint(x)
+	}
+}
+
+func (e *encoder) int64(field int, x int64) {
+	e.uint64(field, uint64(x))
+}
+
+func (e *encoder) string(field int, s string) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) packed(field int, xs []uint64) {
+	var inner encoder
+	for _, x := range xs {
+		inner.varint(x)
+	}
+	e.message(field, &inner)
+}
+
+func (e *encoder) message(field int, inner *encoder) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(inner.buf)))
+	e.buf = append(e.buf, inner.buf...)
+}
+
+// string table, as required by profile.proto. index 0 is always ""
+type strtable struct {
+	list  []string
+	index map[string]int64
+}
+
+func (t *strtable) get(s string) int64 {
+	if t.index == nil {
+		t.index = map[string]int64{"": 0}
+		t.list = []string{""}
+	}
+	i, ok := t.index[s]
+	if !ok {
+		i = int64(len(t.list))
+		t.list = append(t.list, s)
+		t.index[s] = i
+	}
+	return i
+}
+
+type funcKey struct {
+	name, file string
+}
+
+func (p *Profile) encode() []byte {
+	var e encoder
+	var strs strtable
+	strs.get("")
+
+	valueType := func(field int, typ, unit string) {
+		var inner encoder
+		inner.int64(1, strs.get(typ))
+		inner.int64(2, strs.get(unit))
+		e.message(field, &inner)
+	}
+	// Profile.sample_type
+	valueType(1, p.SampleType, p.SampleUnit)
+	if p.Period > 0 {
+		valueType(1, "cpu", "nanoseconds")
+	}
+
+	funcs := make(map[funcKey]uint64)
+	var funcList []funcKey
+	locs := make(map[Frame]uint64)
+	var locList []Frame
+
+	// Profile.sample
+	for _, s := range p.order {
+		ids := make([]uint64, len(s.stack))
+		for i, frame := range s.stack {
+			id, ok := locs[frame]
+			if !ok {
+				id = uint64(len(locList) + 1)
+				locs[frame] = id
+				locList = append(locList, frame)
+			}
+			ids[i] = id
+		}
+		values := []uint64{uint64(s.count)}
+		if p.Period > 0 {
+			values = append(values, uint64(s.count*int64(p.Period)))
+		}
+		var inner encoder
+		inner.packed(1, ids)
+		inner.packed(2, values)
+		e.message(2, &inner)
+	}
+	// Profile.location
+	for i, frame := range locList {
+		key := funcKey{frame.Func, frame.File}
+		fid, ok := funcs[key]
+		if !ok {
+			fid = uint64(len(funcList) + 1)
+			funcs[key] = fid
+			funcList = append(funcList, key)
+		}
+		var line encoder
+		line.uint64(1, fid)
+		line.int64(2, int64(frame.Line))
+		var inner encoder
+		inner.uint64(1, uint64(i+1))
+		inner.message(4, &line)
+		e.message(4, &inner)
+	}
+	// Profile.function
+	for i, key := range funcList {
+		var inner encoder
+		inner.uint64(1, uint64(i+1))
+		inner.int64(2, strs.get(key.name))
+		inner.int64(3, strs.get(key.name))
+		inner.int64(4, strs.get(key.file))
+		e.message(5, &inner)
+	}
+	// Profile.time_nanos, duration_nanos
+	e.int64(9, p.Start.UnixNano())
+	e.int64(10, int64(p.Duration))
+	if p.Period > 0 {
+		// Profile.period_type, period
+		valueType(11, "cpu", "nanoseconds")
+		e.int64(12, int64(p.Period))
+	}
+	// Profile.string_table
+	for _, s := range strs.list {
+		e.string(6, s)
+	}
+	return e.buf
+}
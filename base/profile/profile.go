@@ -0,0 +1,108 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * profile.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"time"
+)
+
+// Frame is a source location in a sampled call stack
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+type sample struct {
+	stack []Frame // innermost frame first
+	count int64
+}
+
+// Profile accumulates samples of interpreted call stacks
+// and writes them in the format read by 'go tool pprof'
+type Profile struct {
+	SampleType string // "samples" for CPU profiles, "count" for statement counts
+	SampleUnit string
+	Period     time.Duration // zero if samples are not taken at regular intervals
+	Start      time.Time
+	Duration   time.Duration
+	samples    map[string]*sample
+	order      []*sample
+}
+
+func New(sampleType, sampleUnit string, period time.Duration) *Profile {
+	return &Profile{
+		SampleType: sampleType,
+		SampleUnit: sampleUnit,
+		Period:     period,
+		Start:      time.Now(),
+		samples:    make(map[string]*sample),
+	}
+}
+
+// Add records n occurrences of the given call stack, innermost frame first
+func (p *Profile) Add(stack []Frame, n int64) {
+	key := stackKey(stack)
+	s := p.samples[key]
+	if s == nil {
+		s = &sample{stack: append([]Frame(nil), stack...)}
+		p.samples[key] = s
+		p.order = append(p.order, s)
+	}
+	s.count += n
+}
+
+// Len returns the number of distinct call stacks recorded
+func (p *Profile) Len() int {
+	return len(p.order)
+}
+
+func stackKey(stack []Frame) string {
+	var buf strings.Builder
+	for _, f := range stack {
+		buf.WriteString(f.Func)
+		buf.WriteByte(0)
+		buf.WriteString(f.File)
+		buf.WriteByte(0)
+		writeVarint(&buf, uint64(f.Line))
+	}
+	return buf.String()
+}
+
+func writeVarint(buf *strings.Builder, x uint64) {
+	for x >= 0x80 {
+		buf.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	buf.WriteByte(byte(x))
+}
+
+// Write writes the profile to out as gzip-compressed profile.proto
+func (p *Profile) Write(out io.Writer) error {
+	if p.Duration == 0 {
+		p.Duration = time.Since(p.Start)
+	}
+	zout := gzip.NewWriter(out)
+	if _, err := zout.Write(p.encode()); err != nil {
+		zout.Close()
+		return err
+	}
+	return zout.Close()
+}
@@ -114,9 +114,12 @@ func unwrapTrivialAst2(in Ast, unwrapTrivialBlockStmt bool) Ast {
 			// to the variable 'x' so they are not equivalent.
 			//
 			// same reasoning for { x := foo() } versus x := foo()
+			//
+			// and for { L: foo() } versus L: foo() because goto L
+			// cannot jump into a block
 			child := form.Get(0)
 			switch child := child.(type) {
-			case DeclStmt:
+			case DeclStmt, LabeledStmt:
 				return in
 			case AssignStmt:
 				if child.Op() == token.DEFINE {
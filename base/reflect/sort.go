@@ -0,0 +1,61 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * sort.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package reflect
+
+import (
+	"fmt"
+	r "reflect"
+	"sort"
+)
+
+// MapKeys returns the keys of map v, sorted with LessValue
+func MapKeys(v r.Value) []r.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return LessValue(keys[i], keys[j])
+	})
+	return keys
+}
+
+// LessValue orders values by kind, then by value.
+// Values of the same kind that cannot be compared directly are ordered by their string representation
+func LessValue(a, b r.Value) bool {
+	for a.Kind() == r.Interface {
+		a = a.Elem()
+	}
+	for b.Kind() == r.Interface {
+		b = b.Elem()
+	}
+	if ka, kb := a.Kind(), b.Kind(); ka != kb {
+		return ka < kb
+	}
+	switch a.Kind() {
+	case r.Invalid:
+		return false
+	case r.Bool:
+		return !a.Bool() && b.Bool()
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		return a.Int() < b.Int()
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64, r.Uintptr:
+		return a.Uint() < b.Uint()
+	case r.Float32, r.Float64:
+		return a.Float() < b.Float()
+	case r.String:
+		return a.String() < b.String()
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
@@ -19,6 +19,8 @@ func init() {
 			"IsOptimizedKind":  r.ValueOf(IsOptimizedKind),
 			"Category":   r.ValueOf(Category),
 			"KindToType":       r.ValueOf(KindToType),
+			"LessValue":        r.ValueOf(LessValue),
+			"MapKeys":          r.ValueOf(MapKeys),
 			"Nil":              r.ValueOf(&Nil).Elem(),
 			"None":             r.ValueOf(&None).Elem(),
 			"PackTypes":        r.ValueOf(PackTypes),
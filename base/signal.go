@@ -58,6 +58,7 @@ const (
 	SigReturn
 	SigInterrupt // user pressed Ctrl+C, process received SIGINT, or similar
 	SigDebug     // debugger asked to execute in single-step mode
+	SigProfile   // profiler asked to sample the statement being executed
 
 	SigNone = Signal(0) // no signal
 	SigAll  = ^SigNone  // mask of all possible signals
@@ -76,6 +77,8 @@ func (sig Signal) String() string {
 		s = "// signal: interrupt"
 	case SigDebug:
 		s = "// signal: debug"
+	case SigProfile:
+		s = "// signal: profile"
 	default:
 		s = fmt.Sprintf("// signal: unknown(%d)", uint16(sig))
 	}
@@ -87,8 +90,20 @@ type Signals struct {
 	Debug Signal
 	Async Signal
 	_     Signal
+	// set by the profiler goroutine at each tick, access it only with Profile() and SetProfile()
+	profile uint32
 }
 
 func (s *Signals) IsEmpty() bool {
-	return atomic.LoadUint32((*uint32)(unsafe.Pointer(s))) == 0
+	return atomic.LoadUint32((*uint32)(unsafe.Pointer(s))) == 0 && atomic.LoadUint32(&s.profile) == 0
+}
+
+// Profile returns the pending profiler signal
+func (s *Signals) Profile() Signal {
+	return Signal(atomic.LoadUint32(&s.profile))
+}
+
+// SetProfile sets the pending profiler signal. Safe to call from any goroutine
+func (s *Signals) SetProfile(sig Signal) {
+	atomic.StoreUint32(&s.profile, uint32(sig))
 }
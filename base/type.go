@@ -51,6 +51,8 @@ const (
 	OptShowParse
 	OptShowPrompt
 	OptShowTime
+	OptBreakOnPanic // enter the debugger at the panicking statement, before the stack unwinds. requires OptDebugger
+	OptTypeCheck    // type-check code with go/types before compiling it, rejecting code that Go compilers reject
 )
 
 const (
@@ -86,6 +88,8 @@ var optNames = map[Options]string{
 	OptShowParse:           "Parse.Show",
 	OptShowPrompt:          "Prompt.Show",
 	OptShowTime:            "Time.Show",
+	OptBreakOnPanic:        "BreakOnPanic",
+	OptTypeCheck:           "TypeCheck",
 }
 
 var optValues = map[string]Options{}
@@ -19,6 +19,7 @@ package untyped
 import (
 	"go/constant"
 	"go/token"
+	"math"
 	"math/big"
 	r "reflect"
 	"unsafe"
@@ -349,47 +350,69 @@ func (untyp *Lit) DefaultType() xr.Type {
 // ======================= utilities for Convert =======================
 
 // extractNumber converts the untyped constant src to an integer, float or complex.
-// panics if src has different kind from constant.Int, constant.Float and constant.Complex
-// the receiver (untyp *Lit) and the second argument (t reflect.Type) are only used to pretty-print the panic error message
+// conversion is exact: panics if src is truncated or overflows t,
+// except for floating point rounding
+// the receiver (untyp *Lit) is only used to pretty-print the panic error message
 func (untyp *Lit) extractNumber(src constant.Value, t xr.Type) interface{} {
 	var n interface{}
-	cat := reflect.Category(t.Kind())
-	var exact bool
 	switch src.Kind() {
-	case constant.Int:
-		switch cat {
-		case r.Int:
-			n, exact = constant.Int64Val(src)
-		case r.Uint:
-			n, exact = constant.Uint64Val(src)
-		default:
-			n, exact = constant.Int64Val(src)
-			if !exact {
-				n, exact = constant.Uint64Val(src)
-			}
+	case constant.Int, constant.Float, constant.Complex:
+	default:
+		output.Errorf("cannot convert untyped constant %v to <%v>", untyp, t)
+		return nil
+	}
+	exact := true
+	switch cat := reflect.Category(t.Kind()); cat {
+	case r.Int, r.Uint:
+		i := constant.ToInt(src)
+		if i.Kind() != constant.Int {
+			output.Errorf("constant %v truncated to <%v>", untyp.Val, t)
+			return nil
 		}
-	case constant.Float:
-		n, exact = constant.Float64Val(src)
-	case constant.Complex:
-		re := untyp.extractNumber(constant.Real(src), t)
-		im := untyp.extractNumber(constant.Imag(src), t)
-		rfloat := r.ValueOf(re).Convert(reflect.TypeOfFloat64).Float()
-		ifloat := r.ValueOf(im).Convert(reflect.TypeOfFloat64).Float()
-		n = complex(rfloat, ifloat)
-		exact = true
+		if cat == r.Int {
+			n, exact = constant.Int64Val(i)
+		} else {
+			n, exact = constant.Uint64Val(i)
+		}
+	case r.Float64:
+		f := constant.ToFloat(src)
+		if f.Kind() != constant.Float {
+			output.Errorf("constant %v truncated to <%v>", untyp.Val, t)
+			return nil
+		}
+		n, exact = floatVal(f, t.Kind() == r.Float32)
+	case r.Complex128:
+		c := constant.ToComplex(src)
+		single := t.Kind() == r.Complex64
+		re, rexact := floatVal(constant.Real(c), single)
+		im, iexact := floatVal(constant.Imag(c), single)
+		n, exact = complex(re, im), rexact && iexact
 	default:
 		output.Errorf("cannot convert untyped constant %v to <%v>", untyp, t)
 		return nil
 	}
-	// allow inexact conversions to float64 and complex128:
-	// floating point is intrinsically inexact, and Go compiler allows them too
-	if !exact && (cat == r.Int || cat == r.Uint) {
-		output.Errorf("untyped constant %v overflows <%v>", untyp, t)
+	// exact is false only on overflow: floating point rounding is allowed,
+	// since floating point is intrinsically inexact, and Go compiler allows it too
+	if !exact {
+		output.Errorf("constant %v overflows <%v>", untyp.Val, t)
 		return nil
 	}
 	return n
 }
 
+// floatVal rounds the constant f to float64, or to float32 if single is true.
+// returns false if f overflows
+func floatVal(f constant.Value, single bool) (float64, bool) {
+	var x float64
+	if single {
+		x32, _ := constant.Float32Val(f)
+		x = float64(x32)
+	} else {
+		x, _ = constant.Float64Val(f)
+	}
+	return x, !math.IsInf(x, 0)
+}
+
 // ConvertLiteralCheckOverflow converts a literal to type t and returns the converted value.
 // panics if the conversion overflows the given type
 func ConvertLiteralCheckOverflow(src interface{}, to xr.Type) interface{} {
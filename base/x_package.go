@@ -27,6 +27,10 @@ func init() {
 		"IsGensymAnonymous":	r.ValueOf(IsGensymAnonymous),
 		"IsGensymInterface":	r.ValueOf(IsGensymInterface),
 		"IsGensymPrivate":	r.ValueOf(IsGensymPrivate),
+		"MIMEHTML":	r.ValueOf(MIMEHTML),
+		"MIMEJSON":	r.ValueOf(MIMEJSON),
+		"MIMEPNG":	r.ValueOf(MIMEPNG),
+		"MIMEText":	r.ValueOf(MIMEText),
 		"MakeBufReadline":	r.ValueOf(MakeBufReadline),
 		"MakeNestedQuote":	r.ValueOf(MakeNestedQuote),
 		"MakeQuote":	r.ValueOf(MakeQuote),
@@ -40,6 +44,7 @@ func init() {
 		"Nil":	r.ValueOf(&Nil).Elem(),
 		"None":	r.ValueOf(&None).Elem(),
 		"One":	r.ValueOf(&One).Elem(),
+		"OptBreakOnPanic":	r.ValueOf(OptBreakOnPanic),
 		"OptCollectDeclarations":	r.ValueOf(OptCollectDeclarations),
 		"OptCollectStatements":	r.ValueOf(OptCollectStatements),
 		"OptCtrlCEnterDebugger":	r.ValueOf(OptCtrlCEnterDebugger),
@@ -66,6 +71,7 @@ func init() {
 		"OptShowPrompt":	r.ValueOf(OptShowPrompt),
 		"OptShowTime":	r.ValueOf(OptShowTime),
 		"OptTrapPanic":	r.ValueOf(OptTrapPanic),
+		"OptTypeCheck":	r.ValueOf(OptTypeCheck),
 		"ParseOptions":	r.ValueOf(ParseOptions),
 		"ReadBytes":	r.ValueOf(ReadBytes),
 		"ReadMultiline":	r.ValueOf(ReadMultiline),
@@ -77,6 +83,7 @@ func init() {
 		"SigDefer":	r.ValueOf(SigDefer),
 		"SigInterrupt":	r.ValueOf(SigInterrupt),
 		"SigNone":	r.ValueOf(SigNone),
+		"SigProfile":	r.ValueOf(SigProfile),
 		"SigReturn":	r.ValueOf(SigReturn),
 		"SimplifyAstForQuote":	r.ValueOf(SimplifyAstForQuote),
 		"SimplifyNodeForQuote":	r.ValueOf(SimplifyNodeForQuote),
@@ -136,12 +143,16 @@ func init() {
 	}, Types: map[string]r.Type{
 		"BufReadline":	r.TypeOf((*BufReadline)(nil)).Elem(),
 		"CmdOpt":	r.TypeOf((*CmdOpt)(nil)).Elem(),
+		"Display":	r.TypeOf((*Display)(nil)).Elem(),
 		"Globals":	r.TypeOf((*Globals)(nil)).Elem(),
+		"HTMLer":	r.TypeOf((*HTMLer)(nil)).Elem(),
 		"Inspector":	r.TypeOf((*Inspector)(nil)).Elem(),
+		"MIMEBundle":	r.TypeOf((*MIMEBundle)(nil)).Elem(),
 		"Options":	r.TypeOf((*Options)(nil)).Elem(),
 		"Output":	r.TypeOf((*Output)(nil)).Elem(),
 		"ReadOptions":	r.TypeOf((*ReadOptions)(nil)).Elem(),
 		"Readline":	r.TypeOf((*Readline)(nil)).Elem(),
+		"Renderer":	r.TypeOf((*Renderer)(nil)).Elem(),
 		"Signal":	r.TypeOf((*Signal)(nil)).Elem(),
 		"Signals":	r.TypeOf((*Signals)(nil)).Elem(),
 		"TtyReadline":	r.TypeOf((*TtyReadline)(nil)).Elem(),
@@ -152,6 +163,10 @@ func init() {
 	}, Untypeds: map[string]string{
 		"CmdOptForceEval":	"int:2",
 		"CmdOptQuit":	"int:1",
+		"MIMEHTML":	"string:text/html",
+		"MIMEJSON":	"string:application/json",
+		"MIMEPNG":	"string:image/png",
+		"MIMEText":	"string:text/plain",
 	}, Wrappers: map[string][]string{
 		"Globals":	[]string{"Copy","Debugf","Error","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Sprintf","ToString","WarnExtraValues","Warnf",},
 		"Output":	[]string{"Copy","ErrorAt","Errorf","Fprintf","IncLine","IncLineBytes","MakeRuntimeError","Position","Sprintf","ToString",},
@@ -66,13 +66,62 @@ func (cmd *Cmd) Main(args []string) (err error) {
 
 	var set, clear Options
 	var repl, forcerepl = true, false
+	var coverfile, covermode string
 	cmd.WriteDeclsAndStmts = false
 	cmd.OverwriteFiles = false
 
 	for len(args) > 0 {
+		if arg := args[0]; strings.HasPrefix(arg, "--") {
+			// convert --option=value to --option value
+			if eq := strings.IndexByte(arg, '='); eq > 0 {
+				args = append([]string{arg[:eq], arg[eq+1:]}, args[1:]...)
+			}
+		}
 		switch args[0] {
 		case "-c", "--collect":
 			g.Options |= OptCollectDeclarations | OptCollectStatements
+		case "--cover":
+			if len(args) > 1 {
+				if len(coverfile) == 0 {
+					if err := ir.StartCoverage(covermode); err != nil {
+						return err
+					}
+					defer func() {
+						if err1 := ir.WriteCoverProfileFile(coverfile); err == nil {
+							err = err1
+						}
+					}()
+				}
+				coverfile = args[1]
+				args = args[1:]
+			}
+		case "--covermode":
+			if len(args) > 1 {
+				covermode = args[1]
+				if len(coverfile) != 0 {
+					if err := ir.StartCoverage(covermode); err != nil {
+						return err
+					}
+				}
+				args = args[1:]
+			}
+		case "--cpuprofile":
+			if len(args) > 1 {
+				if err := ir.StartCPUProfileFile(args[1]); err != nil {
+					return err
+				}
+				defer func() {
+					if err1 := ir.StopCPUProfile(); err == nil {
+						err = err1
+					}
+				}()
+				args = args[1:]
+			}
+		case "--trace":
+			if len(args) > 1 {
+				ir.Trace(args[1])
+				args = args[1:]
+			}
 		case "-e", "--expr":
 			if len(args) > 1 {
 				repl = false
@@ -108,6 +157,9 @@ func (cmd *Cmd) Main(args []string) (err error) {
 		case "-n", "--no-trap":
 			set &^= OptTrapPanic | OptPanicStackTrace
 			clear |= OptTrapPanic | OptPanicStackTrace
+		case "--strict":
+			set |= OptTypeCheck
+			clear &^= OptTypeCheck
 		case "-t", "--trap":
 			set |= OptTrapPanic | OptPanicStackTrace
 			clear &= OptTrapPanic | OptPanicStackTrace
@@ -136,7 +188,9 @@ func (cmd *Cmd) Main(args []string) (err error) {
 			}
 			g.Options &^= OptShowPrompt | OptShowEval | OptShowEvalType // cleared by default, overridden by -s, -v and -vv
 			g.Options = (g.Options | set) &^ clear
-			cmd.EvalFileOrDir(arg)
+			if err := cmd.EvalFileOrDir(arg); err != nil {
+				return err
+			}
 
 			g.Imports, g.Declarations, g.Statements = nil, nil, nil
 		}
@@ -156,6 +210,11 @@ func (cmd *Cmd) Usage() error {
 
   Recognized options:
     -c,   --collect          collect declarations and statements, to print them later
+          --cover FILE       count the executions of interpreted statements and write them to FILE,
+                             to be analyzed with 'go tool cover -html=FILE'
+          --covermode MODE   set coverage mode for --cover: set (default) or count
+          --cpuprofile FILE  sample interpreted code and write a profile to FILE,
+                             to be analyzed with 'go tool pprof FILE'
     -e,   --expr EXPR        evaluate expression
     -f,   --force-overwrite  option -w will overwrite existing files
     -g,   --genimport [PATH] write x_package.go bindings for specified import path and exit.
@@ -167,7 +226,11 @@ func (cmd *Cmd) Usage() error {
     -m,   --macro-only       do not execute code, only parse and macroexpand it.
                              useful to run gomacro as a Go preprocessor
     -n,   --no-trap          do not trap panics in the interpreter
+          --strict           type-check code with go/types before executing it,
+                             and reject the code that Go compilers reject
     -t,   --trap             trap panics in the interpreter (default)
+          --trace PATTERN    log entry and exit of interpreted functions matching PATTERN,
+                             as fib, main.fib, T.String or "T.*". can be repeated
     -s,   --silent           silent. do NOT show startup message, prompt, and expressions results.
                              default when executing files and dirs.
     -v,   --verbose          verbose. show startup message, prompt, and expressions results.
@@ -179,6 +242,7 @@ func (cmd *Cmd) Usage() error {
     -x,   --exec             execute parsed code (default). disabled by -m
 
     Options are processed in order, except for -i that is always processed as last.
+    Long options also accept the syntax --option=VALUE
 
     Collected declarations and statements can be also written to standard output
     or to a file with the REPL command :write
@@ -208,7 +272,16 @@ func (cmd *Cmd) EvalFileOrDir(fileOrDir string) error {
 	}
 }
 
+// EvalDir evaluates the Go files in dirname in batch mode, as a single package,
+// then the *.gomacro files one by one.
+// If cmd.WriteDeclsAndStmts is set, only *.gomacro files are evaluated:
+// the *.go files in dirname may be the ones generated from them
 func (cmd *Cmd) EvalDir(dirname string) error {
+	if !cmd.WriteDeclsAndStmts {
+		if _, err := cmd.Interp.EvalDir(dirname); err != nil {
+			return err
+		}
+	}
 	files, err := ioutil.ReadDir(dirname)
 	if err != nil {
 		return err
@@ -86,7 +86,11 @@ func (c *Comp) Assign(node *ast.AssignStmt) {
 		canreorder = false
 	} else {
 		for i, ri := range rhs {
-			exprs[i] = c.Expr1(ri, nil)
+			var t xr.Type
+			if node.Tok != token.SHL_ASSIGN && node.Tok != token.SHR_ASSIGN {
+				t = places[i].Type
+			}
+			exprs[i] = c.exprContext(ri, t)
 			canreorder = canreorder && exprs[i].Const()
 		}
 	}
@@ -128,7 +132,7 @@ func (c *Comp) assignPrepareRhs(node *ast.AssignStmt, places []*Place, exprs []*
 		exprfuns := make([]func(*Env) r.Value, rn)
 		for i, expr := range exprs {
 			tplace := places[i].Type
-			if expr.Const() {
+			if expr.Const() && !expr.constNeedsConverter(tplace) {
 				expr.ConstTo(tplace)
 			} else if expr.Type.AssignableTo(tplace) {
 				expr.To(c, tplace)
@@ -300,6 +304,9 @@ func (c *Comp) assign1(lhs ast.Expr, op token.Token, rhs ast.Expr, place *Place,
 	c.Pos = lhs.Pos()
 	// c.Debugf("compiling assign1 at [% 3d] %s: %v // %T", c.Pos, c.Fileset.Position(c.Pos), lhs, lhs)
 
+	if op == token.ASSIGN && init.constNeedsConverter(place.Type) {
+		init.To(c, place.Type)
+	}
 	if place.IsVar() {
 		c.SetVar(&place.Var, op, init)
 	} else {
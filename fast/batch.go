@@ -0,0 +1,221 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * batch.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/scanner"
+	"go/token"
+	"io/ioutil"
+	"strings"
+
+	"github.com/steele232/zoumacro/ast2"
+	"github.com/steele232/zoumacro/base/output"
+	"github.com/steele232/zoumacro/base/paths"
+)
+
+// batch mode: all the files of a package are parsed before compiling them,
+// and their package-level declarations are sorted together by dep.Sorter,
+// as Go does. Thus declarations can appear in any order, and in any file.
+//
+// Since the whole source is parsed before executing it,
+// macros declared in batch mode cannot be used in the same batch.
+
+// batch partitions the top-level nodes of one or more files
+type batch struct {
+	pkg     *ast.GenDecl // package clause of the first file, only used to check the others
+	imports []ast.Spec
+	decls   []ast.Node
+	inits   []ast.Node // init() functions, converted to statements that call them
+	stmts   []ast.Node
+	seen    map[string]bool // imports already added
+}
+
+// EvalFiles reads, compiles and executes the files in batch mode.
+// Returns the comments at the beginning of the first file
+func (ir *Interp) EvalFiles(filenames ...string) (comments string, err error) {
+	g := ir.Comp.CompGlobals
+	saveFilepath, saveLine := g.Filepath, g.Line
+	defer func() {
+		g.Filepath, g.Line = saveFilepath, saveLine
+		if rec := recover(); rec != nil {
+			switch rec := rec.(type) {
+			case error:
+				err = rec
+			default:
+				err = errors.New(fmt.Sprint(rec))
+			}
+		}
+	}()
+	b := batch{seen: make(map[string]bool)}
+	for i, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			comments = leadingComments(src)
+		}
+		g.Filepath, g.Line = filename, 0
+		b.addAst(ir.Parse(string(src)))
+	}
+	ir.RunExpr(ir.compileAst(b.toAst(), true))
+	return comments, nil
+}
+
+// EvalDir evaluates in batch mode the Go files in directory dirname,
+// excluding tests and the files that do not match the current build constraints.
+// See EvalFiles
+func (ir *Interp) EvalDir(dirname string) (comments string, err error) {
+	files, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return "", err
+	}
+	var filenames []string
+	for _, file := range files {
+		filename := file.Name()
+		if file.IsDir() || !strings.HasSuffix(filename, ".go") || strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dirname, filename); err != nil {
+			return "", err
+		} else if match {
+			filenames = append(filenames, paths.Subdir(dirname, filename))
+		}
+	}
+	if len(filenames) == 0 {
+		return "", nil
+	}
+	return ir.EvalFiles(filenames...)
+}
+
+// return the comments before the first token in src
+func leadingComments(src []byte) string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, _ := s.Scan()
+		if tok != token.COMMENT {
+			return string(src[:file.Offset(pos)])
+		}
+	}
+}
+
+func (b *batch) addAst(form ast2.Ast) {
+	switch form := form.(type) {
+	case nil:
+	case ast2.AstWithNode:
+		b.addNode(form.Node())
+	case ast2.AstWithSlice:
+		for i, n := 0, form.Size(); i < n; i++ {
+			b.addAst(form.Get(i))
+		}
+	}
+}
+
+func (b *batch) addNode(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+	case *ast.File:
+		for _, decl := range node.Decls {
+			b.addNode(decl)
+		}
+	case *ast.GenDecl:
+		switch node.Tok {
+		case token.PACKAGE:
+			b.addPackage(node)
+		case token.IMPORT:
+			for _, spec := range node.Specs {
+				b.addImport(spec)
+			}
+		default:
+			b.decls = append(b.decls, node)
+		}
+	case *ast.FuncDecl:
+		if node.Recv == nil && node.Name.Name == "init" && node.Body != nil {
+			// Go allows multiple init() functions, and they cannot be referenced:
+			// call them after all package-level variables are initialized
+			call := &ast.CallExpr{Fun: &ast.FuncLit{Type: node.Type, Body: node.Body}}
+			b.inits = append(b.inits, &ast.ExprStmt{X: call})
+		} else {
+			b.decls = append(b.decls, node)
+		}
+	case ast.Decl:
+		b.decls = append(b.decls, node)
+	default:
+		b.stmts = append(b.stmts, node)
+	}
+}
+
+// keep the package clause of the first file. the others must declare the same package
+func (b *batch) addPackage(node *ast.GenDecl) {
+	if b.pkg == nil {
+		b.pkg = node
+	} else if name1, name2 := packageName(b.pkg), packageName(node); name1 != name2 {
+		output.Errorf("found packages %s and %s", name1, name2)
+	}
+}
+
+// return the name or path declared by a package clause
+func packageName(node *ast.GenDecl) string {
+	for _, spec := range node.Specs {
+		if spec, ok := spec.(*ast.ValueSpec); ok {
+			if len(spec.Names) != 0 {
+				return spec.Names[0].Name
+			} else if len(spec.Values) != 0 {
+				if lit, ok := spec.Values[0].(*ast.BasicLit); ok {
+					return lit.Value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// add an import, unless the same import was already added by another file
+func (b *batch) addImport(spec ast.Spec) {
+	if spec, ok := spec.(*ast.ImportSpec); ok {
+		key := spec.Path.Value
+		if spec.Name != nil {
+			key = spec.Name.Name + " " + key
+		}
+		if b.seen[key] {
+			return
+		}
+		b.seen[key] = true
+	}
+	b.imports = append(b.imports, spec)
+}
+
+// return the nodes of the batch in the order expected by dep.Sorter:
+// imports, declarations, then statements.
+// As at REPL, the package clause has no effect: declarations are added to the current package
+func (b *batch) toAst() ast2.Ast {
+	var nodes []ast.Node
+	if len(b.imports) != 0 {
+		nodes = append(nodes, &ast.GenDecl{Tok: token.IMPORT, Specs: b.imports})
+	}
+	nodes = append(nodes, b.decls...)
+	nodes = append(nodes, b.inits...)
+	nodes = append(nodes, b.stmts...)
+	return ast2.NodeSlice{X: nodes}
+}
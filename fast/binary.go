@@ -25,12 +25,70 @@ import (
 	"github.com/steele232/zoumacro/base/reflect"
 	"github.com/steele232/zoumacro/base/untyped"
 	mt "github.com/steele232/zoumacro/token"
+	xr "github.com/steele232/zoumacro/xreflect"
 )
 
 func (c *Comp) BinaryExpr(node *ast.BinaryExpr) *Expr {
-	x := c.Expr1(node.X, nil)
-	y := c.Expr1(node.Y, nil)
-	return c.BinaryExpr1(node, x, y)
+	return c.binaryExpr(node, nil)
+}
+
+// binaryExpr compiles a binary expression.
+// t is the type expected by the context, see Comp.exprContext()
+func (c *Comp) binaryExpr(node *ast.BinaryExpr, t xr.Type) *Expr {
+	t = numericContext(t)
+	op := node.Op
+	switch op {
+	case token.SHL, token.SHR:
+		x := c.exprContext(node.X, t)
+		y := c.Expr1(node.Y, nil)
+		if !x.Untyped() || y.Const() {
+			z := c.BinaryExpr1(node, x, y)
+			z.EFlags |= x.EFlags & EIsUntypedShift
+			return z
+		}
+		// Go specs: "If the left operand of a non-constant shift expression is an untyped constant,
+		// it is first implicitly converted to the type it would assume
+		// if the shift expression were replaced by its left operand alone"
+		c.untypedShiftOperand(node, x, t)
+		z := c.BinaryExpr1(node, x, y)
+		if t == nil {
+			// type still depends on the context
+			z.EFlags |= EIsUntypedShift
+		}
+		return z
+	case token.LAND, token.LOR:
+		t = nil
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		// comparisons produce an untyped bool, they give no type to their operands
+		t = nil
+	}
+	x := c.exprContext(node.X, t)
+	ty := t
+	if !x.Untyped() && !x.IsUntypedShift() && op != token.LAND && op != token.LOR {
+		ty = x.Type
+	}
+	y := c.exprContext(node.Y, ty)
+	if x.IsUntypedShift() {
+		if !y.Untyped() && !y.IsUntypedShift() {
+			// x takes its type from y
+			x = c.exprContext(node.X, y.Type)
+		} else if t == nil && y.Untyped() && y.Value.(UntypedLit).Kind > untyped.Int {
+			// x takes its type from untyped y, as in 1<<s == 1.0
+			x = c.exprContext(node.X, y.DefaultType())
+		}
+	} else if y.IsUntypedShift() && t == nil && x.Untyped() && x.Value.(UntypedLit).Kind > untyped.Int {
+		y = c.exprContext(node.Y, x.DefaultType())
+	}
+	z := c.BinaryExpr1(node, x, y)
+	if t == nil && (x.IsUntypedShift() || y.IsUntypedShift()) {
+		switch op {
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ, token.LAND, token.LOR:
+		default:
+			// type still depends on the context
+			z.EFlags |= EIsUntypedShift
+		}
+	}
+	return z
 }
 
 func (c *Comp) BinaryExpr1(node *ast.BinaryExpr, x *Expr, y *Expr) *Expr {
@@ -38,6 +96,13 @@ func (c *Comp) BinaryExpr1(node *ast.BinaryExpr, x *Expr, y *Expr) *Expr {
 		return c.BinaryExprUntyped(node, x.Value.(UntypedLit), y.Value.(UntypedLit))
 	}
 	bothConst := x.Const() && y.Const()
+	switch node.Op {
+	case token.QUO, token.QUO_ASSIGN, token.REM, token.REM_ASSIGN:
+		// division by constant zero is an error for integers, and for constants
+		if isConstantZero(y) && (x.Const() || reflect.IsCategory(x.Type.Kind(), r.Int, r.Uint)) {
+			c.Errorf("division by zero: %v", node)
+		}
+	}
 	var z *Expr
 
 	switch node.Op {
@@ -83,7 +148,10 @@ func (c *Comp) BinaryExpr1(node *ast.BinaryExpr, x *Expr, y *Expr) *Expr {
 		return c.unimplementedBinaryExpr(node, x, y)
 	}
 	if bothConst {
-		// constant propagation
+		// constant propagation. compute numeric constants exactly, to detect overflows
+		if val := c.constantBinaryOp(node, node.Op, x.Value, y.Value, z.Type); val != nil {
+			return c.exprValue(z.Type, val)
+		}
 		z.EvalConst(COptKeepUntyped)
 	}
 	return z
@@ -113,6 +181,9 @@ func (c *Comp) BinaryExprUntyped(node *ast.BinaryExpr, x UntypedLit, y UntypedLi
 		op2 := tokenWithoutAssign(op)
 		xint := x.Kind == untyped.Int || x.Kind == untyped.Rune
 		yint := y.Kind == untyped.Int || y.Kind == untyped.Rune
+		if (op2 == token.QUO || op2 == token.REM) && isConstantZero(&Expr{Lit: Lit{Value: y}}) {
+			c.Errorf("division by zero: %v", node)
+		}
 		if op2 == token.QUO && xint && yint {
 			// untyped integer division
 			op2 = token.QUO_ASSIGN
@@ -163,59 +234,39 @@ func tokenWithoutAssign(op token.Token) token.Token {
 	return op
 }
 
-var warnUntypedShift, warnUntypedShift2 = true, true
-
 func (c *Comp) ShiftUntyped(node *ast.BinaryExpr, op token.Token, x UntypedLit, y UntypedLit) *Expr {
-	var yn64 uint64
-	var exact bool
-
-	switch y.Val.Kind() {
-	case constant.Int:
-		yn64, exact = constant.Uint64Val(y.Val)
-	case constant.Float:
-		yf, fexact := constant.Float64Val(y.Val)
-		if fexact {
-			yn64 = uint64(yf)
-			exact = float64(yn64) == yf
-		}
-		// c.Debugf("ShiftUntyped: %v %v %v, rhs converted to %v <float64> => %v <uint64> (exact = %v)", x.Val, op, y.Val, yf, yn64, exact)
-	}
-	if !exact {
-		c.Errorf("invalid shift: %v %v %v", x.Val.ExactString(), op, y.Val.ExactString())
-	}
-	yn := uint(yn64)
-	if uint64(yn) != yn64 {
-		c.Errorf("invalid shift: %v %v %v", x.Val.ExactString(), op, y.Val.ExactString())
-	}
-	xn := x.Val
-	xkind := x.Kind
-	switch xkind {
-	case untyped.Int, untyped.Rune:
-		// nothing to do
-	case untyped.Float, untyped.Complex:
-		if warnUntypedShift {
-			c.Warnf("known limitation (warned only once): untyped floating point constant shifted by untyped constant. returning untyped integer instead of deducing the type from the surrounding context: %v",
-				node)
-			warnUntypedShift = false
-		}
-		sign := constant.Sign(xn)
-		if xkind == untyped.Complex {
-			sign = constant.Sign(constant.Real(xn))
-		}
-		if sign >= 0 {
-			xn = constant.MakeUint64(x.Convert(c.TypeOfUint64()).(uint64))
-		} else {
-			xn = constant.MakeInt64(x.Convert(c.TypeOfInt64()).(int64))
-		}
-		xkind = untyped.Int
-	default:
-		c.Errorf("invalid shift: %v %v %v", x.Val, op, y.Val)
+	yn := c.shiftCount(node, y.Val)
+	// Go specs: "If the left operand of a constant shift expression is an untyped constant,
+	// the result is an integer constant"
+	xn := constant.ToInt(x.Val)
+	if xn.Kind() != constant.Int {
+		c.Errorf("invalid shift: shifted operand %v must be integer: %v", x.Val, node)
+	}
+	zkind := x.Kind
+	if zkind != untyped.Rune {
+		zkind = untyped.Int
 	}
 	zobj := constant.Shift(xn, op, yn)
 	if zobj.Kind() == constant.Unknown {
 		c.Errorf("invalid shift: %v %v %v", x.Val, op, y.Val)
 	}
-	return c.exprUntypedLit(xkind, zobj)
+	return c.exprUntypedLit(zkind, zobj)
+}
+
+// untypedShiftOperand converts xe, the untyped left operand of a non-constant shift,
+// to the type t expected by the context or, if t is nil, to its default type
+func (c *Comp) untypedShiftOperand(node *ast.BinaryExpr, xe *Expr, t xr.Type) {
+	lit := xe.Value.(UntypedLit)
+	if constant.ToInt(lit.Val).Kind() != constant.Int {
+		c.Errorf("invalid shift: shifted operand %v must be integer: %v", lit.Val, node)
+	}
+	if t == nil {
+		t = lit.DefaultType()
+	}
+	if !reflect.IsCategory(t.Kind(), r.Int, r.Uint) {
+		c.Errorf("invalid shift: shifted operand %v <%v> must be integer: %v", lit.Val, t, node)
+	}
+	xe.ConstTo(t)
 }
 
 // prepareShift panics if the types of xe and ye are not valid for shifts i.e. << or >>
@@ -225,35 +276,25 @@ func (c *Comp) prepareShift(node *ast.BinaryExpr, xe *Expr, ye *Expr) *Expr {
 		// untyped << untyped should not happen here, it's handled in Comp.BinaryExpr... but let's be safe
 		return c.ShiftUntyped(node, node.Op, xe.Value.(UntypedLit), ye.Value.(UntypedLit))
 	}
-	xet, yet := xe.DefaultType(), ye.DefaultType()
-	if xet == nil || !reflect.IsCategory(xet.Kind(), r.Int, r.Uint) {
-		return c.invalidBinaryExpr(node, xe, ye)
-	}
 	if xe.Untyped() {
 		xuntyp := xe.Value.(UntypedLit)
-		if ye.Const() {
+		if yval := constantOf(ye.Value); ye.Const() && yval != nil {
 			// untyped << constant
-			yuntyp := untyped.MakeLit(untyped.Int, constant.MakeUint64(r.ValueOf(ye.Value).Uint()), &c.Universe.BasicTypes)
+			yuntyp := untyped.MakeLit(untyped.Int, yval, &c.Universe.BasicTypes)
 			return c.ShiftUntyped(node, node.Op, xuntyp, yuntyp)
 		}
-		// untyped << expression
-		// BUG! we should deduce left operand type from its context, instead of assuming int
-		// see https://golang.org/ref/spec#Operators
-		//
-		// "If the left operand of a non-constant shift expression is an untyped constant,
-		// "it is first converted to the type it would assume if the shift expression
-		// "were replaced by its left operand alone."
-		if warnUntypedShift2 {
-			c.Warnf("known limitation (warned only once): untyped constant shifted by a non-constant expression. returning int instead of deducing the type from the surrounding context: %v",
-				node)
-			warnUntypedShift2 = false
-		}
-		xe.ConstTo(c.TypeOfInt())
+		// untyped << expression. Comp.binaryExpr() already converted
+		// the left operand to the type expected by the context, if any
+		c.untypedShiftOperand(node, xe, nil)
+	}
+	xet, yet := xe.DefaultType(), ye.DefaultType()
+	if xet == nil || !reflect.IsCategory(xet.Kind(), r.Int, r.Uint) {
+		return c.invalidBinaryExpr(node, xe, ye)
 	}
 	if ye.Untyped() {
 		// untyped constants do not distinguish between int and uint
-		if yet == nil || !reflect.IsCategory(yet.Kind(), r.Int) {
-			return c.invalidBinaryExpr(node, xe, ye)
+		if yval := constant.ToInt(ye.Value.(UntypedLit).Val); yval.Kind() == constant.Int && constant.Sign(yval) < 0 {
+			c.Errorf("invalid negative shift count %v: %v", yval, node)
 		}
 		ye.ConstTo(c.TypeOfUint64())
 	} else {
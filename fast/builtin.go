@@ -21,7 +21,7 @@ import (
 	"go/ast"
 	"go/constant"
 	"go/token"
-	"os"
+	"io"
 	r "reflect"
 
 	"github.com/steele232/zoumacro/base/reflect"
@@ -122,17 +122,23 @@ func (ce *Interp) addBuiltins() {
 	}
 	ce.DeclTypeAlias("byte", c.TypeOfUint8())
 	ce.DeclTypeAlias("rune", c.TypeOfInt32())
+	ce.DeclTypeAlias("any", c.TypeOfInterface())
 	ce.DeclType(c.TypeOfError())
 
-	/*
-		// --------- proxies ---------
-		if env.Proxies == nil {
-			env.Proxies = make(map[string]Type)
-		}
-		proxies := env.Proxies
+	// --------- proxies ---------
+	c.loadProxies(
+		map[string]r.Type{"error": r.TypeOf((*P_error)(nil)).Elem()},
+		map[string]xr.Type{"error": c.TypeOfError()})
+}
 
-		proxies["error", TypeOf(*Error_builtin)(nil)).Elem()
-	*/
+// --------------- proxy for error ---------------
+type P_error struct {
+	Object interface{}
+	Error_ func(interface{}) string
+}
+
+func (P *P_error) Error() string {
+	return P.Error_(P.Object)
 }
 
 // ============================= builtin functions =============================
@@ -698,15 +704,13 @@ func callParse(argv r.Value, interpv r.Value) r.Value {
 
 // --- print(), println() ---
 
-func callPrint(args ...I) {
-	w := os.Stderr
+func callPrint(w io.Writer, args ...I) {
 	for _, arg := range args {
 		fmt.Fprint(w, arg)
 	}
 }
 
-func callPrintln(args ...I) {
-	w := os.Stderr
+func callPrintln(w io.Writer, args ...I) {
 	n := len(args)
 	if n > 1 {
 		for _, arg := range args[:n-1] {
@@ -726,12 +730,18 @@ func compilePrint(c *Comp, sym Symbol, node *ast.CallExpr) *Call {
 		arg.To(c, c.TypeOfInterface())
 	}
 
-	t := c.TypeOf(callPrint)
-	sym.Type = t
-	call := callPrint
+	// print and println write to the stderr set by Interp.SetStdio(), if any
+	g := c.IrGlobals
+	call := func(args ...I) {
+		callPrint(g.stderr(), args...)
+	}
 	if sym.Name == "println" {
-		call = callPrintln
+		call = func(args ...I) {
+			callPrintln(g.stderr(), args...)
+		}
 	}
+	t := c.TypeOf(call)
+	sym.Type = t
 	fun := exprLit(Lit{Type: t, Value: call}, &sym)
 	return &Call{Fun: fun, Args: args, OutTypes: zeroTypes, Const: false, Ellipsis: node.Ellipsis != token.NoPos}
 }
@@ -850,6 +860,7 @@ func callRecover(v r.Value) r.Value {
 	// consume the current panic
 	run.Panic = nil
 	run.PanicFun = nil
+	run.resetPanicDebug()
 	return v
 }
 
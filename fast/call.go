@@ -84,7 +84,8 @@ func (c *Comp) prepareCall(node *ast.CallExpr, fun *Expr) *Call {
 	}
 	// compile args early, and use them to infer template function instantiation
 	var args []*Expr
-	if len(node.Args) == 1 {
+	ellipsis := node.Ellipsis != token.NoPos
+	if len(node.Args) == 1 && !isOperation(node.Args[0]) {
 		// support foo(bar()) where bar() returns multiple values
 		arg := c.Expr(node.Args[0], nil)
 		if arg.NumOut() == 0 {
@@ -92,7 +93,7 @@ func (c *Comp) prepareCall(node *ast.CallExpr, fun *Expr) *Call {
 		}
 		args = []*Expr{arg}
 	} else {
-		args = c.Exprs(node.Args)
+		args = c.exprsContext(node.Args, paramTypes(t, len(node.Args), ellipsis))
 	}
 	if lastarg != nil {
 		args = append(args, lastarg)
@@ -110,7 +111,12 @@ func (c *Comp) prepareCall(node *ast.CallExpr, fun *Expr) *Call {
 		c.Errorf("call of non-function: %v <%v>", node.Fun, t)
 		return nil
 	}
-	ellipsis := node.Ellipsis != token.NoPos
+	if !ellipsis && !builtin {
+		// fmt functions cannot see the names of interpreted types: help them
+		fun = c.hookFmt(node, fun, args)
+		// compiled functions cannot see the methods of interpreted types: help them
+		c.proxyArgs(node, t, args)
+	}
 	c.checkCallArgs(node, t, args, ellipsis)
 
 	outn := t.NumOut()
@@ -121,6 +127,43 @@ func (c *Comp) prepareCall(node *ast.CallExpr, fun *Expr) *Call {
 	return &Call{Fun: fun, Args: args, OutTypes: outtypes, Builtin: builtin, Ellipsis: ellipsis}
 }
 
+// return true if node is a unary or binary operation, possibly in parentheses
+func isOperation(node ast.Expr) bool {
+	for {
+		switch n := node.(type) {
+		case *ast.ParenExpr:
+			node = n.X
+		case *ast.BinaryExpr, *ast.UnaryExpr:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// return the types of the first n params of function type t,
+// to be used as context for compiling call arguments.
+// returns nil if t is not a function type
+func paramTypes(t xr.Type, n int, ellipsis bool) []xr.Type {
+	if t.Kind() != r.Func {
+		return nil
+	}
+	types := make([]xr.Type, n)
+	nin := t.NumIn()
+	variadic := t.IsVariadic()
+	for i := range types {
+		if variadic && i >= nin-1 {
+			types[i] = t.In(nin - 1)
+			if !ellipsis {
+				types[i] = types[i].Elem()
+			}
+		} else if i < nin {
+			types[i] = t.In(i)
+		}
+	}
+	return types
+}
+
 // call_any emits a compiled function call
 func (c *Comp) call_any(call *Call) *Expr {
 	expr := &Expr{}
@@ -224,7 +267,7 @@ func (c *Comp) checkCallArgs(node *ast.CallExpr, t xr.Type, args []*Expr, ellips
 		}
 		// one argument per parameter: foo(arg1, arg2 /*...*/)
 		arg := args[i]
-		if arg.Const() {
+		if arg.Const() && !arg.constNeedsConverter(ti) {
 			arg.ConstTo(ti)
 		} else if arg.Type == nil || !arg.Type.AssignableTo(ti) {
 			c.Errorf("cannot use <%v> as <%v> in argument to %v", arg.Type, ti, node.Fun)
@@ -25,6 +25,7 @@ import (
 	"github.com/steele232/zoumacro/base/paths"
 
 	"github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/base/output"
 	bstrings "github.com/steele232/zoumacro/base/strings"
 )
 
@@ -275,9 +276,16 @@ func init() {
                    in current package, or from imported package NAME`}},
 		'h': []Cmd{{"help", (*Interp).cmdHelp, `help              show this help`}},
 		'i': []Cmd{{"inspect", (*Interp).cmdInspect, `inspect EXPR      inspect expression interactively`}},
-		'o': []Cmd{{"options", (*Interp).cmdOptions, `options [OPTS]    show or toggle interpreter options`}},
-		'p': []Cmd{{"package", (*Interp).cmdPackage, `package "PKGPATH" switch to package PKGPATH, importing it if possible`}},
+		'o': []Cmd{{"options", (*Interp).cmdOptions, `options [OPTS]    show or toggle interpreter options,
+                   or set print options as MaxDepth=N MaxElems=N MaxStringLen=N MultiLine=true`}},
+		'p': []Cmd{
+			{"package", (*Interp).cmdPackage, `package "PKGPATH" switch to package PKGPATH, importing it if possible`},
+			{"profile", (*Interp).cmdProfile, `profile start FILE start sampling interpreted code, for 'go tool pprof FILE'
+                   use %cprofile stop to stop sampling and write FILE`},
+		},
 		'q': []Cmd{{"quit", (*Interp).cmdQuit, `quit              quit the interpreter`}},
+		't': []Cmd{{"trace", (*Interp).cmdTrace, `trace [PATTERN]   log entry and exit of interpreted functions matching PATTERN,
+                   or show traced patterns. use %ctrace off [PATTERN] to stop tracing`}},
 		'u': []Cmd{{"unload", (*Interp).cmdUnload, `unload "PKGPATH"  remove package PKGPATH from the list of known packages.
                    later attempts to import it will trigger a recompile`}},
 		'w': []Cmd{{"write", (*Interp).cmdWrite, `write [FILE]      write collected declarations and/or statements to standard output or to FILE
@@ -352,7 +360,17 @@ func (ir *Interp) cmdOptions(arg string, opt base.CmdOpt) (string, base.CmdOpt)
 	g := &c.Globals
 
 	if len(arg) != 0 {
-		g.Options ^= base.ParseOptions(arg)
+		var opts []string
+		for _, str := range strings.Fields(arg) {
+			if output.IsPrintOption(str) {
+				if err := g.PrintOptions.Set(str); err != nil {
+					g.Warnf("%v", err)
+				}
+			} else {
+				opts = append(opts, str)
+			}
+		}
+		g.Options ^= base.ParseOptions(strings.Join(opts, " "))
 
 		debugdepth := 0
 		if g.Options&base.OptDebugFromReflect != 0 {
@@ -363,6 +381,7 @@ func (ir *Interp) cmdOptions(arg string, opt base.CmdOpt) (string, base.CmdOpt)
 	} else {
 		g.Fprintf(g.Stdout, "// current options: %v\n", g.Options)
 		g.Fprintf(g.Stdout, "// unset   options: %v\n", ^g.Options)
+		g.Fprintf(g.Stdout, "// print   options: %v\n", g.PrintOptions)
 	}
 	return "", opt
 }
@@ -385,10 +404,51 @@ func (ir *Interp) cmdPackage(path string, cmdopt base.CmdOpt) (string, base.CmdO
 	return "", cmdopt
 }
 
+func (ir *Interp) cmdProfile(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	var err error
+	switch cmd, filename := bstrings.Split2(strings.TrimSpace(arg), ' '); cmd {
+	case "start":
+		if len(filename) == 0 {
+			g.Fprintf(g.Stdout, "// profile start: missing file name\n")
+			return "", opt
+		}
+		err = ir.StartCPUProfileFile(filename)
+	case "stop":
+		err = ir.StopCPUProfile()
+	default:
+		g.Fprintf(g.Stdout, "// profile: expecting start FILE or stop, found %q\n", arg)
+	}
+	if err != nil {
+		g.Fprintf(g.Stdout, "// profile: %v\n", err)
+	}
+	return "", opt
+}
+
 func (ir *Interp) cmdQuit(_ string, opt base.CmdOpt) (string, base.CmdOpt) {
 	return "", opt | base.CmdOptQuit
 }
 
+func (ir *Interp) cmdTrace(arg string, opt base.CmdOpt) (string, base.CmdOpt) {
+	g := &ir.Comp.Globals
+	args := strings.Fields(arg)
+	if len(args) == 0 {
+		if t := ir.env.Run.Tracer; t != nil {
+			g.Fprintf(g.Stdout, "// tracing: %s\n", strings.Join(t.Patterns, " "))
+		} else {
+			g.Fprintf(g.Stdout, "// tracing: no functions\n")
+		}
+	} else if args[0] == "off" {
+		ir.Untrace(args[1:]...)
+	} else {
+		if g.Options&base.OptDebugger == 0 {
+			g.Warnf("only functions compiled with %coptions Debugger can be traced", g.ReplCmdChar)
+		}
+		ir.Trace(args...)
+	}
+	return "", opt
+}
+
 // remove package 'path' from the list of known packages
 func (ir *Interp) cmdUnload(path string, opt base.CmdOpt) (string, base.CmdOpt) {
 	if len(path) != 0 {
@@ -18,6 +18,7 @@ package fast
 
 import (
 	"go/token"
+	r "reflect"
 
 	. "github.com/steele232/zoumacro/base"
 )
@@ -64,12 +65,13 @@ func spinInterrupt(env *Env) (Stmt, *Env) {
 	if run.Signals.IsEmpty() {
 		run.Signals.Sync = SigReturn
 	} else if sig := run.Signals.Async; sig != SigNone {
-		run.applyAsyncSignal(sig)
+		run.applyAsyncSignal(env, sig)
 	}
 	return run.Interrupt, env
 }
 
-func (run *Run) applyAsyncSignal(sig Signal) {
+// env is the Env executing when the signal is serviced, and may be nil
+func (run *Run) applyAsyncSignal(env *Env, sig Signal) {
 	run.Signals.Async = SigNone
 	switch sig {
 	case SigNone:
@@ -77,6 +79,10 @@ func (run *Run) applyAsyncSignal(sig Signal) {
 	case SigDebug:
 		run.applyDebugOp(DebugOpStep)
 	default:
+		run.InterruptPos = token.NoPos
+		if env != nil && env.IP >= 0 && env.IP < len(env.DebugPos) {
+			run.InterruptPos = env.DebugPos[env.IP]
+		}
 		panic(SigInterrupt)
 	}
 }
@@ -104,10 +110,48 @@ func restore(run *Run, isDefer bool, interrupt Stmt, caller *Env) {
 	run.Signals.Sync = SigNone
 	if sig := run.Signals.Async; sig == SigInterrupt {
 		// do NOT handle async SigDebug here
-		run.applyAsyncSignal(sig)
+		run.applyAsyncSignal(caller, sig)
 	}
 }
 
+// panicState is the panic being handled by the defers of an interpreted function
+type panicState struct {
+	fun   *Env
+	panic interface{}
+	saved bool
+}
+
+func (p *panicState) save(run *Run) {
+	if !p.saved {
+		*p = panicState{run.PanicFun, run.Panic, true}
+	}
+}
+
+func (p *panicState) restore(run *Run) {
+	if p.saved {
+		run.PanicFun, run.Panic = p.fun, p.panic
+		*p = panicState{}
+	}
+}
+
+// deferredByCompiled executes the body of an interpreted function
+// that compiled code deferred directly while panicking.
+// The caller already stopped the panic with recover(), so the body
+// can recover() it too. Otherwise, the panic is resumed
+func deferredByCompiled(env *Env, funcbody func(*Env), rec interface{}) {
+	run := env.Run
+	var outer panicState
+	outer.save(run)
+	run.Panic = rec
+	// identifies the compiled function whose defers are running
+	compiled := &Env{}
+	defer popDefer(pushDefer(run, compiled, true))
+	funcbody(env)
+	maybeRepanic(run)
+	outer.restore(run)
+	env.freeEnv4Func()
+}
+
 func maybeRepanic(run *Run) bool {
 	if run.PanicFun != nil {
 		panic(run.Panic)
@@ -116,6 +160,85 @@ func maybeRepanic(run *Run) bool {
 	return false
 }
 
+// debugOnPanic is deferred by reExecWithFlags if OptBreakOnPanic is set.
+// It enters the debugger while env still describes the panicking statement,
+// then resumes panicking
+func debugOnPanic(run *Run, env **Env) {
+	if rec := recover(); rec != nil {
+		panic(run.breakOnPanic(*env, rec))
+	}
+}
+
+// breakOnPanic invokes the debugger at the statement that panicked,
+// at most once per panic. returns the value to continue panicking with
+func (run *Run) breakOnPanic(env *Env, rec interface{}) interface{} {
+	if run.panicDebugged(env, rec) {
+		return rec
+	}
+	run.PanicDebug, run.PanicValue = env, rec
+	c := env.DebugComp
+	if c == nil {
+		// code was not compiled with OptDebugger
+		return rec
+	}
+	if run.Debugger == nil {
+		c.Warnf("// panic: no debugger set with Interp.SetDebugger(), resuming panic (warned only once)")
+		run.Debugger = stubDebugger{}
+	}
+	debugger, ok := run.Debugger.(PanicDebugger)
+	if !ok {
+		return rec
+	}
+	// do NOT single-step the code evaluated at debugger prompt
+	run.applyDebugOp(DebugOpContinue)
+
+	ir := Interp{c, env}
+	op := debugger.Panic(&ir, env, rec)
+	if run.Options&OptDebugDebugger != 0 {
+		run.Debugf("Debugger returned op = %v after panic(%v)", op, rec)
+	}
+	if op.Panic != nil {
+		rec = *op.Panic
+		run.PanicValue = rec
+	}
+	return rec
+}
+
+// return true if the debugger was already entered for the panic rec,
+// while it was unwinding the stack from a function called by env.
+// compiled code may recover() a panic without notifying the interpreter:
+// then a new panic is raised elsewhere, or with a different value
+func (run *Run) panicDebugged(env *Env, rec interface{}) bool {
+	if run.PanicDebug == nil || !samePanic(run.PanicValue, rec) {
+		return false
+	}
+	for e := run.PanicDebug; e != nil; {
+		if e == env {
+			return true
+		} else if e.Caller != nil {
+			e = e.Caller
+		} else {
+			e = e.Outer
+		}
+	}
+	return false
+}
+
+func (run *Run) resetPanicDebug() {
+	run.PanicDebug, run.PanicValue = nil, nil
+}
+
+// return true if a and b are the same panic value.
+// values that cannot be compared are considered the same if they have the same type
+func samePanic(a, b interface{}) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = r.TypeOf(a) == r.TypeOf(b)
+		}
+	}()
+	return a == b
+}
+
 func (run *Run) interrupt() {
 	const CtrlCDebug = OptDebugger | OptCtrlCEnterDebugger
 	var sig Signal
@@ -156,7 +279,7 @@ func exec(all []Stmt, pos []token.Pos) func(*Env) {
 			return
 		}
 		if sig := run.Signals.Async; sig != SigNone {
-			run.applyAsyncSignal(sig)
+			run.applyAsyncSignal(env, sig)
 		}
 		saveInterrupt := run.Interrupt
 		run.Interrupt = nil
@@ -227,9 +350,9 @@ func exec(all []Stmt, pos []token.Pos) func(*Env) {
 		// restore env.ThreadGlobals.Interrupt and Signal before returning
 		run.Interrupt = saveInterrupt
 		if sig := run.Signals.Async; sig != SigNone {
-			run.applyAsyncSignal(sig) // may set run.Signals.Debug if OptCtrlCEnterDebugger is set
+			run.applyAsyncSignal(env, sig) // may set run.Signals.Debug if OptCtrlCEnterDebugger is set
 		}
-		if run.Signals.Debug == SigNone {
+		if run.Signals.Debug == SigNone && run.Signals.Profile() == SigNone {
 			run.Signals.Sync = SigNone
 		} else {
 			reExecWithFlags(env, all, pos, stmt, env.IP)
@@ -254,7 +377,7 @@ func reExecWithFlags(env *Env, all []Stmt, pos []token.Pos, stmt Stmt, ip int) {
 		run.Debugf("reExecWithFlags:  executing function   stmt = %p, env = %p, IP = %v, execFlags = %v, signals = %#v", stmt, env, ip, *ef, run.Signals)
 	}
 	if sig := run.Signals.Async; sig != SigNone {
-		run.applyAsyncSignal(sig)
+		run.applyAsyncSignal(env, sig)
 	}
 	caller := run.CurrEnv
 	// restore g.IsDefer, g.Signal, g.DebugCallDepth, g.Interrupt and g.Caller on return
@@ -268,19 +391,48 @@ func reExecWithFlags(env *Env, all []Stmt, pos []token.Pos, stmt Stmt, ip int) {
 	env.Code = all
 	env.DebugPos = pos
 
+	if ef.BreakOnPanic() {
+		// runs after the defers installed below: they enter the debugger by themselves
+		defer debugOnPanic(run, &env)
+	}
+
+	// panic of the caller, interrupted by a panic of this function.
+	// restored if the latter is recovered
+	var outer panicState
 	panicking, panicking2 := true, false
 	rundefer := func(fun func()) {
-		if panicking || panicking2 {
+		// fun == nil is installed before a compiled function deferred directly:
+		// it runs after it, and checks whether it recovered or raised a panic
+		if panicking || panicking2 || fun == nil {
+			rec := recover()
+			if fun == nil && rec == nil {
+				if panicking {
+					// recovered by the compiled function
+					panicking = false
+					run.resetPanicDebug()
+					outer.restore(run)
+				}
+				panicking2 = false
+				return
+			}
+			outer.save(run)
 			panicking = true
 			panicking2 = false
-			run.Panic = recover()
+			run.Panic = rec
+			if run.ExecFlags.BreakOnPanic() {
+				run.Panic = run.breakOnPanic(env, run.Panic)
+			}
 		}
 		defer popDefer(pushDefer(run, funenv, panicking))
-		panicking2 = true // detect panics inside defer
-		fun()
-		panicking2 = false
+		if fun != nil {
+			panicking2 = true // detect panics inside defer
+			fun()
+			panicking2 = false
+		}
 		if panicking {
-			panicking = maybeRepanic(run)
+			if panicking = maybeRepanic(run); !panicking {
+				outer.restore(run)
+			}
 		}
 	}
 
@@ -325,7 +477,14 @@ again:
 			run.Signals.Sync = SigNone
 			fun := run.InstallDefer
 			run.InstallDefer = nil
-			defer rundefer(fun)
+			if run.DeferGo {
+				// compiled functions must be deferred directly to recover() a panic
+				run.DeferGo = false
+				defer rundefer(nil)
+				defer fun()
+			} else {
+				defer rundefer(fun)
+			}
 			stmt = env.Code[env.IP]
 			if stmt == nil {
 				goto signal
@@ -359,7 +518,14 @@ again:
 			run.Signals.Sync = SigNone
 			fun := run.InstallDefer
 			run.InstallDefer = nil
-			defer rundefer(fun)
+			if run.DeferGo {
+				// compiled functions must be deferred directly to recover() a panic
+				run.DeferGo = false
+				defer rundefer(nil)
+				defer fun()
+			} else {
+				defer rundefer(fun)
+			}
 			// single step
 			stmt = env.Code[env.IP]
 			stmt, env = stmt(env)
@@ -369,10 +535,18 @@ again:
 		}
 	}
 signal:
+	if run.Signals.Profile() != SigNone {
+		run.sampleProfile(env)
+		if run.Signals.IsEmpty() {
+			// only a profiler sample was requested: resume execution
+			stmt = env.Code[env.IP]
+			goto again
+		}
+	}
 	if sig := run.Signals.Async; sig != SigNone {
 		// if OptCtrlCEnterDebugger is set, convert early
 		// Signals.Async = SigDebug to Signals.Debug = SigDebug
-		run.applyAsyncSignal(sig)
+		run.applyAsyncSignal(env, sig)
 	}
 
 	for run.Signals.Debug != SigNone {
@@ -381,6 +555,9 @@ signal:
 		if trace {
 			run.Debugf("singleStep returned stmt = %p, env = %p, IP = %v, execFlags = %v, signals = %#v", stmt, env, env.IP, run.ExecFlags, run.Signals)
 		}
+		if run.Signals.Profile() != SigNone {
+			run.sampleProfile(env)
+		}
 		// a Sync or Async signal may be pending.
 		sig := run.Signals.Sync
 		if run.Signals.IsEmpty() || sig == SigDefer {
@@ -388,7 +565,7 @@ signal:
 		} else if sig == SigReturn {
 			break
 		} else if sig = run.Signals.Async; sig != SigNone {
-			run.applyAsyncSignal(sig)
+			run.applyAsyncSignal(env, sig)
 		}
 	}
 	panicking = false
@@ -66,6 +66,7 @@ func (c *Comp) FileComp() *Comp {
 func NewIrGlobals() *IrGlobals {
 	return &IrGlobals{
 		gls:     make(map[uintptr]*Run),
+		forking: &forkTracker{},
 		Globals: *NewGlobals(),
 	}
 }
@@ -184,6 +185,10 @@ func NewEnv(outer *Env, nbind int, nintbind int) *Env {
 
 func newEnv4Func(outer *Env, nbind int, nintbind int, debugComp *Comp) *Env {
 	goid := gls.GoID()
+	if outer.Run.forking.count() != 0 {
+		// outer may belong to the interpreter that was forked
+		outer = forkedEnv(outer, goid)
+	}
 	run := outer.Run
 	if run.goid != goid {
 		// no luck... get the correct ThreadGlobals for goid
@@ -382,6 +387,18 @@ func (c *Comp) compileDecl(decl *dep.Decl) *Expr {
 			return c.Code.AsExpr()
 		}
 	}
+	if spec, ok := decl.Node.(*ast.ValueSpec); ok {
+		switch decl.Kind {
+		case dep.Package:
+			// dep.Sorter returns naked *ast.ValueSpec for package clauses,
+			// wrap it again in the *ast.GenDecl created by the parser
+			return c.compileNode(&ast.GenDecl{TokPos: decl.Pos, Tok: token.PACKAGE, Specs: []ast.Spec{spec}}, decl.Kind)
+		case dep.VarMulti:
+			// dep.Sorter returns naked *ast.ValueSpec for the first variable in VarMulti,
+			// wrap it again in the *ast.GenDecl created by the parser
+			return c.compileNode(&ast.GenDecl{TokPos: decl.Pos, Tok: token.VAR, Specs: []ast.Spec{spec}}, decl.Kind)
+		}
+	}
 	if node := decl.Node; node != nil {
 		return c.compileNode(node, decl.Kind)
 	}
@@ -413,9 +430,17 @@ func (c *Comp) compileNode(node ast.Node, kind dep.Kind) *Expr {
 	c.Loop = nil
 	c.Func = nil
 	c.Labels = nil
+	c.gotos = &gotoInfo{}
 	c.FuncMaker = nil
 	c.Pos = node.Pos()
 	switch node := node.(type) {
+	case *ast.FuncDecl:
+		if kind == dep.FuncFwd {
+			// forward function declaration
+			c.declFuncFwd(node)
+		} else {
+			c.Decl(node)
+		}
 	case ast.Decl:
 		c.Decl(node)
 	case ast.Expr:
@@ -438,6 +463,7 @@ func (c *Comp) compileNode(node ast.Node, kind dep.Kind) *Expr {
 		return c.Expr(node.X, nil)
 	case ast.Stmt:
 		c.Stmt(node)
+		c.checkGotos()
 	case *ast.File:
 		// not c.File(node): unnecessary and risks an infinite recursion
 		for _, decl := range node.Decls {
@@ -459,9 +485,15 @@ func (c *Comp) File(node *ast.File) {
 }
 
 func (c *Comp) Append(stmt Stmt, pos token.Pos) {
+	if cover := c.Coverage; cover != nil && stmt != nil {
+		stmt = cover.instrument(stmt, pos)
+	}
+	if sb := c.Sandbox; sb != nil && sb.MaxSteps != 0 && stmt != nil {
+		stmt = sb.instrument(stmt, pos)
+	}
 	c.Code.Append(stmt, pos)
 }
 
 func (c *Comp) append(stmt Stmt) {
-	c.Code.Append(stmt, c.Pos)
+	c.Append(stmt, c.Pos)
 }
@@ -167,7 +167,7 @@ func (c *Comp) compositeLitElements(t xr.Type, ellipsis bool, node *ast.Composit
 		keys[i] = lastkey
 
 		eval := c.Expr1(elv, tval)
-		if eval.Const() {
+		if eval.Const() && !eval.constNeedsConverter(tval) {
 			eval.ConstTo(tval)
 		} else if !eval.Type.AssignableTo(tval) {
 			c.Errorf("cannot use %v <%v> as type <%v> in %s value", elv, eval.Type, tval, t.Kind())
@@ -210,7 +210,7 @@ func (c *Comp) compositeLitMap(t xr.Type, node *ast.CompositeLit) *Expr {
 				ekey.To(c, tkey)
 			}
 			eval := c.Expr1(elkv.Value, tval)
-			if eval.Const() {
+			if eval.Const() && !eval.constNeedsConverter(tval) {
 				eval.ConstTo(tval)
 			} else if !eval.Type.AssignableTo(tval) {
 				c.Errorf("cannot use %v <%v> as type <%v> in map value", elkv.Value, eval.Type, tval)
@@ -272,7 +272,7 @@ func (c *Comp) compositeLitStruct(t xr.Type, node *ast.CompositeLit) *Expr {
 					c.Errorf("unknown field '%v' in struct literal of type %v", name, t)
 				}
 				expr := c.Expr1(elkv.Value, field.Type)
-				if expr.Const() {
+				if expr.Const() && !expr.constNeedsConverter(field.Type) {
 					expr.ConstTo(field.Type)
 				} else if !expr.Type.AssignableTo(field.Type) {
 					c.Errorf("cannot use %v <%v> as type <%v> in field value", elkv.Value, expr.Type, field.Type)
@@ -291,7 +291,7 @@ func (c *Comp) compositeLitStruct(t xr.Type, node *ast.CompositeLit) *Expr {
 			}
 			field := t.Field(i)
 			expr := c.Expr1(el, field.Type)
-			if expr.Const() {
+			if expr.Const() && !expr.constNeedsConverter(field.Type) {
 				expr.ConstTo(field.Type)
 			} else if !expr.Type.AssignableTo(field.Type) {
 				c.Errorf("cannot use %v <%v> as type <%v> in field value", el, expr.Type, field.Type)
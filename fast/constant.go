@@ -0,0 +1,156 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * constant.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	r "reflect"
+
+	"github.com/steele232/zoumacro/base/reflect"
+	"github.com/steele232/zoumacro/base/untyped"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// maximum count of constant shifts. as Go compiler, allows to express the smallest float64.
+// untyped constants have arbitrary precision, which is needed to convert them to *big.Int
+const shiftBound = 1023 - 1 + 52
+
+// return true if kind is an integer, float or complex kind
+func isNumericKind(k r.Kind) bool {
+	return reflect.IsCategory(k, r.Int, r.Uint, r.Float64, r.Complex128)
+}
+
+// constantOf returns the exact value of a typed or untyped numeric constant,
+// or nil if value is not a numeric constant
+func constantOf(value I) constant.Value {
+	if lit, ok := value.(UntypedLit); ok {
+		return lit.Val
+	}
+	var val constant.Value
+	v := r.ValueOf(value)
+	switch reflect.Category(v.Kind()) {
+	case r.Int:
+		val = constant.MakeInt64(v.Int())
+	case r.Uint:
+		val = constant.MakeUint64(v.Uint())
+	case r.Float64:
+		val = constant.MakeFloat64(v.Float())
+	case r.Complex128:
+		z := v.Complex()
+		re, im := constant.MakeFloat64(real(z)), constant.MakeFloat64(imag(z))
+		val = constant.BinaryOp(re, token.ADD, constant.MakeImag(im))
+	default:
+		return nil
+	}
+	if val.Kind() == constant.Unknown {
+		// infinities and NaN
+		return nil
+	}
+	return val
+}
+
+// return true if e is a numeric constant equal to zero
+func isConstantZero(e *Expr) bool {
+	if !e.Const() {
+		return false
+	}
+	val := constantOf(e.Value)
+	return val != nil && constant.Sign(val) == 0
+}
+
+// typedConstant converts the exact value val to type t,
+// failing on overflow or truncation as Go compilers do.
+// node is the constant expression, used for error messages
+func (c *Comp) typedConstant(node ast.Expr, val constant.Value, t xr.Type) I {
+	if node != nil {
+		defer func() {
+			if rec := recover(); rec != nil {
+				c.Pos = node.Pos()
+				c.Errorf("%v: %v", rec, node)
+			}
+		}()
+	}
+	lit := untyped.MakeLit(untyped.MakeKind(val.Kind()), val, &c.Universe.BasicTypes)
+	return lit.Convert(t)
+}
+
+// constantBinaryOp computes exactly x op y on typed numeric constants,
+// then converts the result to t. Returns nil if x or y are not numeric constants
+func (c *Comp) constantBinaryOp(node *ast.BinaryExpr, op token.Token, x I, y I, t xr.Type) I {
+	if t == nil || !isNumericKind(t.Kind()) {
+		return nil
+	}
+	xv, yv := constantOf(x), constantOf(y)
+	if xv == nil || yv == nil {
+		return nil
+	}
+	var zv constant.Value
+	switch op = tokenWithoutAssign(op); op {
+	case token.SHL, token.SHR:
+		zv = constant.Shift(xv, op, c.shiftCount(node, yv))
+	case token.QUO:
+		if reflect.IsCategory(t.Kind(), r.Int, r.Uint) {
+			op = token.QUO_ASSIGN // integer division
+		}
+		fallthrough
+	case token.ADD, token.SUB, token.MUL, token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+		zv = constant.BinaryOp(xv, op, yv)
+	default:
+		return nil
+	}
+	return c.typedConstant(node, zv, t)
+}
+
+// constantUnaryOp computes exactly op x on a typed numeric constant,
+// then converts the result to t. Returns nil if x is not a numeric constant
+func (c *Comp) constantUnaryOp(node *ast.UnaryExpr, op token.Token, x I, t xr.Type) I {
+	if t == nil || !isNumericKind(t.Kind()) {
+		return nil
+	}
+	xv := constantOf(x)
+	if xv == nil {
+		return nil
+	}
+	var prec uint
+	switch op {
+	case token.XOR:
+		if reflect.IsCategory(t.Kind(), r.Uint) {
+			prec = uint(t.ReflectType().Bits())
+		}
+	case token.ADD, token.SUB:
+	default:
+		return nil
+	}
+	return c.typedConstant(node, constant.UnaryOp(op, xv, prec), t)
+}
+
+// shiftCount returns the constant shift count y, which must be a non-negative integer
+func (c *Comp) shiftCount(node *ast.BinaryExpr, y constant.Value) uint {
+	yn := constant.ToInt(y)
+	if yn.Kind() != constant.Int {
+		c.Errorf("invalid shift count %v: %v", y, node)
+	} else if constant.Sign(yn) < 0 {
+		c.Errorf("invalid negative shift count %v: %v", y, node)
+	}
+	n, exact := constant.Uint64Val(yn)
+	if !exact || n > shiftBound {
+		c.Errorf("invalid shift count %v: %v", y, node)
+	}
+	return uint(n)
+}
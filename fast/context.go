@@ -0,0 +1,130 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * context.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	r "reflect"
+
+	"github.com/steele232/zoumacro/ast2"
+	. "github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/gls"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// ContextError is returned by Interp.EvalContext and Interp.RunExprContext
+// when the context is cancelled or its deadline expires during execution
+type ContextError struct {
+	Err error          // the error returned by context.Context.Err()
+	Pos token.Position // position of the interpreted statement where execution stopped
+}
+
+func (err *ContextError) Error() string {
+	if !err.Pos.IsValid() {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", err.Pos, err.Err)
+}
+
+// Unwrap returns the error returned by context.Context.Err()
+func (err *ContextError) Unwrap() error {
+	return err.Err
+}
+
+// combined Parse + Compile + RunExprContext.
+// As EvalErr, it never panics: parse and compile errors are returned as *ParseError or *CompileError,
+// runtime errors as *RuntimeError or *PanicError
+func (ir *Interp) EvalContext(ctx context.Context, src string) ([]r.Value, []xr.Type, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	c := ir.Comp
+	form, err := c.catchParse(func() ast2.Ast {
+		return ir.Parse(src)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := c.catchCompile(func() *Expr {
+		return ir.CompileAst(form)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ir.RunExprContext(ctx, e)
+}
+
+// RunExprContext is like RunExpr, but stops execution at the next statement boundary
+// when ctx is cancelled or its deadline expires. In such case, it returns a *ContextError
+// that wraps ctx.Err() and contains the position where execution stopped.
+// As RunExprErr, it never panics: runtime errors are returned as *RuntimeError or *PanicError.
+// Goroutines started by the interpreted code are not stopped
+func (ir *Interp) RunExprContext(ctx context.Context, e *Expr) (vals []r.Value, types []xr.Type, err error) {
+	if err = ctx.Err(); err != nil || e == nil {
+		return nil, nil, err
+	}
+	// RunExpr executes top-level statements with ir.env.Run,
+	// and function bodies with the Run of the current goroutine: interrupt both
+	runs := []*Run{ir.env.Run}
+	if goid := gls.GoID(); runs[0].goid != goid {
+		runs = append(runs, runs[0].getRun4Goid(goid))
+	}
+	for _, run := range runs {
+		run.InterruptPos = token.NoPos
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			// same as Interp.Interrupt(), but never enters the debugger
+			for _, run := range runs {
+				run.Signals.Async = SigInterrupt
+			}
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+		if ctx.Err() == nil {
+			return
+		}
+		// do not interrupt the next execution
+		for _, run := range runs {
+			if run.Signals.Async == SigInterrupt {
+				run.Signals.Async = SigNone
+			}
+		}
+		if perr, ok := err.(*PanicError); !ok || perr.Value != SigInterrupt {
+			return
+		}
+		var pos token.Pos
+		for _, run := range runs {
+			if run.InterruptPos.IsValid() {
+				pos = run.InterruptPos
+				break
+			}
+		}
+		vals, types = nil, nil
+		err = &ContextError{Err: ctx.Err(), Pos: runs[0].Fileset.Position(pos)}
+	}()
+	return ir.RunExprErr(e)
+}
@@ -26,7 +26,7 @@ import (
 
 // Convert compiles a type conversion expression
 func (c *Comp) Convert(node ast.Expr, t xr.Type) *Expr {
-	e := c.Expr1(node, nil)
+	e := c.exprContext(node, t)
 
 	return c.convert(e, t, node)
 }
@@ -54,6 +54,12 @@ func (c *Comp) convert(e *Expr, t xr.Type, nodeOpt ast.Expr) *Expr {
 		return nil
 	}
 	rtype := t.ReflectType()
+	if e.Const() && isNumericKind(e.Type.Kind()) && isNumericKind(t.Kind()) {
+		// constant numeric conversions must be exact, or at least must not overflow
+		if val := constantOf(e.Value); val != nil {
+			return c.exprValue(t, c.typedConstant(nodeOpt, val, t))
+		}
+	}
 	if e.Const() {
 		val := convert(r.ValueOf(e.Value), rtype).Interface()
 		return c.exprValue(t, val)
@@ -0,0 +1,254 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * cover.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	r "reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/steele232/zoumacro/ast2"
+	mt "github.com/steele232/zoumacro/token"
+)
+
+// Coverage counts the executions of each compiled statement,
+// using the same positions stored in Code.DebugPos.
+// Only statements compiled after Interp.StartCoverage() are counted.
+type Coverage struct {
+	Mode     string // "set" or "count", as in 'go test -covermode'
+	lock     sync.Mutex
+	counters []*coverCounter
+}
+
+type coverCounter struct {
+	pos token.Pos
+	n   uint32
+}
+
+// StartCoverage starts counting statement executions. mode must be "set" or "count".
+// Coverage only applies to code compiled after this call
+func (ir *Interp) StartCoverage(mode string) error {
+	switch mode {
+	case "":
+		mode = "set"
+	case "set", "count":
+		break
+	default:
+		return fmt.Errorf("invalid coverage mode %q, expecting \"set\" or \"count\"", mode)
+	}
+	g := ir.Comp.CompGlobals
+	if g.Coverage == nil {
+		g.Coverage = &Coverage{Mode: mode}
+	} else {
+		g.Coverage.Mode = mode
+	}
+	return nil
+}
+
+// StopCoverage stops instrumenting newly compiled statements, and returns
+// the counters collected so far. Already instrumented code keeps counting
+func (ir *Interp) StopCoverage() *Coverage {
+	g := ir.Comp.CompGlobals
+	cover := g.Coverage
+	g.Coverage = nil
+	return cover
+}
+
+// WriteCoverProfile writes the counters collected since StartCoverage()
+// to out, in the format read by 'go tool cover'
+func (ir *Interp) WriteCoverProfile(out io.Writer) error {
+	cover := ir.Comp.CompGlobals.Coverage
+	if cover == nil {
+		return fmt.Errorf("coverage not started")
+	}
+	return cover.Write(out, ir.Comp.Fileset)
+}
+
+// WriteCoverProfileFile is a wrapper around WriteCoverProfile that creates the file
+func (ir *Interp) WriteCoverProfileFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = ir.WriteCoverProfile(f)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// return a statement that increments a counter, then executes stmt
+func (cover *Coverage) instrument(stmt Stmt, pos token.Pos) Stmt {
+	if pos == token.NoPos {
+		return stmt
+	}
+	counter := &coverCounter{pos: pos}
+	cover.lock.Lock()
+	cover.counters = append(cover.counters, counter)
+	cover.lock.Unlock()
+
+	if cover.Mode == "set" {
+		return func(env *Env) (Stmt, *Env) {
+			if atomic.LoadUint32(&counter.n) == 0 {
+				atomic.StoreUint32(&counter.n, 1)
+			}
+			return stmt(env)
+		}
+	}
+	return func(env *Env) (Stmt, *Env) {
+		atomic.AddUint32(&counter.n, 1)
+		return stmt(env)
+	}
+}
+
+type coverBlock struct {
+	file      string
+	line, col int
+	n         uint32
+}
+
+// Write writes the collected counters to out, in the format read by 'go tool cover'.
+// Each block extends from the statement start to the next statement or to the end of line.
+// File names are relative to the current directory
+func (cover *Coverage) Write(out io.Writer, fileset *mt.FileSet) error {
+	cover.lock.Lock()
+	counters := append([]*coverCounter(nil), cover.counters...)
+	cover.lock.Unlock()
+
+	// statements compiled more than once, or compiled to several Stmt, share the same position
+	type key struct {
+		file      string
+		line, col int
+	}
+	merged := make(map[key]*coverBlock)
+	var blocks []*coverBlock
+	wd, _ := os.Getwd()
+	for _, counter := range counters {
+		pos := fileset.Position(counter.pos)
+		if !pos.IsValid() || len(pos.Filename) == 0 {
+			// code evaluated from a string has no file to show coverage for
+			continue
+		}
+		file := coverPath(pos.Filename, wd)
+		k := key{file, pos.Line, pos.Column}
+		n := atomic.LoadUint32(&counter.n)
+		if b := merged[k]; b != nil {
+			if b.n < n {
+				b.n = n
+			}
+			continue
+		}
+		b := &coverBlock{file: file, line: pos.Line, col: pos.Column, n: n}
+		merged[k] = b
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		bi, bj := blocks[i], blocks[j]
+		if bi.file != bj.file {
+			return bi.file < bj.file
+		} else if bi.line != bj.line {
+			return bi.line < bj.line
+		}
+		return bi.col < bj.col
+	})
+
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "mode: %s\n", cover.Mode)
+	lines := make(map[string][]string)
+	for i, b := range blocks {
+		endcol := -1
+		if i+1 < len(blocks) {
+			if next := blocks[i+1]; next.file == b.file && next.line == b.line {
+				endcol = next.col
+			}
+		}
+		if endcol < 0 {
+			endcol = b.col + 1
+			if source := coverSource(lines, b.file); b.line <= len(source) {
+				if n := len(source[b.line-1]) + 1; n > b.col {
+					endcol = n
+				}
+			}
+		}
+		fmt.Fprintf(w, "%s:%d.%d,%d.%d 1 %d\n", b.file, b.line, b.col, b.line, endcol, b.n)
+	}
+	return w.Flush()
+}
+
+// return file relative to the directory wd, starting with "./"
+// because 'go tool cover' interprets other names as import paths.
+// if file is not below wd, return its absolute path
+func coverPath(file string, wd string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	if len(wd) == 0 {
+		return abs
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abs
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// return the lines of source file, reading it if needed
+func coverSource(cache map[string][]string, file string) []string {
+	source, ok := cache[file]
+	if !ok {
+		if bytes, err := ioutil.ReadFile(file); err == nil {
+			source = strings.Split(string(bytes), "\n")
+		}
+		cache[file] = source
+	}
+	return source
+}
+
+// coverMacroExpansion attributes the code produced by a macro to the macro call site:
+// positions outside [start, end), i.e. not belonging to the macro arguments,
+// are replaced with start
+func coverMacroExpansion(out ast2.Ast, start token.Pos, end token.Pos) {
+	tpos := r.TypeOf(token.NoPos)
+	for _, node := range ast2.ToNodes(out) {
+		ast.Inspect(node, func(node ast.Node) bool {
+			v := r.ValueOf(node)
+			if v.Kind() != r.Ptr || v.IsNil() || v.Elem().Kind() != r.Struct {
+				return true
+			}
+			v = v.Elem()
+			for i, n := 0, v.NumField(); i < n; i++ {
+				if f := v.Field(i); f.Type() == tpos && f.CanSet() {
+					if pos := token.Pos(f.Int()); pos < start || pos >= end {
+						f.SetInt(int64(start))
+					}
+				}
+			}
+			return true
+		})
+	}
+}
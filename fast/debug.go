@@ -33,6 +33,10 @@ func (s stubDebugger) At(ir *Interp, env *Env) DebugOp {
 	return DebugOpContinue
 }
 
+func (s stubDebugger) Panic(ir *Interp, env *Env, rec interface{}) DebugOp {
+	return DebugOpContinue
+}
+
 // return true if statement is either "break" or _ = "break"
 func isBreakpoint(stmt ast.Stmt) bool {
 	switch node := stmt.(type) {
@@ -37,14 +37,27 @@ type Debugger struct {
 }
 
 func (d *Debugger) Breakpoint(interp *fast.Interp, env *fast.Env) DebugOp {
-	return d.main(interp, env, true)
+	return d.main(interp, env, "breakpoint")
 }
 
 func (d *Debugger) At(interp *fast.Interp, env *fast.Env) DebugOp {
-	return d.main(interp, env, false)
+	return d.main(interp, env, "stopped")
 }
 
-func (d *Debugger) main(interp *fast.Interp, env *fast.Env, breakpoint bool) DebugOp {
+// Panic is invoked at the panicking statement, before the stack unwinds.
+// 'continue' resumes panicking, 'kill' panics with a different value
+func (d *Debugger) Panic(interp *fast.Interp, env *fast.Env, rec interface{}) DebugOp {
+	g := &interp.Comp.Globals
+	g.Fprintf(g.Stdout, "// panic: %v\n// type continue to resume panicking, kill to abort\n", rec)
+	op := d.main(interp, env, "panic")
+	if op.Panic == nil {
+		// step, next and finish make no sense while panicking
+		op = DebugOpContinue
+	}
+	return op
+}
+
+func (d *Debugger) main(interp *fast.Interp, env *fast.Env, label string) DebugOp {
 	// create an inner Interp to preserve existing Binds, compiled Code and IP
 	//
 	// this is needed to allow compiling and evaluating code at a breakpoint or single step
@@ -52,7 +65,7 @@ func (d *Debugger) main(interp *fast.Interp, env *fast.Env, breakpoint bool) Deb
 	d.interp = fast.NewInnerInterp(interp, "debug", "debug")
 	d.env = env
 	d.globals = &interp.Comp.Globals
-	if !d.Show(breakpoint) {
+	if !d.show(label) && label == "stopped" {
 		// skip synthetic statements
 		return DebugOp{Depth: env.Run.DebugDepth}
 	}
@@ -61,7 +61,7 @@ func (d *Debugger) showEnv(env *fast.Env) {
 	})
 	for _, bind := range binds {
 		value := bind.RuntimeValue(env)
-		g.Fprintf(g.Stdout, "%s\t= %v\t// %v\n", bind.Name, value, bind.Type)
+		g.Fprintf(g.Stdout, "%s\t= %v\t// %v\n", bind.Name, g.TypedValue(value, bind.Type), bind.Type)
 	}
 }
 
@@ -79,12 +79,12 @@ func (d *Debugger) showBinds(env *fast.Env, binds []*fast.Bind) {
 
 func (d *Debugger) showBind(env *fast.Env, bind *fast.Bind) {
 	value := bind.RuntimeValue(env)
-	var ivalue interface{} = value
+	g := d.globals
+	var ivalue interface{} = g.TypedValue(value, bind.Type)
 	if !value.IsValid() {
 		ivalue = "nil"
 	}
 
-	g := d.globals
 	if name := bind.Name; len(name) != 0 {
 		g.Fprintf(g.Stdout, "%s=%v <%v>", name, ivalue, bind.Type)
 	} else {
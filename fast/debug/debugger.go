@@ -52,6 +52,13 @@ vars            show local variables
 }
 
 func (d *Debugger) Show(breakpoint bool) bool {
+	if breakpoint {
+		return d.show("breakpoint")
+	}
+	return d.show("stopped")
+}
+
+func (d *Debugger) show(label string) bool {
 	// d.env is the Env being debugged.
 	// to execute code at debugger prompt, use d.interp
 	env := d.env
@@ -59,12 +66,6 @@ func (d *Debugger) Show(breakpoint bool) bool {
 	g := d.globals
 	ip := env.IP
 
-	var label string
-	if breakpoint {
-		label = "breakpoint"
-	} else {
-		label = "stopped"
-	}
 	if ip < len(pos) && g.Fileset != nil {
 		p := pos[ip]
 		if p == token.NoPos {
@@ -101,7 +101,9 @@ func (c *Comp) GenDecl(node *ast.GenDecl) {
 			if decl, ok := node.Specs[0].(*ast.ValueSpec); ok {
 				if len(decl.Values) == 1 {
 					if lit, ok := decl.Values[0].(*ast.BasicLit); ok {
-						if lit.Kind == token.STRING && (lit.Value == c.Name || strings.MaybeUnescapeString(lit.Value) == c.Path) {
+						// nested Comp, as the ones created by Interp.CompileProgram, have no name or path
+						fc := c.FileComp()
+						if lit.Kind == token.STRING && (lit.Value == fc.Name || strings.MaybeUnescapeString(lit.Value) == fc.Path) {
 							break
 						}
 					}
@@ -188,7 +190,23 @@ func (c *Comp) prepareDeclConstsOrVars(names []string, typ ast.Expr, exprs []ast
 	if typ != nil {
 		t = c.Type(typ)
 	}
-	if exprs != nil {
+	if exprs != nil && t != nil && len(exprs) == n {
+		// compile each expression in the context of the declared type
+		types := make([]xr.Type, n)
+		for i := range types {
+			types[i] = t
+		}
+		inits = c.exprsContext(exprs, types)
+		for i, init := range inits {
+			if init.Untyped() && !init.constNeedsConverter(t) {
+				// check a copy now, to report overflows at the expression position.
+				// do not modify init: it is converted by DeclConst0 or DeclVar0
+				c.Pos = exprs[i].Pos()
+				lit := init.Lit
+				lit.ConstTo(t)
+			}
+		}
+	} else if exprs != nil {
 		inits = c.ExprsMultipleValues(exprs, n)
 	}
 	return names, t, inits
@@ -451,7 +469,7 @@ func (c *Comp) DeclVar0(name string, t xr.Type, init *Expr) *Bind {
 			})
 			return bind
 		}
-		if init.Const() {
+		if init.Const() && !init.constNeedsConverter(t) {
 			init.ConstTo(t) // convert untyped constants, check typed constants
 		}
 		fun := init.AsX1() // AsX1() panics if init.NumOut() == 0, warns if init.NumOut() > 1
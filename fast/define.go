@@ -0,0 +1,151 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * define.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	r "reflect"
+	"sort"
+
+	"github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/base/genimport"
+	"github.com/steele232/zoumacro/base/paths"
+	"github.com/steele232/zoumacro/base/strings"
+	"github.com/steele232/zoumacro/imports"
+)
+
+// DefinePackage makes a package available to interpreted code without generating an x_package.go:
+// afterwards, 'import "path"' imports it with the given name - if empty, it is derived from path.
+// symbols maps each name to its value: reflect.Type values define types, as reflect.TypeOf((*T)(nil)).Elem(),
+// non-nil pointers define variables, as &v that interpreted code can read and modify
+// (to define a variable containing a pointer p, use &p), and other values define functions and constants.
+// Interfaces with the same methods as an interface of a known package,
+// as fmt.Stringer or io.Reader, reuse its proxy: interpreted types can implement them.
+// Other interfaces cannot be implemented by interpreted types.
+// The package is visible only to this interpreter and its forks. Defining it again replaces it
+// in code compiled afterwards
+func (ir *Interp) DefinePackage(path, name string, symbols map[string]interface{}) *Import {
+	c := ir.Comp
+	path = c.sanitizeImportPath(path)
+	if len(name) == 0 {
+		name = strings.TailIdentifier(paths.FileName(path))
+	}
+	pkg := imports.Package{}
+	pkg.LazyInit()
+	for sym, val := range symbols {
+		switch val := val.(type) {
+		case nil:
+			c.Errorf("define package %q: symbol %s has nil value", path, sym)
+		case r.Type:
+			pkg.Types[sym] = val
+		default:
+			v := r.ValueOf(val)
+			if v.Kind() == r.Ptr && !v.IsNil() {
+				v = v.Elem()
+			}
+			pkg.Binds[sym] = v
+		}
+	}
+	findProxies(pkg)
+
+	g := c.CompGlobals
+	imp := g.NewImport(&genimport.PackageRef{Package: pkg, Name: name, Path: path})
+	g.KnownImports[path] = imp
+	return imp
+}
+
+// add to pkg.Proxies the proxies of known packages that implement the interfaces in pkg.Types
+func findProxies(pkg imports.Package) {
+	var candidates map[string][]r.Type
+	for name, rtype := range pkg.Types {
+		if rtype.Kind() != r.Interface || rtype.NumMethod() == 0 {
+			continue
+		}
+		if candidates == nil {
+			candidates = knownProxies()
+		}
+		for _, proxy := range candidates[methodNames(rtype)] {
+			if proxyImplements(proxy, rtype) {
+				pkg.Proxies[name] = proxy
+				break
+			}
+		}
+	}
+}
+
+// return the proxies of known packages, indexed by the method names of the interface they implement
+func knownProxies() map[string][]r.Type {
+	m := make(map[string][]r.Type)
+	for _, pkg := range imports.Packages {
+		for name, proxy := range pkg.Proxies {
+			if rtype := pkg.Types[name]; rtype != nil && rtype.Kind() == r.Interface {
+				key := methodNames(rtype)
+				m[key] = append(m[key], proxy)
+			}
+		}
+	}
+	// be deterministic
+	for _, proxies := range m {
+		sort.Slice(proxies, func(i, j int) bool {
+			return proxies[i].String() < proxies[j].String()
+		})
+	}
+	return m
+}
+
+func methodNames(rtype r.Type) string {
+	var buf []byte
+	for i, n := 0, rtype.NumMethod(); i < n; i++ {
+		mtd := rtype.Method(i)
+		buf = append(buf, mtd.PkgPath...)
+		buf = append(buf, '.')
+		buf = append(buf, mtd.Name...)
+		buf = append(buf, ' ')
+	}
+	return string(buf)
+}
+
+// return true if proxy has the layout expected by Comp.converterToProxy() for the interface rtype
+func proxyImplements(proxy r.Type, rtype r.Type) bool {
+	n := rtype.NumMethod()
+	if proxy.Kind() != r.Struct || proxy.NumField() != n+1 || !r.PtrTo(proxy).Implements(rtype) {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		mtd := rtype.Method(i)
+		field := proxy.Field(i + 1)
+		if field.Name != mtd.Name+"_" || field.Type != proxyFuncType(mtd.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// return the type of the proxy field that implements a method of type t:
+// the same as t, with an additional interface{} first argument
+func proxyFuncType(t r.Type) r.Type {
+	nin, nout := t.NumIn(), t.NumOut()
+	in := make([]r.Type, nin+1)
+	out := make([]r.Type, nout)
+	in[0] = base.TypeOfInterface
+	for i := 0; i < nin; i++ {
+		in[i+1] = t.In(i)
+	}
+	for i := 0; i < nout; i++ {
+		out[i] = t.Out(i)
+	}
+	return r.FuncOf(in, out, t.IsVariadic())
+}
@@ -0,0 +1,76 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * display.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"context"
+	r "reflect"
+
+	. "github.com/steele232/zoumacro/base"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// RegisterRenderer adds a renderer that converts values of type t to the MIME type mime.
+// If t is an interface, the renderer is used for all types implementing it.
+// Use Interp.TypeOf() to obtain the xr.Type of a compiled type.
+// A nil fn removes the renderer previously registered for t and mime
+func (ir *Interp) RegisterRenderer(t xr.Type, mime string, fn Renderer) {
+	ir.Comp.Globals.Display.Register(t, mime, fn)
+}
+
+// Render returns the MIME representations of a value,
+// as text/plain, text/html, image/png, application/json or any MIME type
+// supported by the renderers registered with RegisterRenderer.
+// Front-ends as Jupyter kernels can pass the results of Eval() to it.
+func (ir *Interp) Render(v r.Value, t xr.Type) MIMEBundle {
+	return ir.Comp.Globals.Render(v, t)
+}
+
+// EvalRender is a combined Eval + Render
+func (ir *Interp) EvalRender(src string) []MIMEBundle {
+	vals, types := ir.Eval(src)
+	return ir.renderAll(vals, types)
+}
+
+// EvalRenderContext is a combined EvalContext + Render, for servers that must not panic,
+// as JSON-RPC servers or Jupyter kernels: each request can be served by calling it
+// and marshalling the results to JSON. Errors are returned as EvalContext does,
+// and a panic raised while rendering is returned as error too
+func (ir *Interp) EvalRenderContext(ctx context.Context, src string) (bundles []MIMEBundle, err error) {
+	vals, types, err := ir.EvalContext(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			bundles, err = nil, recoveredError(rec)
+		}
+	}()
+	return ir.renderAll(vals, types), nil
+}
+
+func (ir *Interp) renderAll(vals []r.Value, types []xr.Type) []MIMEBundle {
+	bundles := make([]MIMEBundle, len(vals))
+	for i, v := range vals {
+		var t xr.Type
+		if i < len(types) {
+			t = types[i]
+		}
+		bundles[i] = ir.Render(v, t)
+	}
+	return bundles
+}
@@ -0,0 +1,304 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * errors.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	r "reflect"
+	"runtime"
+	"strings"
+
+	"github.com/steele232/zoumacro/ast2"
+	. "github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/base/output"
+	"github.com/steele232/zoumacro/base/reflect"
+	"github.com/steele232/zoumacro/scanner"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// Frame is an interpreted function call in a backtrace
+type Frame struct {
+	Func string         // function name, as "main.f" if Options include OptDebugger, otherwise "func@file:line"
+	Pos  token.Position // position of the statement being executed
+}
+
+func (f Frame) String() string {
+	if !f.Pos.IsValid() {
+		return f.Func
+	}
+	return fmt.Sprintf("%s at %s", f.Func, f.Pos)
+}
+
+// ParseError is returned by Interp.EvalErr when the source code cannot be parsed or macroexpanded
+type ParseError struct {
+	Pos token.Position
+	Err error
+	msg string
+}
+
+// CompileError is returned by Interp.EvalErr when the source code cannot be compiled,
+// for example because of undefined identifiers or mismatched types
+type CompileError struct {
+	Pos token.Position
+	Err error
+	msg string
+}
+
+// RuntimeError is returned by Interp.EvalErr and Interp.RunExprErr when interpreted code fails at runtime,
+// as an index out of range, a nil pointer dereference, a failed type assertion or a sandbox violation
+type RuntimeError struct {
+	Pos       token.Position // position of the innermost interpreted statement being executed
+	Backtrace []Frame        // interpreted call stack, innermost frame first
+	Err       error
+	msg       string
+}
+
+// PanicError is returned by Interp.EvalErr and Interp.RunExprErr when interpreted code calls panic(),
+// or calls a compiled function that panics, and the panic is not recovered
+type PanicError struct {
+	Pos       token.Position // position of the innermost interpreted statement being executed
+	Backtrace []Frame        // interpreted call stack, innermost frame first
+	Value     interface{}    // the value passed to panic()
+}
+
+func (err *ParseError) Error() string {
+	return err.msg
+}
+
+func (err *ParseError) Unwrap() error {
+	return err.Err
+}
+
+func (err *CompileError) Error() string {
+	return err.msg
+}
+
+func (err *CompileError) Unwrap() error {
+	return err.Err
+}
+
+func (err *RuntimeError) Error() string {
+	return err.msg
+}
+
+func (err *RuntimeError) Unwrap() error {
+	return err.Err
+}
+
+func (err *PanicError) Error() string {
+	return prefixPosition(err.Pos, fmt.Sprintf("panic: %v", err.Value))
+}
+
+// Unwrap returns the panic value, if it is an error
+func (err *PanicError) Unwrap() error {
+	e, _ := err.Value.(error)
+	return e
+}
+
+// prepend pos to msg, unless msg already starts with it
+func prefixPosition(pos token.Position, msg string) string {
+	if !pos.IsValid() {
+		return msg
+	}
+	prefix := pos.String()
+	if strings.HasPrefix(msg, prefix) {
+		return msg
+	}
+	return prefix + ": " + msg
+}
+
+// convert a recovered panic value to error
+func recoveredError(rec interface{}) error {
+	if err, ok := rec.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", rec)
+}
+
+// EvalErr is like Eval, but it never panics: errors are returned as
+// *ParseError, *CompileError, *RuntimeError or *PanicError
+func (ir *Interp) EvalErr(src string) ([]r.Value, []xr.Type, error) {
+	c := ir.Comp
+	form, err := c.catchParse(func() ast2.Ast {
+		return ir.Parse(src)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	e, err := c.catchCompile(func() *Expr {
+		return ir.CompileAst(form)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ir.RunExprErr(e)
+}
+
+// call parse, converting panics to *ParseError
+func (c *Comp) catchParse(parse func() ast2.Ast) (form ast2.Ast, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			e := recoveredError(rec)
+			pos := c.Position()
+			if list, ok := e.(scanner.ErrorList); ok && len(list) != 0 {
+				pos = list[0].Pos
+			}
+			form, err = nil, &ParseError{Pos: pos, Err: e, msg: prefixPosition(pos, e.Error())}
+		}
+	}()
+	return parse(), nil
+}
+
+// call compile, converting panics to *CompileError
+func (c *Comp) catchCompile(compile func() *Expr) (e *Expr, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			e = nil
+			err = c.newCompileError(recoveredError(rec))
+		}
+	}()
+	return compile(), nil
+}
+
+func (c *Comp) newCompileError(e error) *CompileError {
+	pos := c.Position()
+	if serr, ok := e.(*SandboxError); ok && serr.Pos.IsValid() {
+		pos = serr.Pos
+	} else if terr, ok := e.(*TypeCheckError); ok && terr.Pos.IsValid() {
+		pos = terr.Pos
+	}
+	return &CompileError{Pos: pos, Err: e, msg: prefixPosition(pos, e.Error())}
+}
+
+// RunExprErr is like RunExpr, but it never panics: runtime errors
+// are returned as *RuntimeError or *PanicError
+func (ir *Interp) RunExprErr(e *Expr) ([]r.Value, []xr.Type, error) {
+	if e == nil {
+		return nil, nil, nil
+	}
+	env := ir.PrepareEnv()
+	if ir.Comp.Globals.Options&OptKeepUntyped == 0 && e.Untyped() {
+		e.ConstTo(e.DefaultType())
+	}
+	return runExprErr(env, e, ir.Comp.Position())
+}
+
+// execute e in env, converting panics to *RuntimeError or *PanicError
+func runExprErr(env *Env, e *Expr, fallback token.Position) (vals []r.Value, types []xr.Type, err error) {
+	run := env.Run
+	run.applyDebugOp(DebugOpContinue)
+
+	defer run.setCurrEnv(run.setCurrEnv(env))
+	defer run.enterFork()()
+	// executed before the deferred setCurrEnv() above:
+	// run.CurrEnv is still the innermost *Env that panicked
+	defer func() {
+		if rec := recover(); rec != nil {
+			vals, types = nil, nil
+			err = run.newRunError(rec, fallback)
+		}
+	}()
+	fun := e.AsXV(COptKeepUntyped)
+	v, vs := fun(env)
+	return reflect.PackValues(v, vs), reflect.PackTypes(e.Type, e.Types), nil
+}
+
+// convert a value recovered while executing interpreted code to *RuntimeError or *PanicError.
+// fallback is used as position if the interpreted call stack does not contain one
+func (run *Run) newRunError(rec interface{}, fallback token.Position) error {
+	backtrace := run.backtrace(run.CurrEnv)
+	pos := fallback
+	if len(backtrace) != 0 && backtrace[0].Pos.IsValid() {
+		pos = backtrace[0].Pos
+	}
+	var e error
+	switch rec := rec.(type) {
+	case runtime.Error:
+		e = rec
+	case output.RuntimeError:
+		e = rec
+	case *SandboxError:
+		e = rec
+		if rec.Pos.IsValid() {
+			pos = rec.Pos
+		}
+	case *r.ValueError:
+		e = rec
+	case string:
+		// reflect package panics with strings on out-of-range indexes and similar runtime errors
+		if !strings.HasPrefix(rec, "reflect") {
+			return &PanicError{Pos: pos, Backtrace: backtrace, Value: rec}
+		}
+		e = errors.New(rec)
+	default:
+		return &PanicError{Pos: pos, Backtrace: backtrace, Value: rec}
+	}
+	return &RuntimeError{Pos: pos, Backtrace: backtrace, Err: e, msg: prefixPosition(pos, e.Error())}
+}
+
+// return the interpreted call stack that starts at env, innermost frame first.
+// Used by runtime errors and by the profiler
+func (run *Run) backtrace(env *Env) []Frame {
+	var stack []Frame
+	for env != nil {
+		// env is the innermost *Env of a function: it contains the current statement
+		inner := env
+		// follow env.Outer.Outer... chain until we find the function body
+		for env != nil && env.Caller == nil && env.Outer != nil && env.FileEnv != env {
+			env = env.Outer
+		}
+		var frame Frame
+		if ip := inner.IP; ip >= 0 && ip < len(inner.DebugPos) && run.Fileset != nil {
+			frame.Pos = run.Fileset.Position(inner.DebugPos[ip])
+		}
+		frame.Func = run.funcName(env)
+		stack = append(stack, frame)
+		if env == nil {
+			break
+		}
+		env = env.Caller
+	}
+	return stack
+}
+
+// return the name of the interpreted function executing in funenv
+func (run *Run) funcName(funenv *Env) string {
+	if funenv == nil || funenv.Caller == nil {
+		return "main.<toplevel>"
+	}
+	if c := funenv.DebugComp; c != nil {
+		if m := c.FuncMaker; m != nil && len(m.Name) != 0 {
+			return c.funcPackage() + m.Name
+		}
+	}
+	// closure, or no debug information: name the function after its first statement
+	if len(funenv.DebugPos) != 0 && run.Fileset != nil {
+		position := run.Fileset.Position(funenv.DebugPos[0])
+		return fmt.Sprintf("func@%s:%d", position.Filename, position.Line)
+	}
+	return fmt.Sprintf("func@%p", funenv)
+}
+
+// return the package prefix of function names, as shown in backtraces and profiles
+func (c *Comp) funcPackage() string {
+	if name := c.FileComp().Name; len(name) != 0 {
+		return name + "."
+	}
+	return "main."
+}
@@ -49,6 +49,56 @@ func (c *Comp) ExprsMultipleValues(nodes []ast.Expr, expectedValuesN int) (inits
 	return inits
 }
 
+// exprsContext compiles multiple single-valued expressions.
+// types are the types expected by the context, see Comp.exprContext()
+func (c *Comp) exprsContext(nodes []ast.Expr, types []xr.Type) []*Expr {
+	var inits []*Expr
+	if n := len(nodes); n != 0 {
+		inits = make([]*Expr, n)
+		for i := range nodes {
+			var t xr.Type
+			if i < len(types) {
+				t = types[i]
+			}
+			inits[i] = c.exprContext(nodes[i], t)
+		}
+	}
+	return inits
+}
+
+// exprContext compiles a single-valued expression.
+// t is the type expected by the context, as the type of a variable being assigned:
+// non-constant shifts of untyped constants, as 1 << s, are converted to t
+// if it is a numeric type, otherwise to the default type of the untyped constant.
+// Unlike Comp.Expr1(), composite literals do not infer their type from t
+func (c *Comp) exprContext(in ast.Expr, t xr.Type) *Expr {
+	for {
+		switch node := in.(type) {
+		case *ast.ParenExpr:
+			in = node.X
+			continue
+		case *ast.BinaryExpr:
+			c.Pos = node.Pos()
+			return c.binaryExpr(node, t)
+		case *ast.UnaryExpr:
+			switch node.Op {
+			case token.ADD, token.SUB, token.XOR:
+				c.Pos = node.Pos()
+				return c.unaryExpr(node, t)
+			}
+		}
+		return c.Expr1(in, nil)
+	}
+}
+
+// numericContext returns t if it is a numeric type, otherwise nil
+func numericContext(t xr.Type) xr.Type {
+	if t != nil && !isNumericKind(t.Kind()) {
+		t = nil
+	}
+	return t
+}
+
 // Exprs compiles multiple expressions
 func (c *Comp) Exprs(nodes []ast.Expr) []*Expr {
 	var inits []*Expr
@@ -83,7 +133,7 @@ func (c *Comp) Expr1(in ast.Expr, t xr.Type) *Expr {
 				xe := c.Expr1(node.X, nil)
 				return c.Recv1(node, xe)
 			} else {
-				return c.UnaryExpr(node)
+				return c.unaryExpr(node, t)
 			}
 		}
 		break
@@ -104,6 +154,7 @@ func (c *Comp) Expr1(in ast.Expr, t xr.Type) *Expr {
 // Expr compiles an expression.
 // t is optional and used for type inference on composite literals,
 // see https://golang.org/ref/spec#Composite_literals
+// and on non-constant shifts of untyped constants, see Comp.exprContext()
 func (c *Comp) Expr(in ast.Expr, t xr.Type) *Expr {
 	for {
 		if in != nil {
@@ -114,7 +165,7 @@ func (c *Comp) Expr(in ast.Expr, t xr.Type) *Expr {
 		case *ast.BasicLit:
 			return c.BasicLit(node)
 		case *ast.BinaryExpr:
-			return c.BinaryExpr(node)
+			return c.binaryExpr(node, t)
 		case *ast.CallExpr:
 			return c.CallExpr(node)
 		case *ast.CompositeLit:
@@ -130,7 +181,7 @@ func (c *Comp) Expr(in ast.Expr, t xr.Type) *Expr {
 			in = node.X
 			continue
 		case *ast.UnaryExpr:
-			return c.UnaryExpr(node)
+			return c.unaryExpr(node, t)
 		case *ast.SelectorExpr:
 			return c.SelectorExpr(node)
 		case *ast.SliceExpr:
@@ -174,8 +225,8 @@ func (c *Comp) Expr1OrType(expr ast.Expr) (e *Expr, t xr.Type) {
 				}
 			}
 		case *ast.IndexExpr:
-			if lit, ok := n.Index.(*ast.CompositeLit); ok && lit.Type == nil {
-				// foo#[a, b...] can be a template function or a template type
+			if lit, ok := n.Index.(*ast.CompositeLit); !ok || lit.Type == nil {
+				// foo#[a, b...] and foo[a] can be a template function or a template type
 				node = n.X
 				continue
 			}
@@ -0,0 +1,322 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * fmt.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"io"
+	r "reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// reflect cannot create named types, so interpreted named types
+// are implemented by unnamed reflect.Type, and compiled code cannot see their name.
+// As a workaround, arguments of interpreted named types passed to fmt printing functions
+// are wrapped in a namedValue, which prints the type name where Go would print it:
+// in %#v and %T verbs. It also removes the prefixes added to unexported fields names
+
+// namedValue wraps a value of interpreted named type, or pointer to interpreted named type.
+// If the type has methods that fmt looks for, as String() or Error(),
+// p contains the value wrapped in a proxy: see Comp.methodProxy()
+type namedValue struct {
+	v r.Value
+	t xr.Type
+	p r.Value
+}
+
+var fmtUngensym = strings.NewReplacer(xr.StrGensymPrivate, "", xr.StrGensymAnonymous, "")
+
+// Format implements fmt.Formatter
+func (x namedValue) Format(s fmt.State, verb rune) {
+	if x.p.IsValid() {
+		if p := x.p.Interface(); fmtUsesMethods(p, verb, s.Flag('#')) {
+			fmt.Fprintf(s, fmtDirective(s, verb), p)
+			return
+		}
+	}
+	var i interface{}
+	if x.v.IsValid() && x.v.CanInterface() {
+		i = x.v.Interface()
+	}
+	str := fmt.Sprintf(fmtDirective(s, verb), i)
+	if verb == 'v' && s.Flag('#') {
+		rtype, t := x.t.ReflectType(), x.t
+		if t.Kind() == r.Ptr {
+			rtype, t = rtype.Elem(), t.Elem()
+		}
+		str = strings.Replace(str, rtype.String(), t.String(), -1)
+	}
+	if verb == 'v' && (s.Flag('#') || s.Flag('+')) {
+		str = fmtUngensym.Replace(str)
+	}
+	io.WriteString(s, str)
+}
+
+// return true if fmt calls the methods of p when formatting it with verb
+func fmtUsesMethods(p interface{}, verb rune, sharp bool) bool {
+	if _, ok := p.(fmt.Formatter); ok {
+		return true
+	} else if verb == 'v' && sharp {
+		_, ok := p.(fmt.GoStringer)
+		return ok
+	}
+	switch verb {
+	case 'v', 's', 'x', 'X', 'q':
+		switch p.(type) {
+		case error, fmt.Stringer:
+			return true
+		}
+	}
+	return false
+}
+
+// rebuild the fmt directive that invoked fmt.Formatter.Format()
+func fmtDirective(s fmt.State, verb rune) string {
+	var buf bytes.Buffer
+	buf.WriteByte('%')
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			buf.WriteRune(flag)
+		}
+	}
+	if width, ok := s.Width(); ok {
+		buf.WriteString(strconv.Itoa(width))
+	}
+	if prec, ok := s.Precision(); ok {
+		buf.WriteByte('.')
+		buf.WriteString(strconv.Itoa(prec))
+	}
+	buf.WriteRune(verb)
+	return buf.String()
+}
+
+// fmtTypeVerbs replaces the %T verbs whose argument is a namedValue
+// with %s verbs whose argument is the name of the interpreted type.
+// The names are appended to args, so the format is rewritten
+// to use explicit argument indexes everywhere
+func fmtTypeVerbs(format string, args []interface{}) (string, []interface{}) {
+	// position in format and argument of each verb or '*'
+	type use struct {
+		pos, arg int
+		verb     rune
+	}
+	var uses []use
+	found := false
+	n := len(format)
+	arg := 0
+	argIndex := func(i int) int {
+		if i < n && format[i] == '[' {
+			if j := strings.IndexByte(format[i:], ']'); j > 0 {
+				if k, err := strconv.Atoi(format[i+1 : i+j]); err == nil && k > 0 {
+					arg = k - 1
+				}
+				return i + j + 1
+			}
+		}
+		return i
+	}
+	number := func(i int) int {
+		start := i
+		if i = argIndex(i); i < n && format[i] == '*' {
+			uses = append(uses, use{start, arg, '*'})
+			arg++
+			return i + 1
+		} else if i != start {
+			// argument index is followed by the verb
+			return start
+		}
+		for i < n && format[i] >= '0' && format[i] <= '9' {
+			i++
+		}
+		return i
+	}
+	for i := 0; i < n; {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		i++
+		for i < n && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		i = number(i)
+		if i < n && format[i] == '.' {
+			i = number(i + 1)
+		}
+		start := i
+		i = argIndex(i)
+		if i >= n {
+			break
+		}
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		if verb != '%' {
+			if _, ok := getNamedValue(args, arg); ok && verb == 'T' {
+				found = true
+			}
+			uses = append(uses, use{start, arg, verb})
+			arg++
+		}
+		i += size
+	}
+	if !found {
+		return format, args
+	}
+	var buf bytes.Buffer
+	names := make(map[int]int) // argument -> index of its type name in args
+	args = append([]interface{}(nil), args...)
+	last := 0
+	for _, u := range uses {
+		// skip the original argument index, if any
+		buf.WriteString(format[last:u.pos])
+		last = argIndex(u.pos)
+		verb, size := u.verb, 1
+		arg := u.arg
+		if u.verb == '*' {
+			last++
+		} else {
+			_, size = utf8.DecodeRuneInString(format[last:])
+		}
+		if x, ok := getNamedValue(args, u.arg); ok && u.verb == 'T' {
+			if _, ok := names[u.arg]; !ok {
+				names[u.arg] = len(args)
+				args = append(args, x.t.String())
+			}
+			arg, verb = names[u.arg], 's'
+		}
+		fmt.Fprintf(&buf, "[%d]%c", arg+1, verb)
+		if u.verb != '*' {
+			last += size
+		}
+	}
+	buf.WriteString(format[last:])
+	return buf.String(), args
+}
+
+func getNamedValue(args []interface{}, i int) (namedValue, bool) {
+	if i >= 0 && i < len(args) {
+		x, ok := args[i].(namedValue)
+		return x, ok
+	}
+	return namedValue{}, false
+}
+
+type fmtHook struct {
+	hooked      I    // replacement for functions with a format argument, or nil
+	skipStrings bool // do not wrap strings: fmt.Print() adds spaces only between non-string operands
+}
+
+// hooks for the functions of package fmt that accept values to print
+var fmtHooks = map[string]fmtHook{
+	"Print":    {skipStrings: true},
+	"Println":  {},
+	"Sprint":   {skipStrings: true},
+	"Sprintln": {},
+	"Fprint":   {skipStrings: true},
+	"Fprintln": {},
+	"Printf": {hooked: func(format string, args ...interface{}) (int, error) {
+		format, args = fmtTypeVerbs(format, args)
+		return fmt.Printf(format, args...)
+	}},
+	"Sprintf": {hooked: func(format string, args ...interface{}) string {
+		format, args = fmtTypeVerbs(format, args)
+		return fmt.Sprintf(format, args...)
+	}},
+	"Fprintf": {hooked: func(w io.Writer, format string, args ...interface{}) (int, error) {
+		format, args = fmtTypeVerbs(format, args)
+		return fmt.Fprintf(w, format, args...)
+	}},
+	"Errorf": {hooked: func(format string, args ...interface{}) error {
+		format, args = fmtTypeVerbs(format, args)
+		return fmt.Errorf(format, args...)
+	}},
+}
+
+// return true if t is an interpreted named type, or a pointer to it
+func isInterpretedNamed(t xr.Type) bool {
+	if t != nil && t.Kind() == r.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && t.Named() && t.Kind() != r.Interface && t.ReflectType().Name() != t.Name()
+}
+
+// hookFmt wraps the arguments of interpreted named types passed to fmt printing functions.
+// returns the function to call, which may differ from fun
+func (c *Comp) hookFmt(node *ast.CallExpr, fun *Expr, args []*Expr) *Expr {
+	hook, ok := fmtHooks[c.fmtFunctionName(node.Fun)]
+	if !ok || fun.Type.Kind() != r.Func || !fun.Type.IsVariadic() {
+		return fun
+	}
+	wrapped := false
+	for i := fun.Type.NumIn() - 1; i < len(args); i++ {
+		arg := args[i]
+		if !isInterpretedNamed(arg.Type) || (hook.skipStrings && arg.Type.Kind() == r.String) {
+			continue
+		}
+		args[i] = c.namedValueArg(arg)
+		wrapped = true
+	}
+	if wrapped && hook.hooked != nil {
+		fun = c.exprValue(fun.Type, hook.hooked)
+	}
+	return fun
+}
+
+// if node is a function of compiled package fmt, as fmt.Printf, return its name
+func (c *Comp) fmtFunctionName(node ast.Expr) string {
+	sel, ok := node.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	sym := c.TryResolve(ident.Name)
+	if sym == nil || sym.Desc.Class() != ConstBind {
+		return ""
+	}
+	imp, ok := sym.Value.(*Import)
+	if !ok || imp.Path != "fmt" {
+		return ""
+	}
+	if bind, ok := imp.Binds[sel.Sel.Name]; !ok || bind.Desc.Class() != FuncBind {
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// wrap arg in a namedValue
+func (c *Comp) namedValueArg(arg *Expr) *Expr {
+	t := arg.Type
+	fun := arg.AsX1()
+	conv := c.methodProxy(t, "fmt")
+	return exprX1(c.TypeOfInterface(), func(env *Env) r.Value {
+		v := fun(env)
+		var p r.Value
+		if conv != nil {
+			p = conv(v)
+		}
+		var i interface{} = namedValue{v, t, p}
+		return r.ValueOf(&i).Elem()
+	})
+}
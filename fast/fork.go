@@ -0,0 +1,567 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * fork.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"go/token"
+	r "reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/steele232/zoumacro/gls"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// funcRemaker recreates in a forked Env the function or macro declared by a Bind
+type funcRemaker struct {
+	desc   BindDescriptor
+	remake func(env *Env, bind *Bind)
+}
+
+func (c *Comp) addRemaker(name string, bind *Bind, remake func(env *Env, bind *Bind)) {
+	if c.remakers == nil {
+		c.remakers = make(map[string]funcRemaker)
+	}
+	c.remakers[name] = funcRemaker{bind.Desc, remake}
+}
+
+// Fork returns a copy of the interpreter that can be used independently from ir,
+// even concurrently with it. ir must not be executing code while Fork() runs.
+//
+// The fork has its own copy of declarations, types, imported packages and variables.
+// Values reachable from variables are deep-copied, preserving aliasing among them.
+// The following values cannot be deep-copied and are shared with ir:
+//   - channels, unsafe.Pointer and pointers to compiled types with unexported fields,
+//     as *os.File, *sync.Mutex or *bytes.Buffer: they usually wrap OS resources or synchronization state
+//   - variables of compiled packages, as os.Args
+//   - the local variables captured by closures created inside ir functions:
+//     the fork copies them (shallowly) the first time it calls such a closure
+//   - the xreflect.Universe, which becomes thread-safe, and the Fileset
+//   - the Tracer, which is never modified: Interp.Trace() and Interp.Untrace() on either interpreter
+//     replace it only in that interpreter
+//   - the Coverage started by Interp.StartCoverage(): statements executed by the fork
+//     are counted in the same coverage profile, until Interp.StopCoverage() is called on either interpreter
+//
+// The Sandbox is copied: the fork has the same rules, and counts its own statements and goroutines.
+// Goroutines started by ir keep running in ir: the fork starts with none.
+// When the fork is garbage collected, ir stops tracking it.
+func (ir *Interp) Fork() *Interp {
+	cg := ir.Comp.CompGlobals
+	g := cg.IrGlobals
+	if !cg.Universe.ThreadSafe {
+		// set only while no fork exists: later forks share the Universe and read the flag concurrently
+		cg.Universe.ThreadSafe = true
+	}
+
+	ng := &IrGlobals{
+		gls:     make(map[uintptr]*Run),
+		Tracer:  g.Tracer,
+		forking: g.forking,
+		Globals: *g.Globals.Fork(),
+	}
+	if sb := g.Sandbox; sb != nil {
+		ng.Sandbox = sb.fork()
+	}
+	if s := g.Stdio; s != nil {
+		stdio := *s
+		ng.Stdio = &stdio
+	}
+	run := &Run{IrGlobals: ng, goid: gls.GoID(), Debugger: ir.env.Run.Debugger}
+	ng.gls[run.goid] = run
+
+	ncg := *cg
+	ncg.IrGlobals = ng
+	ncg.interf2proxy = make(map[r.Type]r.Type, len(cg.interf2proxy))
+	for k, v := range cg.interf2proxy {
+		ncg.interf2proxy[k] = v
+	}
+	ncg.proxy2interf = make(map[r.Type]xr.Type, len(cg.proxy2interf))
+	for k, v := range cg.proxy2interf {
+		ncg.proxy2interf[k] = v
+	}
+	ncg.namedTypes = make(map[r.Type][]xr.Type, len(cg.namedTypes))
+	for k, v := range cg.namedTypes {
+		ncg.namedTypes[k] = append([]xr.Type(nil), v...)
+	}
+	ncg.typesPackages = nil // forked imports are different objects
+	imports := make(map[*Import]*Import, len(cg.KnownImports))
+	ncg.KnownImports = make(map[string]*Import, len(cg.KnownImports))
+	for path, imp := range cg.KnownImports {
+		nimp := imp.fork(imports)
+		ncg.rebindStdio(nimp)
+		ncg.KnownImports[path] = nimp
+	}
+
+	// Comp and Env chains have the same length: Interp.Comp.Outer... matches Interp.env.Outer...
+	var comps []*Comp
+	var envs []*Env
+	for c, env := ir.Comp, ir.env; c != nil && env != nil; c, env = c.Outer, env.Outer {
+		comps = append(comps, c)
+		envs = append(envs, env)
+	}
+	n := len(comps)
+	ncomps := make([]*Comp, n)
+	nenvs := make([]*Env, n)
+	f := newForker()
+	for i := n - 1; i >= 0; i-- {
+		c, env := comps[i], envs[i]
+		nc := *c
+		nc.CompGlobals = &ncg
+		nc.CompBinds = c.CompBinds.fork(imports)
+		nc.Code = Code{
+			List:       append([]Stmt(nil), c.Code.List...),
+			DebugPos:   append([]token.Pos(nil), c.Code.DebugPos...),
+			WithDefers: c.Code.WithDefers,
+		}
+		if c.Labels != nil {
+			nc.Labels = make(map[string]*int, len(c.Labels))
+			for k, v := range c.Labels {
+				label := *v
+				nc.Labels[k] = &label
+			}
+		}
+		nenv := &Env{
+			Run:             run,
+			CallDepth:       env.CallDepth,
+			UsedByClosure:   true, // do not free this *Env
+			IntAddressTaken: env.IntAddressTaken,
+		}
+		if i+1 < n {
+			nc.Outer = ncomps[i+1]
+			nenv.Outer = nenvs[i+1]
+		}
+		ncomps[i], nenvs[i] = &nc, nenv
+		f.addEnv(env, nenv)
+	}
+	for i, env := range envs {
+		nenv := nenvs[i]
+		for j, fileenv := range envs {
+			if env.FileEnv == fileenv {
+				nenv.FileEnv = nenvs[j]
+			}
+		}
+		f.copyVals(env, nenv)
+	}
+	// closures and methods created by ir will access the forked variables when called by the fork
+	ng.forks.envs = make(map[*Env]*Env, n)
+	for i, env := range envs {
+		ng.forks.envs[env] = nenvs[i]
+	}
+	g.forks.lock.Lock()
+	for env, fenv := range g.forks.envs {
+		// ir may be a fork too: closures created by its parent must also access the forked variables
+		if nenv := ng.forks.envs[fenv]; nenv != nil {
+			ng.forks.envs[env] = nenv
+		}
+	}
+	g.forks.lock.Unlock()
+	ng.forks.parent = g
+	ref := &forkRef{parent: g}
+	ref.add(1)
+	ng.forks.ref = ref
+	runtime.SetFinalizer(ref, (*forkRef).drop)
+
+	// recreate declared functions and macros, so that they access the forked variables
+	for i, nc := range ncomps {
+		for name, rm := range nc.remakers {
+			if bind := nc.Binds[name]; bind != nil && bind.Desc == rm.desc {
+				rm.remake(nenvs[i], bind)
+			}
+		}
+	}
+	return &Interp{Comp: ncomps[0], env: nenvs[0]}
+}
+
+// ================================== forked closures ==================================
+
+// forkTracker records which interpreter each goroutine is executing,
+// among an interpreter and its forks, once some fork exists:
+// closures and methods are bound to the *Env they were created in,
+// and newEnv4Func must know whether they are being called by a fork of that interpreter.
+// It is shared by an interpreter and all its forks
+type forkTracker struct {
+	n      int32 // number of forks alive. accessed atomically
+	lock   sync.RWMutex
+	active map[uintptr]*IrGlobals // goroutine id -> interpreter it is executing
+}
+
+func (t *forkTracker) count() int32 {
+	if t == nil {
+		return 0
+	}
+	return atomic.LoadInt32(&t.n)
+}
+
+func (t *forkTracker) add(delta int32) {
+	t.lock.Lock()
+	if t.active == nil {
+		t.active = make(map[uintptr]*IrGlobals)
+	}
+	t.lock.Unlock()
+	atomic.AddInt32(&t.n, delta)
+}
+
+func (t *forkTracker) get(goid uintptr) *IrGlobals {
+	t.lock.RLock()
+	g := t.active[goid]
+	t.lock.RUnlock()
+	return g
+}
+
+// record that the current goroutine is executing code of run's interpreter.
+// returns the function that restores the previous state
+func (run *Run) enterFork() func() {
+	t := run.forking
+	if t.count() == 0 {
+		return func() {}
+	}
+	goid := gls.GoID()
+	t.lock.Lock()
+	prev, ok := t.active[goid]
+	t.active[goid] = run.IrGlobals
+	t.lock.Unlock()
+	return func() {
+		t.lock.Lock()
+		if ok {
+			t.active[goid] = prev
+		} else {
+			delete(t.active, goid)
+		}
+		t.lock.Unlock()
+	}
+}
+
+// forkEnvs maps the *Env of a forked interpreter to their copies in the fork
+type forkEnvs struct {
+	forked int32 // number of forks alive of the interpreter, including forks of forks. accessed atomically
+	lock   sync.Mutex
+	envs   map[*Env]*Env
+	parent *IrGlobals // the interpreter this one was forked from
+	ref    *forkRef
+}
+
+// forkRef is referenced only by the IrGlobals of a fork, and is garbage collected together with it.
+// IrGlobals cannot have a finalizer: it references itself through Run and Env
+type forkRef struct {
+	parent *IrGlobals
+}
+
+// update the count of forks alive of ref.parent and its ancestors
+func (ref *forkRef) add(delta int32) {
+	g := ref.parent
+	g.forking.add(delta)
+	for ; g != nil; g = g.forks.parent {
+		atomic.AddInt32(&g.forks.forked, delta)
+	}
+}
+
+// called when a fork is garbage collected: its ancestors stop tracking it
+func (ref *forkRef) drop() {
+	ref.add(-1)
+}
+
+// if the current goroutine is executing a fork of env's interpreter,
+// return the fork's copy of env. Otherwise return env
+func forkedEnv(env *Env, goid uintptr) *Env {
+	if atomic.LoadInt32(&env.Run.forks.forked) == 0 {
+		return env
+	}
+	g := env.Run.forking.get(goid)
+	if g == nil || g == env.Run.IrGlobals {
+		return env
+	}
+	g.forks.lock.Lock()
+	nenv := g.forks.get(env)
+	g.forks.lock.Unlock()
+	if nenv == nil {
+		return env
+	}
+	return nenv
+}
+
+// return the copy of env, creating it if env is the local *Env of a function, captured by some closure.
+// return nil if env does not belong to the forked interpreter
+func (fe *forkEnvs) get(env *Env) *Env {
+	if env == nil {
+		return nil
+	} else if nenv := fe.envs[env]; nenv != nil {
+		return nenv
+	}
+	nouter := fe.get(env.Outer)
+	if nouter == nil {
+		return nil
+	}
+	nenv := &Env{
+		EnvBinds: EnvBinds{
+			Vals: make([]r.Value, len(env.Vals)),
+			Ints: append([]uint64(nil), env.Ints...),
+		},
+		Outer:           nouter,
+		Run:             nouter.Run,
+		FileEnv:         nouter.FileEnv,
+		DebugComp:       env.DebugComp,
+		CallDepth:       env.CallDepth,
+		UsedByClosure:   true, // do not free this *Env
+		IntAddressTaken: env.IntAddressTaken,
+	}
+	if fileenv := fe.envs[env.FileEnv]; fileenv != nil {
+		nenv.FileEnv = fileenv
+	}
+	for i, v := range env.Vals {
+		if v.IsValid() && v.CanAddr() {
+			nv := r.New(v.Type()).Elem()
+			nv.Set(v)
+			v = nv
+		}
+		nenv.Vals[i] = v
+	}
+	fe.envs[env] = nenv
+	return nenv
+}
+
+// return a copy of cb that can be modified without affecting cb
+func (cb *CompBinds) fork(imports map[*Import]*Import) CompBinds {
+	fork := *cb
+	if cb.Binds != nil {
+		fork.Binds = make(map[string]*Bind, len(cb.Binds))
+		for name, bind := range cb.Binds {
+			nbind := *bind
+			if imp, ok := nbind.Value.(*Import); ok && imports[imp] != nil {
+				nbind.Value = imports[imp]
+			}
+			fork.Binds[name] = &nbind
+		}
+	}
+	if cb.Types != nil {
+		fork.Types = make(map[string]xr.Type, len(cb.Types))
+		for name, t := range cb.Types {
+			fork.Types[name] = t
+		}
+	}
+	if cb.remakers != nil {
+		fork.remakers = make(map[string]funcRemaker, len(cb.remakers))
+		for name, rm := range cb.remakers {
+			fork.remakers[name] = rm
+		}
+	}
+	return fork
+}
+
+// return a copy of imp that can be modified without affecting imp.
+// the values of package variables are shared
+func (imp *Import) fork(imports map[*Import]*Import) *Import {
+	if fork := imports[imp]; fork != nil {
+		return fork
+	}
+	fork := &Import{}
+	imports[imp] = fork
+	var env Env
+	if imp.env != nil {
+		env = *imp.env
+	}
+	env.Vals = append([]r.Value(nil), imp.Vals...)
+	env.Ints = append([]uint64(nil), imp.Ints...)
+	fork.env = &env
+	fork.EnvBinds = &env.EnvBinds
+	fork.CompBinds = imp.CompBinds.fork(imports)
+	return fork
+}
+
+// ================================== deep copy ==================================
+
+type forkKey struct {
+	ptr uintptr
+	typ r.Type
+	len int // for slices
+	cap int // for slices
+}
+
+type intsFork struct {
+	from, to []uint64
+}
+
+// forker deep-copies values, preserving aliasing
+type forker struct {
+	memo map[forkKey]r.Value
+	ints []intsFork
+}
+
+func newForker() *forker {
+	return &forker{memo: make(map[forkKey]r.Value)}
+}
+
+// prepare nenv to receive a copy of env Vals and Ints.
+// variables are allocated in advance, so that pointers to them can be copied in any order
+func (f *forker) addEnv(env *Env, nenv *Env) {
+	nenv.Ints = make([]uint64, len(env.Ints), cap(env.Ints))
+	copy(nenv.Ints, env.Ints)
+	if cap(env.Ints) != 0 {
+		f.ints = append(f.ints, intsFork{env.Ints[:cap(env.Ints)], nenv.Ints[:cap(nenv.Ints)]})
+	}
+	nenv.Vals = make([]r.Value, len(env.Vals), cap(env.Vals))
+	for i, v := range env.Vals {
+		if v.IsValid() && v.CanAddr() {
+			nv := r.New(v.Type()).Elem()
+			f.memo[forkKey{ptr: v.UnsafeAddr(), typ: v.Type()}] = nv
+			nenv.Vals[i] = nv
+		}
+	}
+}
+
+func (f *forker) copyVals(env *Env, nenv *Env) {
+	for i, v := range env.Vals {
+		if !v.IsValid() {
+			continue
+		} else if v.CanAddr() {
+			if nv := nenv.Vals[i]; nv.CanSet() {
+				nv.Set(f.value(v))
+			} else {
+				nenv.Vals[i] = v
+			}
+		} else {
+			nenv.Vals[i] = f.value(v)
+		}
+	}
+}
+
+// return a deep copy of v
+func (f *forker) value(v r.Value) r.Value {
+	if !v.IsValid() || !v.CanInterface() {
+		return v
+	}
+	t := v.Type()
+	switch v.Kind() {
+	case r.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		return f.pointer(v)
+	case r.Interface:
+		if v.IsNil() {
+			return v
+		}
+		nv := r.New(t).Elem()
+		nv.Set(f.value(v.Elem()))
+		return nv
+	case r.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := forkKey{ptr: v.Pointer(), typ: t, len: v.Len(), cap: v.Cap()}
+		if nv, ok := f.memo[key]; ok {
+			return nv
+		}
+		n := v.Cap()
+		nv := r.MakeSlice(t, v.Len(), n)
+		f.memo[key] = nv
+		full, nfull := v.Slice(0, n), nv.Slice(0, n)
+		for i := 0; i < n; i++ {
+			nfull.Index(i).Set(f.value(full.Index(i)))
+		}
+		return nv
+	case r.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := forkKey{ptr: v.Pointer(), typ: t}
+		if nv, ok := f.memo[key]; ok {
+			return nv
+		}
+		nv := r.MakeMapWithSize(t, v.Len())
+		f.memo[key] = nv
+		for _, k := range v.MapKeys() {
+			nv.SetMapIndex(f.value(k), f.value(v.MapIndex(k)))
+		}
+		return nv
+	case r.Array:
+		nv := r.New(t).Elem()
+		for i, n := 0, v.Len(); i < n; i++ {
+			nv.Index(i).Set(f.value(v.Index(i)))
+		}
+		return nv
+	case r.Struct:
+		nv := r.New(t).Elem()
+		f.copyStruct(nv, v)
+		return nv
+	default:
+		// basic kinds are copied by value. channels, functions and unsafe.Pointer are shared
+		return v
+	}
+}
+
+func (f *forker) copyStruct(nv r.Value, v r.Value) {
+	if !forkable(v.Type()) {
+		nv.Set(v)
+		return
+	}
+	for i, n := 0, v.NumField(); i < n; i++ {
+		nv.Field(i).Set(f.value(v.Field(i)))
+	}
+}
+
+// return a copy of pointer v, or v itself if the pointed value cannot be deep-copied
+func (f *forker) pointer(v r.Value) r.Value {
+	elem := v.Type().Elem()
+	key := forkKey{ptr: v.Pointer(), typ: elem}
+	if nv, ok := f.memo[key]; ok {
+		return nv.Addr()
+	}
+	if nv, ok := f.intPointer(v); ok {
+		return nv
+	}
+	if !forkable(elem) {
+		return v
+	}
+	nv := r.New(elem).Elem()
+	f.memo[key] = nv
+	if elem.Kind() == r.Struct {
+		f.copyStruct(nv, v.Elem())
+	} else {
+		nv.Set(f.value(v.Elem()))
+	}
+	return nv.Addr()
+}
+
+// if v points inside some Env.Ints, return the pointer to the same position in the forked Env.Ints
+func (f *forker) intPointer(v r.Value) (r.Value, bool) {
+	ptr := v.Pointer()
+	for _, ints := range f.ints {
+		start := uintptr(unsafe.Pointer(&ints.from[0]))
+		end := start + uintptr(len(ints.from))*unsafe.Sizeof(ints.from[0])
+		if ptr >= start && ptr < end {
+			nptr := unsafe.Pointer(uintptr(unsafe.Pointer(&ints.to[0])) + (ptr - start))
+			return r.NewAt(v.Type().Elem(), nptr), true
+		}
+	}
+	return r.Value{}, false
+}
+
+// return false for struct types with unexported fields, as os.File or sync.Mutex:
+// they can only be copied as a whole, and pointers to them are shared
+func forkable(t r.Type) bool {
+	if t.Kind() != r.Struct {
+		return true
+	}
+	for i, n := 0, t.NumField(); i < n; i++ {
+		if len(t.Field(i).PkgPath) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -42,6 +42,11 @@ func (c *Comp) func0ret0(t xr.Type, m *funcMaker) func(env *Env) r.Value {
 		env.MarkUsedByClosure()
 		return r.ValueOf(func() {
 			env := newEnv4Func(env, nbind, nintbind, debugC)
+			if rec := recover(); rec != nil {
+				// deferred directly by compiled code while panicking
+				deferredByCompiled(env, funcbody, rec)
+				return
+			}
 			// execute the body
 			funcbody(env)
 
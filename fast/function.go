@@ -35,6 +35,36 @@ type funcMaker struct {
 	funcbody  func(*Env)
 }
 
+// declFuncFwd forward-declares a function: it declares the function name and type
+// without compiling its body. Used to compile mutually recursive functions
+func (c *Comp) declFuncFwd(funcdecl *ast.FuncDecl) {
+	funcname := funcdecl.Name.Name
+	if funcdecl.Recv != nil || funcname == "_" {
+		// only functions are forward-declared, not macros, methods or templates
+		return
+	}
+	t, _, _ := c.TypeFunction(funcdecl.Type)
+	funcbind := c.NewBind(funcname, FuncBind, t)
+	if c.funcFwds == nil {
+		c.funcFwds = make(map[string]*Bind)
+	}
+	c.funcFwds[funcname] = funcbind
+}
+
+// return the bind created by declFuncFwd for function funcname with type t,
+// or nil if the function was not forward-declared
+func (c *Comp) funcFwd(funcname string, t xr.Type) *Bind {
+	funcbind := c.funcFwds[funcname]
+	if funcbind == nil {
+		return nil
+	}
+	delete(c.funcFwds, funcname)
+	if funcbind != c.Binds[funcname] || !funcbind.Type.IdenticalTo(t) {
+		return nil
+	}
+	return funcbind
+}
+
 // DeclFunc compiles a function, macro or method declaration
 // For closure declarations, use FuncLit()
 //
@@ -75,7 +105,7 @@ func (c *Comp) DeclFunc(funcdecl *ast.FuncDecl) {
 	if ismacro {
 		// use a ConstBind, as builtins do
 		funcbind = c.NewBind(funcname, ConstBind, c.TypeOfMacro())
-	} else {
+	} else if funcbind = c.funcFwd(funcname, t); funcbind == nil {
 		funcbind = c.NewBind(funcname, FuncBind, t)
 	}
 	cf := NewComp(c, nil)
@@ -87,6 +117,7 @@ func (c *Comp) DeclFunc(funcdecl *ast.FuncDecl) {
 		for _, node := range body.List {
 			cf.Stmt(node)
 		}
+		cf.checkGotos()
 	}
 
 	funcindex := funcbind.Desc.Index()
@@ -112,6 +143,11 @@ func (c *Comp) DeclFunc(funcdecl *ast.FuncDecl) {
 			env.IP++
 			return env.Code[env.IP], env
 		}
+		c.addRemaker(funcname, funcbind, func(env *Env, bind *Bind) {
+			if bind.Value != nil {
+				bind.Value = Macro{f(env), argnum}
+			}
+		})
 	} else {
 		// a function declaration is a statement:
 		// executing it creates the function in the runtime environment
@@ -124,6 +160,11 @@ func (c *Comp) DeclFunc(funcdecl *ast.FuncDecl) {
 			env.IP++
 			return env.Code[env.IP], env
 		}
+		c.addRemaker(funcname, funcbind, func(env *Env, bind *Bind) {
+			if env.Vals[funcindex].IsValid() {
+				env.Vals[funcindex] = f(env)
+			}
+		})
 	}
 	c.Append(stmt, funcdecl.Pos())
 	panicking = false
@@ -150,6 +191,7 @@ func (c *Comp) methodAdd(funcdecl *ast.FuncDecl, t xr.Type) (methodindex int, me
 	if n1 == n2 {
 		c.Warnf("redefined method: %s.%s", trecv.Name(), name)
 	}
+	c.addNamedType(trecv)
 	methods = trecv.GetMethods()
 	panicking = false
 	return
@@ -164,6 +206,11 @@ func (c *Comp) methodDecl(funcdecl *ast.FuncDecl) {
 		return
 	}
 	recvdecl := funcdecl.Recv.List[0]
+	if typ, params := c.templateMethodRecv(recvdecl); typ != nil {
+		// method of a template type, as func (l *List[T]) Len() int
+		c.DeclTemplateMethod(funcdecl, typ, params)
+		return
+	}
 
 	functype := funcdecl.Type
 	t, paramnames, resultnames := c.TypeFunctionOrMethod(recvdecl, functype)
@@ -174,18 +221,7 @@ func (c *Comp) methodDecl(funcdecl *ast.FuncDecl) {
 	// declare the method name and type before compiling its body: allows recursive methods
 	methodindex, methods := c.methodAdd(funcdecl, t)
 
-	cf := NewComp(c, nil)
-	info, resultfuns := cf.funcBinds(funcdecl.Name.Name, functype, t, paramnames, resultnames)
-	cf.Func = info
-
-	body := funcdecl.Body
-	if body != nil && len(body.List) != 0 {
-		// in Go, function arguments/results and function body are in the same scope
-		cf.List(body.List)
-	}
-	// do NOT keep a reference to compile environment!
-	funcbody := cf.Code.Exec()
-	f := cf.funcCreate(t, info, resultfuns, funcbody)
+	f := c.methodBody(funcdecl, t, paramnames, resultnames)
 
 	// a method declaration is a statement:
 	// executing it sets the method value in the receiver type
@@ -213,6 +249,25 @@ func (c *Comp) methodDecl(funcdecl *ast.FuncDecl) {
 	c.Append(stmt, funcdecl.Pos())
 }
 
+// methodBody compiles the body of a method declaration.
+// Returns a function that creates the method at runtime
+func (c *Comp) methodBody(funcdecl *ast.FuncDecl, t xr.Type, paramnames, resultnames []string) func(*Env) r.Value {
+	functype := funcdecl.Type
+	cf := NewComp(c, nil)
+	info, resultfuns := cf.funcBinds(funcdecl.Name.Name, functype, t, paramnames, resultnames)
+	cf.Func = info
+
+	body := funcdecl.Body
+	if body != nil && len(body.List) != 0 {
+		// in Go, function arguments/results and function body are in the same scope
+		cf.List(body.List)
+		cf.checkGotos()
+	}
+	// do NOT keep a reference to compile environment!
+	funcbody := cf.Code.Exec()
+	return cf.funcCreate(t, info, resultfuns, funcbody)
+}
+
 // FuncLit compiles a function literal, i.e. a closure.
 // For functions or methods declarations, use FuncDecl()
 func (c *Comp) FuncLit(funclit *ast.FuncLit) *Expr {
@@ -227,6 +282,7 @@ func (c *Comp) FuncLit(funclit *ast.FuncLit) *Expr {
 	if body != nil && len(body.List) != 0 {
 		// in Go, function arguments/results and function body are in the same scope
 		cf.List(body.List)
+		cf.checkGotos()
 	}
 	// do NOT keep a reference to compile environment!
 	funcbody := cf.Code.Exec()
@@ -332,6 +388,7 @@ func (c *Comp) funcMaker(info *FuncInfo, resultfuns []I, funcbody func(*Env)) *f
 func (c *Comp) funcCreate(t xr.Type, info *FuncInfo, resultfuns []I, funcbody func(*Env)) func(*Env) r.Value {
 
 	m := c.funcMaker(info, resultfuns, funcbody)
+	c.traceFuncBody(t, m)
 
 	rtype := t.ReflectType() // has receiver as first parameter (unless it's xreflect.Forward)
 	nin := t.NumIn()
@@ -21,6 +21,7 @@ import (
 	"go/ast"
 	"go/constant"
 	"go/token"
+	"go/types"
 	r "reflect"
 	"sort"
 
@@ -113,12 +114,17 @@ type EFlags uint32
 const (
 	EIsNil EFlags = 1 << iota
 	EIsTypeAssert
+	EIsUntypedShift // non-constant shift of an untyped constant, whose type depends on the context
 )
 
 func (f EFlags) IsNil() bool {
 	return f&EIsNil != 0
 }
 
+func (f EFlags) IsUntypedShift() bool {
+	return f&EIsUntypedShift != 0
+}
+
 func MakeEFlag(flag bool, iftrue EFlags) EFlags {
 	if flag {
 		return iftrue
@@ -467,9 +473,11 @@ const (
 type ExecFlags uint32
 
 const (
-	EFStartDefer ExecFlags = 1 << iota // true next executed function body is a defer
-	EFDefer                            // function body being executed is a defer
-	EFDebug                            // function body is executed with debugging enabled
+	EFStartDefer   ExecFlags = 1 << iota // true next executed function body is a defer
+	EFDefer                              // function body being executed is a defer
+	EFDebug                              // function body is executed with debugging enabled
+	EFBreakOnPanic                       // enter the debugger if a statement panics. set from OptBreakOnPanic
+	EFProfile                            // the profiler is sampling statements
 )
 
 func (ef ExecFlags) StartDefer() bool {
@@ -484,6 +492,14 @@ func (ef ExecFlags) IsDebug() bool {
 	return ef&EFDebug != 0
 }
 
+func (ef ExecFlags) BreakOnPanic() bool {
+	return ef&EFBreakOnPanic != 0
+}
+
+func (ef ExecFlags) IsProfile() bool {
+	return ef&EFProfile != 0
+}
+
 func (ef *ExecFlags) SetDefer(flag bool) {
 	if flag {
 		(*ef) |= EFDefer
@@ -508,6 +524,22 @@ func (ef *ExecFlags) SetDebug(flag bool) {
 	}
 }
 
+func (ef *ExecFlags) SetBreakOnPanic(flag bool) {
+	if flag {
+		(*ef) |= EFBreakOnPanic
+	} else {
+		(*ef) &^= EFBreakOnPanic
+	}
+}
+
+func (ef *ExecFlags) SetProfile(flag bool) {
+	if flag {
+		(*ef) |= EFProfile
+	} else {
+		(*ef) &^= EFProfile
+	}
+}
+
 type DebugOp struct {
 	// statements at env.CallDepth < Depth will be executed in single-stepping mode,
 	// i.e. invoking the debugger after every statement
@@ -528,10 +560,22 @@ type Debugger interface {
 	At(ir *Interp, env *Env) DebugOp
 }
 
+// PanicDebugger is optionally implemented by a Debugger to be notified of panics
+type PanicDebugger interface {
+	// Panic is invoked when OptBreakOnPanic is set and a statement panics,
+	// before the stack unwinds. DebugOp.Panic replaces the panic value
+	Panic(ir *Interp, env *Env, rec interface{}) DebugOp
+}
+
 // IrGlobals contains interpreter configuration
 type IrGlobals struct {
-	gls  map[uintptr]*Run
-	lock atomic.SpinLock
+	gls     map[uintptr]*Run
+	lock    atomic.SpinLock
+	Tracer  *Tracer      // set by Interp.Trace()
+	Sandbox *Sandbox     // set by Interp.SetSandbox()
+	Stdio   *Stdio       // set by Interp.SetStdio()
+	forks   forkEnvs     // set by Interp.Fork()
+	forking *forkTracker // shared by an interpreter and its forks
 	Globals
 }
 
@@ -544,12 +588,17 @@ type Run struct {
 	ExecFlags    ExecFlags
 	CurrEnv      *Env        // caller of current function. used ONLY at function entry to build call stack
 	InstallDefer func()      // defer function to be installed
+	DeferGo      bool        // true if InstallDefer is a compiled function, to be deferred directly
 	DeferOfFun   *Env        // function whose defer are running
 	PanicFun     *Env        // the currently panicking function
 	Panic        interface{} // current panic. needed for recover()
+	PanicDebug   *Env        // where the debugger was entered for the current panic. nil if not entered
+	PanicValue   interface{} // value of the panic for which the debugger was entered
 	CmdOpt       CmdOpt
 	Debugger     Debugger
-	DebugDepth   int // depth of function to debug with single-step
+	DebugDepth   int       // depth of function to debug with single-step
+	Profiler     *Profiler // set by Interp.StartCPUProfile()
+	InterruptPos token.Pos // position of the statement executing when SigInterrupt was last serviced
 	PoolSize     int
 	Pool         [poolCapacity]*Env
 }
@@ -557,11 +606,15 @@ type Run struct {
 // CompGlobals contains interpreter compile bookeeping information
 type CompGlobals struct {
 	*IrGlobals
-	Universe     *xr.Universe
-	KnownImports map[string]*Import // map[path]*Import cache of known imports
-	interf2proxy map[r.Type]r.Type  // interface -> proxy
-	proxy2interf map[r.Type]xr.Type // proxy -> interface
-	Prompt       string
+	Universe      *xr.Universe
+	KnownImports  map[string]*Import         // map[path]*Import cache of known imports
+	interf2proxy  map[r.Type]r.Type          // interface -> proxy
+	proxy2interf  map[r.Type]xr.Type         // proxy -> interface
+	constraints   map[types.Type]*constraint // named interface -> constraint, for interfaces containing type sets
+	namedTypes    map[r.Type][]xr.Type       // reflect.Type -> interpreted named types
+	typesPackages map[*Import]*types.Package // import -> go/types package, for strict mode type checking
+	Prompt        string
+	Coverage      *Coverage // if not nil, compiled statements are instrumented to count their executions
 }
 
 func (cg *CompGlobals) CompileOptions() CompileOptions {
@@ -585,6 +638,8 @@ type CompBinds struct {
 	Types      map[string]xr.Type
 	Name       string // set by "package" directive
 	Path       string
+	remakers   map[string]funcRemaker // functions and macros declared at this level. used by Interp.Fork()
+	funcFwds   map[string]*Bind       // functions forward-declared at this level, whose body is not compiled yet
 }
 
 // Comp is a tree-of-closures builder: it transforms ast.Nodes into closures
@@ -600,6 +655,7 @@ type Comp struct {
 	Loop      *LoopInfo // != nil when compiling a for or switch
 	Func      *FuncInfo // != nil when compiling a function
 	Labels    map[string]*int
+	gotos     *gotoInfo // labels and forward gotos. only set in the *Comp of a function or top-level statement
 	Outer     *Comp
 	FuncMaker *funcMaker // used by debugger command 'backtrace' to obtain function name, type and binds for arguments and results
 }
@@ -142,16 +142,14 @@ func (c *Comp) ImportPackage(name, path string) *Import {
 // If name is the empty string, it defaults to the identifier
 // specified in the package clause of the imported package
 func (c *Comp) ImportPackageOrError(name, path string) (*Import, error) {
-	g := c.CompGlobals
-	imp := g.KnownImports[path]
-	if imp == nil {
-		pkgref, err := g.Importer.ImportPackageOrError(name, path)
-		if err != nil {
-			return nil, err
-		}
-		imp = g.NewImport(pkgref)
+	imp, err := c.loadImport(path)
+	if err != nil {
+		return nil, err
 	}
 	if name == "." {
+		if err := c.checkDotImport(imp); err != nil {
+			return nil, err
+		}
 		c.declDotImport0(imp)
 	} else if name != "_" {
 		// https://golang.org/ref/spec#Package_clause states:
@@ -162,7 +160,24 @@ func (c *Comp) ImportPackageOrError(name, path string) (*Import, error) {
 		}
 		c.declImport0(name, imp)
 	}
-	g.KnownImports[path] = imp
+	return imp, nil
+}
+
+// loadImport loads a package and adds it to known imports, without declaring it
+func (c *Comp) loadImport(path string) (*Import, error) {
+	if err := c.checkImport(path); err != nil {
+		return nil, err
+	}
+	g := c.CompGlobals
+	imp := g.KnownImports[path]
+	if imp == nil {
+		pkgref, err := g.Importer.ImportPackageOrError("", path)
+		if err != nil {
+			return nil, err
+		}
+		imp = g.NewImport(pkgref)
+		g.KnownImports[path] = imp
+	}
 	return imp, nil
 }
 
@@ -288,6 +303,7 @@ func (g *CompGlobals) NewImport(pkgref *genimport.PackageRef) *Import {
 		imp.loadTypes(g, pkgref)
 		imp.loadBinds(g, pkgref)
 		g.loadProxies(pkgref.Proxies, imp.Types)
+		g.rebindStdio(imp)
 	}
 	return imp
 }
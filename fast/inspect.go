@@ -17,6 +17,7 @@
 package fast
 
 import (
+	"fmt"
 	r "reflect"
 
 	. "github.com/steele232/zoumacro/base"
@@ -42,5 +43,46 @@ func (ir *Interp) Inspect(src string) {
 		}
 		typ = val.Type()
 	}
+	if setter, ok := inspector.(interface {
+		SetEval(func(src string, t r.Type) (r.Value, error))
+	}); ok {
+		setter.SetEval(ir.inspectEval)
+	}
 	inspector.Inspect(src, val, typ, xtyp, &ir.Comp.Globals)
 }
+
+// compile and execute src, for inspector command 'set'.
+// return an error if the result is not assignable to t
+func (ir *Interp) inspectEval(src string, t r.Type) (val r.Value, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			val, err = Nil, fmt.Errorf("%v", rec)
+		}
+	}()
+	c := ir.Comp
+	e := c.Compile(c.Parse(src))
+	if e == nil {
+		return Nil, fmt.Errorf("expression returns no values: %s", src)
+	}
+	e.CheckX1()
+	if e.Untyped() {
+		e.ConstTo(c.Universe.FromReflectType(t))
+	}
+	if e.Const() && e.Type == nil {
+		// untyped nil
+		val = Nil
+	} else {
+		val, _ = ir.RunExpr1(e)
+	}
+	if !val.IsValid() || val == None {
+		switch t.Kind() {
+		case r.Chan, r.Func, r.Interface, r.Map, r.Ptr, r.Slice:
+			return r.Zero(t), nil
+		}
+		return Nil, fmt.Errorf("cannot use nil as <%v>", t)
+	}
+	if !val.Type().AssignableTo(t) {
+		return Nil, fmt.Errorf("cannot use %v <%v> as <%v>", val, val.Type(), t)
+	}
+	return val, nil
+}
@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"go/ast"
 	r "reflect"
+	"sync"
 
 	"github.com/steele232/zoumacro/base/reflect"
 
 	"github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/typeutil"
 	xr "github.com/steele232/zoumacro/xreflect"
 )
 
@@ -32,7 +34,7 @@ func (c *Comp) TypeInterface(node *ast.InterfaceType) xr.Type {
 	if node.Methods == nil || len(node.Methods.List) == 0 {
 		return c.TypeOfInterface()
 	}
-	types, names := c.TypeFields(node.Methods)
+	types, names := c.TypeFields(typeSetFilter(node.Methods))
 
 	// parser returns embedded interfaces as unnamed fields
 	var methodnames []string
@@ -41,15 +43,35 @@ func (c *Comp) TypeInterface(node *ast.InterfaceType) xr.Type {
 		if i < len(names) && len(names[i]) != 0 {
 			methodnames = append(methodnames, names[i])
 			methodtypes = append(methodtypes, typ)
-		} else {
+		} else if typ.Kind() == r.Interface {
 			embeddedtypes = append(embeddedtypes, typ)
 		}
+		// embedded non-interface types are type set elements:
+		// they only matter for constraints, see Comp.constraint()
 	}
 	universe := c.Universe
 	pkg := universe.LoadPackage(c.FileComp().Path)
 	return universe.InterfaceOf(pkg, methodnames, methodtypes, embeddedtypes)
 }
 
+// return fields without type set elements ~T and T1 | T2, which only matter for constraints
+func typeSetFilter(fields *ast.FieldList) *ast.FieldList {
+	var list []*ast.Field
+	for i, field := range fields.List {
+		if len(field.Names) != 0 || !isTypeSetElem(field.Type) {
+			if list != nil {
+				list = append(list, field)
+			}
+		} else if list == nil {
+			list = append(make([]*ast.Field, 0, len(fields.List)), fields.List[:i]...)
+		}
+	}
+	if list == nil {
+		return fields
+	}
+	return &ast.FieldList{Opening: fields.Opening, List: list, Closing: fields.Closing}
+}
+
 // InterfaceProxy returns the proxy struct that implements a compiled interface
 func (c *Comp) InterfaceProxy(t xr.Type) r.Type {
 	ret := c.interf2proxy[t.ReflectType()]
@@ -65,11 +87,16 @@ func (c *Comp) converterToProxy(tin xr.Type, tout xr.Type) func(val r.Value) r.V
 	rtout := tout.ReflectType()       // a compiled interface
 	rtproxy := c.InterfaceProxy(tout) // one of our proxies that pre-implement the compiled interface
 
+	tsrc := tin
+	if tin.Kind() == r.Ptr {
+		// xr.Type.MethodByName wants T, not *T, even for methods with pointer receiver
+		tsrc = tin.Elem()
+	}
 	vtable := r.New(rtproxy).Elem()
 	n := rtout.NumMethod()
 	for i := 0; i < n; i++ {
 		mtdout := rtout.Method(i)
-		mtdin, count := tin.MethodByName(mtdout.Name, mtdout.PkgPath)
+		mtdin, count := tsrc.MethodByName(mtdout.Name, mtdout.PkgPath)
 		if count == 0 {
 			c.Errorf("cannot convert type <%v> to interface <%v>: missing method %s %s", tin, rtout, mtdout.PkgPath, mtdout.Name)
 		} else if count > 1 {
@@ -117,7 +144,8 @@ func setProxyField(place r.Value, mtd r.Value) {
 }
 
 // extract a value from a proxy struct (one of the imports.* structs) that implements an interface
-// this is the inverse of the function returned by Comp.converterToProxy() above
+// this is the inverse of the function returned by Comp.converterToProxy() above.
+// Also extracts values from emulated interfaces stored inside compiled interfaces
 func (g *CompGlobals) extractFromProxy(v r.Value) (r.Value, xr.Type) {
 	// base.Debugf("type assertion: value = %v <%v>", v, base.Type(v))
 
@@ -132,6 +160,9 @@ func (g *CompGlobals) extractFromProxy(v r.Value) (r.Value, xr.Type) {
 	rt := v.Type()
 	var xt xr.Type
 	// base.Debugf("type assertion: concrete value = %v <%v>", i, t)
+	if xr.IsEmulatedInterfaceReflectType(rt) {
+		return xr.FromEmulatedInterface(v)
+	}
 	if rt != nil && rt.Kind() == r.Ptr && g.proxy2interf[rt.Elem()] != nil {
 		v = v.Elem().Field(0)
 		if j, ok := reflect.Interface(v).(xr.InterfaceHeader); ok {
@@ -198,6 +229,116 @@ func (c *Comp) converterToEmulatedInterface(tin, tout xr.Type) func(val r.Value)
 	}
 }
 
+// interfaceAsserter returns a function that converts a value extracted from an interface,
+// and its interpreted type t (may be nil), to the interface type tout.
+// The returned function reports false if the value is nil or its dynamic type does not implement tout.
+// Used by type assertions and type switches on interface types, both compiled and emulated
+func (c *Comp) interfaceAsserter(tout xr.Type) func(v r.Value, t xr.Type) (r.Value, bool) {
+	rtout := tout.ReflectType()
+	emulated := xr.IsEmulatedInterface(tout)
+
+	// converters for each dynamic type seen so far. nil means dynamic type does not implement tout
+	var mutex sync.Mutex
+	var cache typeutil.Map // interpreted type -> converter
+
+	converter := func(rt r.Type, t xr.Type) func(r.Value) r.Value {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if t == nil {
+			// not cached by reflect.Type: the interpreted types implemented by rt may change
+			t = c.dynamicType(rt, tout)
+		}
+		if conv, ok := cache.At(t.GoType()).(func(r.Value) r.Value); ok {
+			return conv
+		}
+		// need the compiler at run-time :(
+		var conv func(r.Value) r.Value
+		if t.Implements(tout) {
+			conv = c.Converter(t, tout)
+			if conv == nil {
+				conv = func(v r.Value) r.Value {
+					return v
+				}
+			}
+		}
+		cache.Set(t.GoType(), conv)
+		return conv
+	}
+	return func(v r.Value, t xr.Type) (r.Value, bool) {
+		if v.Kind() == r.Interface {
+			v = v.Elem()
+		}
+		if !v.IsValid() || v == base.None {
+			return v, false
+		}
+		rt := v.Type()
+		if t == nil && !emulated && rt.Implements(rtout) {
+			// fast path: compiled type implementing compiled interface
+			return convert(v, rtout), true
+		}
+		conv := converter(rt, t)
+		if conv == nil {
+			return v, false
+		}
+		return conv(v), true
+	}
+}
+
+// dynamicType returns the type of a value extracted from an interface
+// when the interpreted type is not known, as for interpreted values stored in compiled interfaces:
+// the interpreted named type with reflect.Type rt, if any, otherwise the type corresponding to rt.
+// Panics with *AmbiguousTypeError if several interpreted types have reflect.Type rt and some implement tout
+func (g *CompGlobals) dynamicType(rt r.Type, tout xr.Type) xr.Type {
+	list := g.interpretedTypes(rt)
+	if len(list) > 1 {
+		for _, t := range list {
+			if t.Implements(tout) {
+				panic(&AmbiguousTypeError{ReflectType: rt, Types: list})
+			}
+		}
+	} else if len(list) == 1 && (len(rt.Name()) == 0 || list[0].Implements(tout)) {
+		// an unnamed reflect.Type, as struct { n int }, is the interpreted type.
+		// a named reflect.Type, as int, is also a compiled type:
+		// prefer the interpreted type only if it implements tout
+		return list[0]
+	}
+	return g.Universe.FromReflectType(rt)
+}
+
+// return the interpreted named types, or pointers to them, implemented by reflect.Type rt
+func (g *CompGlobals) interpretedTypes(rt r.Type) []xr.Type {
+	list := g.namedTypes[rt]
+	if len(list) != 0 || rt.Kind() != r.Ptr {
+		return list
+	}
+	// pointers to interpreted named types are not registered
+	list = g.namedTypes[rt.Elem()]
+	ptrs := make([]xr.Type, len(list))
+	for i, t := range list {
+		ptrs[i] = g.Universe.PtrTo(t)
+	}
+	return ptrs
+}
+
+// remember the reflect.Type of interpreted named type t. Used by CompGlobals.dynamicType()
+func (g *CompGlobals) addNamedType(t xr.Type) {
+	rt := t.ReflectType()
+	if g.namedTypes == nil {
+		g.namedTypes = make(map[r.Type][]xr.Type)
+	}
+	list := g.namedTypes[rt]
+	for i, other := range list {
+		if other.IdenticalTo(t) {
+			return
+		} else if xr.QName1(other) == xr.QName1(t) {
+			// t was redefined
+			list[i] = t
+			return
+		}
+	}
+	g.namedTypes[rt] = append(list, t)
+}
+
 // return a function that extracts value wrapped in a proxy or emulated interface
 // returns nil if no extraction is needed
 func (g *CompGlobals) extractor(tin xr.Type) func(r.Value) (r.Value, xr.Type) {
@@ -24,6 +24,7 @@ import (
 	"io"
 	"os"
 	r "reflect"
+	"strings"
 
 	. "github.com/steele232/zoumacro/base"
 	"github.com/steele232/zoumacro/base/paths"
@@ -261,7 +262,15 @@ func (ir *Interp) Eval(src string) ([]r.Value, []xr.Type) {
 	return ir.RunExpr(ir.Compile(src))
 }
 
+// EvalFile reads, compiles and executes a file.
+// Go files are evaluated in batch mode, see EvalFiles.
+// Other files, as *.gomacro, are evaluated as if typed at REPL:
+// each declaration or statement is executed before reading the next one,
+// thus macros can be used as soon as they are declared
 func (ir *Interp) EvalFile(filepath string) (comments string, err error) {
+	if strings.HasSuffix(filepath, ".go") {
+		return ir.EvalFiles(filepath)
+	}
 	g := ir.Comp.CompGlobals
 	saveFilename := g.Filepath
 	f, err := os.Open(filepath)
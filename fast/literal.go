@@ -169,6 +169,13 @@ func (lit *Lit) ConstTo(t xr.Type) I {
 	return nil
 }
 
+// return true if the typed constant e cannot be converted to interface type t by Lit.ConstTo(),
+// because its interpreted type implements t only thanks to interpreted methods
+func (e *Expr) constNeedsConverter(t xr.Type) bool {
+	return e.Const() && !e.Untyped() && e.Type != nil && t != nil && t.Kind() == r.Interface &&
+		e.Value != nil && !e.Type.ReflectType().ConvertibleTo(t.ReflectType()) && e.Type.Implements(t)
+}
+
 // return a closure that duplicates at each invokation any *big.Int, *big.Rat, *big.Float passed as 'val'
 func makeMathBigFun(val I) func(*Env) r.Value {
 	switch a := val.(type) {
@@ -256,8 +263,14 @@ func (lit *Lit) Set(x I) {
 // panics if Expr has an incompatible type.
 func (e *Expr) To(c *Comp, t xr.Type) {
 	if e.Const() {
-		e.ConstTo(t)
-		return
+		if !e.constNeedsConverter(t) {
+			e.ConstTo(t)
+			return
+		}
+		// typed constant of interpreted type converted to an interface:
+		// the conversion may need a proxy, perform it at runtime
+		e.WithFun()
+		e.Value = nil
 	}
 	if e.Type.IdenticalTo(t) {
 		return
@@ -266,7 +279,9 @@ func (e *Expr) To(c *Comp, t xr.Type) {
 		c.Errorf("cannot use <%v> as <%v>", e.Type, t)
 	}
 	k := e.Type.Kind()
-	if reflect.IsOptimizedKind(k) {
+	if reflect.IsOptimizedKind(k) && (k == t.Kind() || t.Kind() != r.Interface ||
+		(!xr.IsEmulatedInterface(t) && e.Type.ReflectType().Implements(t.ReflectType()))) {
+		// interpreted types implementing interfaces with interpreted methods need a Converter, see below
 		if k == t.Kind() {
 			// same optimized representation
 			e.Type = t
@@ -262,6 +262,9 @@ func (c *Comp) MacroExpand1(in Ast) (out Ast, expanded bool) {
 			any := results[0].Interface()
 			if any != nil {
 				out = anyToAst(any, "macroexpansion")
+				if c.Coverage != nil {
+					coverMacroExpansion(out, ToNode(elt).Pos(), ToNode(ins.Get(i+argn)).End())
+				}
 				break
 			}
 			fallthrough
@@ -0,0 +1,145 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * profile.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/base/profile"
+)
+
+// ProfilePeriod is the interval between samples taken by Interp.StartCPUProfile
+const ProfilePeriod = 10 * time.Millisecond
+
+// Profiler samples the interpreted call stack of a Run at regular intervals.
+// Samples are taken at statement boundaries, using Env.IP, Env.DebugPos and Env.Caller
+type Profiler struct {
+	lock sync.Mutex
+	prof *profile.Profile
+	out  io.Writer
+	file *os.File // closed by StopCPUProfile, if set by StartCPUProfileFile
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartCPUProfile starts sampling the interpreted code executed by ir.
+// Samples are written to out by StopCPUProfile() in the format read by 'go tool pprof'.
+// Only the goroutine that evaluates code with ir is sampled:
+// goroutines started by interpreted 'go' statements are not.
+func (ir *Interp) StartCPUProfile(out io.Writer) error {
+	run := ir.env.Run
+	if run.Profiler != nil {
+		return errors.New("cpu profiling already in use")
+	}
+	p := &Profiler{
+		prof: profile.New("samples", "count", ProfilePeriod),
+		out:  out,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	run.Profiler = p
+	run.ExecFlags.SetProfile(true)
+	go p.tick(run)
+	return nil
+}
+
+// StartCPUProfileFile is a wrapper around StartCPUProfile
+// that creates the file and closes it when profiling is stopped
+func (ir *Interp) StartCPUProfileFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err = ir.StartCPUProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	ir.env.Run.Profiler.file = f
+	return nil
+}
+
+// StopCPUProfile stops the profiler started by StartCPUProfile,
+// and writes the collected samples
+func (ir *Interp) StopCPUProfile() error {
+	run := ir.env.Run
+	p := run.Profiler
+	if p == nil {
+		return errors.New("cpu profiling not started")
+	}
+	close(p.stop)
+	<-p.done
+	run.Profiler = nil
+	run.ExecFlags.SetProfile(false)
+	run.Signals.SetProfile(SigNone)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.prof.Duration = time.Since(p.prof.Start)
+	err := p.prof.Write(p.out)
+	if p.file != nil {
+		if err2 := p.file.Close(); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// ask run to take a sample at each tick.
+// a sample not yet taken when the next tick arrives is not counted twice
+func (p *Profiler) tick(run *Run) {
+	ticker := time.NewTicker(ProfilePeriod)
+	defer func() {
+		ticker.Stop()
+		close(p.done)
+	}()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			run.Signals.SetProfile(SigProfile)
+		}
+	}
+}
+
+// sampleProfile records the interpreted call stack that starts at env
+func (run *Run) sampleProfile(env *Env) {
+	run.Signals.SetProfile(SigNone)
+	p := run.Profiler
+	if p == nil {
+		return
+	}
+	stack := run.profileStack(env)
+	p.lock.Lock()
+	p.prof.Add(stack, 1)
+	p.lock.Unlock()
+}
+
+// return the interpreted call stack that starts at env, innermost frame first
+func (run *Run) profileStack(env *Env) []profile.Frame {
+	frames := run.backtrace(env)
+	stack := make([]profile.Frame, len(frames))
+	for i, frame := range frames {
+		stack[i] = profile.Frame{Func: frame.Func, File: frame.Pos.Filename, Line: frame.Pos.Line}
+	}
+	return stack
+}
@@ -0,0 +1,186 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * program.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	r "reflect"
+	"sort"
+
+	"github.com/steele232/zoumacro/ast2"
+	. "github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/gls"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// Program is source code compiled once by Interp.CompileProgram or Interp.CompileProgramFiles,
+// that can be executed many times - also concurrently - by Program.Run
+type Program struct {
+	ir       *Interp
+	comp     *Comp // contains the bind layout of the program's top-level declarations
+	exprs    []*Expr
+	vars     map[string]*programVar
+	fallback token.Position // position reported by runtime errors, if the interpreted call stack does not contain one
+}
+
+type programVar struct {
+	index int
+	value r.Value // default value
+}
+
+// CompileProgram parses and compiles src once, and returns a Program that can be executed many times.
+// The program can use the imports and declarations of ir, and the variables listed in vars:
+// the type of each variable is the type of its value in vars, which is also its default value.
+// Declarations in src are not visible to ir: each Program.Run creates them anew
+func (ir *Interp) CompileProgram(src string, vars map[string]interface{}) (*Program, error) {
+	p, err := ir.newProgram(vars)
+	if err == nil {
+		err = p.compile(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CompileProgramFiles is like CompileProgram, for the contents of one or more files.
+// Files are compiled in order: each one can use the declarations of the previous ones
+func (ir *Interp) CompileProgramFiles(vars map[string]interface{}, filenames ...string) (*Program, error) {
+	p, err := ir.newProgram(vars)
+	if err != nil {
+		return nil, err
+	}
+	g := &p.comp.Globals
+	saveFilepath, saveLine := g.Filepath, g.Line
+	defer func() {
+		g.Filepath, g.Line = saveFilepath, saveLine
+	}()
+	for _, filename := range filenames {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		g.Filepath, g.Line = filename, 0
+		if err = p.compile(string(src)); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (ir *Interp) newProgram(vars map[string]interface{}) (*Program, error) {
+	ir.PrepareEnv()
+	c := NewComp(ir.Comp, nil)
+	p := &Program{
+		ir:   ir,
+		comp: c,
+		vars: make(map[string]*programVar, len(vars)),
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names) // be deterministic
+	for _, name := range names {
+		value := vars[name]
+		if value == nil {
+			return nil, fmt.Errorf("program variable %s has nil value, cannot infer its type", name)
+		}
+		v := r.ValueOf(value)
+		// use c.CompBinds.NewBind() to prevent optimization VarBind -> IntBind
+		bind := c.CompBinds.NewBind(&c.Output, name, VarBind, c.Universe.FromReflectType(v.Type()))
+		if idx := bind.Desc.Index(); idx != NoIndex {
+			p.vars[name] = &programVar{index: idx, value: v}
+		}
+	}
+	return p, nil
+}
+
+func (p *Program) compile(src string) error {
+	c := p.comp
+	form, err := c.catchParse(func() ast2.Ast {
+		return c.Parse(src)
+	})
+	if err != nil {
+		return err
+	}
+	e, err := c.catchCompile(func() *Expr {
+		e := c.Compile(form)
+		// convert untyped constants now: Program.Run must not modify e
+		if e != nil && c.Globals.Options&OptKeepUntyped == 0 && e.Untyped() {
+			e.ConstTo(e.DefaultType())
+		}
+		return e
+	})
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		p.exprs = append(p.exprs, e)
+	}
+	p.fallback = c.Position()
+	return nil
+}
+
+// Run executes the program in a new Env, where the program's top-level declarations are created anew.
+// vars contains the values of the variables declared by CompileProgram: the missing ones have their default value.
+// Returns the values of the last expression in the program, or an error as Interp.EvalErr.
+// Run can be called concurrently from several goroutines, provided that the program does not modify
+// the variables of the Interp that compiled it, and does not declare methods: they are shared among all runs
+func (p *Program) Run(vars map[string]interface{}) ([]r.Value, []xr.Type, error) {
+	for name := range vars {
+		if p.vars[name] == nil {
+			return nil, nil, fmt.Errorf("program has no variable %s", name)
+		}
+	}
+	outer := p.ir.env
+	run := outer.Run
+	if goid := gls.GoID(); run.goid != goid {
+		if run = run.glsGet(goid); run == nil {
+			run = outer.Run.new(goid)
+			run.glsStore()
+			defer run.glsDel()
+		}
+	}
+	env := newEnv(run, outer, p.comp.BindNum, p.comp.IntBindNum)
+	env.CallDepth = outer.CallDepth
+
+	for name, pv := range p.vars {
+		v := r.New(pv.value.Type()).Elem()
+		if value, ok := vars[name]; !ok {
+			v.Set(pv.value)
+		} else if value != nil {
+			rv := r.ValueOf(value)
+			if !rv.Type().AssignableTo(v.Type()) {
+				return nil, nil, fmt.Errorf("cannot use %v <%v> as program variable %s of type <%v>", value, rv.Type(), name, v.Type())
+			}
+			v.Set(rv)
+		}
+		env.Vals[pv.index] = v
+	}
+	var vals []r.Value
+	var types []xr.Type
+	for _, e := range p.exprs {
+		var err error
+		if vals, types, err = runExprErr(env, e, p.fallback); err != nil {
+			return nil, nil, err
+		}
+	}
+	return vals, types, nil
+}
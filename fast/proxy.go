@@ -0,0 +1,180 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * proxy.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"go/ast"
+	r "reflect"
+
+	"github.com/steele232/zoumacro/imports"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// reflect cannot add methods to the types it creates, so compiled code
+// cannot see the methods of interpreted types. Converting them to a compiled interface
+// wraps them in the proxy that implements such interface, see Comp.converterToProxy().
+//
+// Many compiled functions accept interface{} arguments, then check with type assertions
+// whether they implement some well-known interface, as fmt.Stringer or json.Marshaler.
+// Values of interpreted types passed to such functions are wrapped in the proxy
+// of the first well-known interface they implement, among the ones looked for
+// by the package of the called function. Values passed to other packages are not wrapped:
+// a proxy hides the original value from reflect, encoding/json and similar packages.
+
+type wellKnownInterface struct {
+	pkgpath, name string
+	user          string // package whose functions look for the interface
+}
+
+// well-known interfaces, in order of preference
+var wellKnownInterfaces = []wellKnownInterface{
+	{"fmt", "Formatter", "fmt"},
+	{"", "error", "fmt"},
+	{"fmt", "Stringer", "fmt"},
+	{"fmt", "GoStringer", "fmt"},
+	{"encoding/json", "Marshaler", "encoding/json"},
+	{"encoding/json", "Unmarshaler", "encoding/json"},
+	{"encoding/xml", "Marshaler", "encoding/xml"},
+	{"encoding/xml", "Unmarshaler", "encoding/xml"},
+	{"encoding/gob", "GobEncoder", "encoding/gob"},
+	{"encoding/gob", "GobDecoder", "encoding/gob"},
+	{"encoding", "TextMarshaler", "encoding"},
+	{"encoding", "TextUnmarshaler", "encoding"},
+	{"encoding", "BinaryMarshaler", "encoding"},
+	{"encoding", "BinaryUnmarshaler", "encoding"},
+}
+
+// return the well-known interface type, and load its proxy.
+// returns nil if the package of the interface, or its proxy, are not available
+func (g *CompGlobals) wellKnownInterface(w wellKnownInterface) xr.Type {
+	if len(w.pkgpath) == 0 {
+		return g.TypeOfError()
+	}
+	pkg, ok := imports.Packages[w.pkgpath]
+	if !ok {
+		return nil
+	}
+	rtype, proxy := pkg.Types[w.name], pkg.Proxies[w.name]
+	if rtype == nil || proxy == nil {
+		return nil
+	}
+	if t := g.proxy2interf[proxy]; t != nil {
+		return t
+	}
+	t := g.Universe.FromReflectType(rtype)
+	g.loadProxies(map[string]r.Type{w.name: proxy}, map[string]xr.Type{w.name: t})
+	return t
+}
+
+// methodProxy returns a function that wraps values of interpreted type t
+// in the proxy of the first well-known interface implemented by t
+// and looked for by pkgpath, the package of the called function.
+// returns nil if t is not an interpreted type, or if it implements no such interface
+func (c *Comp) methodProxy(t xr.Type, pkgpath string) func(r.Value) r.Value {
+	if !isInterpretedNamed(t) || pkgpath == "reflect" {
+		return nil
+	}
+	var tout xr.Type
+	for _, w := range wellKnownInterfaces {
+		if w.user != pkgpath {
+			continue
+		}
+		if iface := c.wellKnownInterface(w); iface != nil && t.Implements(iface) {
+			tout = iface
+			break
+		}
+	}
+	if tout == nil {
+		return nil
+	}
+	conv := c.converterToProxy(t, tout)
+	return func(v r.Value) r.Value {
+		if v.Kind() == r.Ptr && v.IsNil() {
+			// do not hide nil pointers inside a proxy
+			return v
+		}
+		return conv(v)
+	}
+}
+
+// proxyArgs wraps in proxies the arguments of interpreted types
+// passed to interface{} params of compiled functions
+func (c *Comp) proxyArgs(node *ast.CallExpr, t xr.Type, args []*Expr) {
+	pkgpath, ok := c.compiledCallee(node.Fun)
+	if !ok {
+		return
+	}
+	for i, ti := range paramTypes(t, len(args), false) {
+		arg := args[i]
+		if ti == nil || ti.Kind() != r.Interface || ti.NumMethod() != 0 || arg.NumOut() != 1 {
+			continue
+		}
+		if targ := arg.Type; targ != nil && targ.Kind() == r.Interface && targ.NumMethod() != 0 &&
+			!xr.IsEmulatedInterface(targ) {
+			// a compiled interface may contain a proxy: do not extract the value inside it
+			args[i] = c.proxyArg(arg, ti, nil)
+		} else if conv := c.methodProxy(targ, pkgpath); conv != nil {
+			args[i] = c.proxyArg(arg, ti, conv)
+		}
+	}
+}
+
+// if node is a function of a compiled package, or a method of a compiled type,
+// return the package path and true
+func (c *Comp) compiledCallee(node ast.Expr) (string, bool) {
+	sel, ok := node.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	sym := c.TryResolve(ident.Name)
+	if sym == nil {
+		return "", false
+	}
+	if imp, ok := sym.Value.(*Import); ok && sym.Desc.Class() == ConstBind {
+		return imp.Path, true
+	}
+	t := sym.Type
+	if t != nil && t.Kind() == r.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || !t.Named() || len(t.PkgPath()) == 0 || isInterpretedNamed(t) || xr.IsEmulatedInterface(t) {
+		return "", false
+	}
+	return t.PkgPath(), true
+}
+
+// wrap arg in a proxy if conv != nil, and convert it to the interface type t
+func (c *Comp) proxyArg(arg *Expr, t xr.Type, conv func(r.Value) r.Value) *Expr {
+	fun := arg.AsX1()
+	rtype := t.ReflectType()
+	zero := r.Zero(rtype)
+	if conv == nil {
+		return exprX1(t, func(env *Env) r.Value {
+			if v := fun(env); v.IsValid() && (v.Kind() != r.Interface || !v.IsNil()) {
+				return convert(v, rtype)
+			}
+			return zero
+		})
+	}
+	return exprX1(t, func(env *Env) r.Value {
+		return convert(conv(fun(env)), rtype)
+	})
+}
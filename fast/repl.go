@@ -80,6 +80,11 @@ func (ir *Interp) CompileNode(node ast.Node) *Expr {
 }
 
 func (ir *Interp) CompileAst(form ast2.Ast) *Expr {
+	return ir.compileAst(form, false)
+}
+
+// compile form. files must be true if form contains whole files, see Comp.typeCheck
+func (ir *Interp) compileAst(form ast2.Ast, files bool) *Expr {
 	if form == nil {
 		return nil
 	}
@@ -91,6 +96,10 @@ func (ir *Interp) CompileAst(form ast2.Ast) *Expr {
 		return c.exprValue(c.TypeOf(x), x)
 	}
 
+	if g.Options&OptTypeCheck != 0 {
+		c.typeCheck(form, files)
+	}
+
 	// compile phase
 	expr := c.Compile(form)
 
@@ -128,6 +137,7 @@ func (ir *Interp) RunExpr(e *Expr) ([]r.Value, []xr.Type) {
 	run.applyDebugOp(DebugOpContinue)
 
 	defer run.setCurrEnv(run.setCurrEnv(env))
+	defer run.enterFork()()
 
 	fun := e.AsXV(COptKeepUntyped)
 	v, vs := fun(env)
@@ -158,6 +168,7 @@ func (ir *Interp) DebugExpr(e *Expr) ([]r.Value, []xr.Type) {
 	run := env.Run
 	run.applyDebugOp(DebugOpStep)
 	defer run.setCurrEnv(run.setCurrEnv(env))
+	defer run.enterFork()()
 
 	fun := e.AsXV(COptKeepUntyped)
 	v, vs := fun(env)
@@ -243,12 +254,18 @@ func (ir *Interp) prepareEnv(minValDelta int, minIntDelta int) *Env {
 	// in case we received a SigInterrupt in the meantime
 	g.Signals.Sync = SigNone
 	g.Signals.Async = SigNone
+	g.Signals.SetProfile(SigNone) // do not count the time spent waiting for input
+	g.resetPanicDebug()
+	if sb := g.Sandbox; sb != nil {
+		sb.resetSteps()
+	}
 	if g.Options&OptDebugger != 0 {
 		// for debugger
 		env.DebugComp = c
 	} else {
 		env.DebugComp = nil
 	}
+	g.ExecFlags.SetBreakOnPanic(g.Options&(OptDebugger|OptBreakOnPanic) == OptDebugger|OptBreakOnPanic)
 	return env
 }
 
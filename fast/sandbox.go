@@ -0,0 +1,264 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * sandbox.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"sync/atomic"
+
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// Sandbox restricts what interpreted code can do.
+// Import and symbol rules are path.Match() patterns, as "os/exec", "net/*" or "os.Exit".
+// Install it with Interp.SetSandbox() before compiling untrusted code:
+// code compiled earlier is not restricted
+type Sandbox struct {
+	AllowImports []string // if not empty, only packages matching one of these can be imported
+	DenyImports  []string // packages matching one of these cannot be imported
+	AllowSymbols []string // if not empty, only package symbols "path.Name" matching one of these can be used
+	DenySymbols  []string // package symbols "path.Name" or methods "path.Type.Method" matching one of these cannot be used
+	// maximum number of statements executed by each top-level evaluation,
+	// including the goroutines it starts. 0 = unlimited
+	MaxSteps uint64
+	// maximum number of goroutines started by interpreted 'go' statements
+	// and running at the same time. 0 = unlimited
+	MaxGoroutines int32
+	steps         uint64
+	goroutines    int32
+	parent        *Sandbox // the Sandbox this one was copied from by Interp.Fork()
+}
+
+// SandboxViolation describes which rule of a Sandbox was violated
+type SandboxViolation uint8
+
+const (
+	SandboxImport     SandboxViolation = iota // import of a denied package
+	SandboxSymbol                             // use of a denied package symbol
+	SandboxSteps                              // too many statements executed
+	SandboxGoroutines                         // too many goroutines running
+)
+
+func (v SandboxViolation) String() string {
+	switch v {
+	case SandboxImport:
+		return "import"
+	case SandboxSymbol:
+		return "symbol"
+	case SandboxSteps:
+		return "steps"
+	case SandboxGoroutines:
+		return "goroutines"
+	default:
+		return fmt.Sprintf("SandboxViolation(%d)", uint8(v))
+	}
+}
+
+// SandboxError is the panic value of code that violates a Sandbox
+// and the error returned by Interp.ImportPackageOrError for denied packages
+type SandboxError struct {
+	Violation SandboxViolation
+	Path      string         // package path, for SandboxImport and SandboxSymbol
+	Name      string         // symbol name, for SandboxSymbol
+	Limit     uint64         // exceeded limit, for SandboxSteps and SandboxGoroutines
+	Pos       token.Position // position of the offending code, if known
+}
+
+func (err *SandboxError) Error() string {
+	var msg string
+	switch err.Violation {
+	case SandboxImport:
+		msg = fmt.Sprintf("sandbox: import of package %q is not allowed", err.Path)
+	case SandboxSymbol:
+		msg = fmt.Sprintf("sandbox: use of %s.%s is not allowed", err.Path, err.Name)
+	case SandboxSteps:
+		msg = fmt.Sprintf("sandbox: exceeded the limit of %d executed statements", err.Limit)
+	case SandboxGoroutines:
+		msg = fmt.Sprintf("sandbox: exceeded the limit of %d running goroutines", err.Limit)
+	default:
+		msg = fmt.Sprintf("sandbox: %v violation", err.Violation)
+	}
+	if err.Pos.IsValid() {
+		msg = err.Pos.String() + ": " + msg
+	}
+	return msg
+}
+
+// SetSandbox installs a Sandbox that restricts the code compiled afterwards. nil removes it
+func (ir *Interp) SetSandbox(sb *Sandbox) {
+	ir.env.Run.Sandbox = sb
+}
+
+func matchAny(patterns []string, str string) bool {
+	for _, pattern := range patterns {
+		if pattern == str {
+			return true
+		}
+		if ok, _ := path.Match(pattern, str); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowImport returns true if the package path can be imported
+func (sb *Sandbox) AllowImport(pkgpath string) bool {
+	if len(sb.AllowImports) != 0 && !matchAny(sb.AllowImports, pkgpath) {
+		return false
+	}
+	return !matchAny(sb.DenyImports, pkgpath)
+}
+
+// AllowSymbol returns true if the symbol name of package path can be used
+func (sb *Sandbox) AllowSymbol(pkgpath string, name string) bool {
+	qualified := pkgpath + "." + name
+	if len(sb.AllowSymbols) != 0 && !matchAny(sb.AllowSymbols, qualified) {
+		return false
+	}
+	return !matchAny(sb.DenySymbols, qualified)
+}
+
+// return a *SandboxError if the sandbox denies the import of pkgpath
+func (c *Comp) checkImport(pkgpath string) error {
+	if sb := c.Sandbox; sb != nil && !sb.AllowImport(pkgpath) {
+		return &SandboxError{Violation: SandboxImport, Path: pkgpath, Pos: c.Position()}
+	}
+	return nil
+}
+
+// panic if the sandbox denies the use of symbol name from imp
+func (c *Comp) checkSymbol(imp *Import, name string) {
+	if sb := c.Sandbox; sb != nil && !sb.AllowSymbol(imp.Path, name) {
+		panic(&SandboxError{Violation: SandboxSymbol, Path: imp.Path, Name: name, Pos: c.Position()})
+	}
+}
+
+// panic if the sandbox denies the use of method mtd of a type declared in another package:
+// methods of denied types are denied too, otherwise values as os.Stdout would give access to them
+func (c *Comp) checkMethod(mtd xr.Method) {
+	sb := c.Sandbox
+	if sb == nil || mtd.GoFun == nil {
+		return
+	}
+	recv := mtd.GoFun.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return
+	}
+	pkgpath, name := named.Obj().Pkg().Path(), named.Obj().Name()
+	if pkgpath == c.FileComp().Path {
+		return
+	}
+	if !sb.AllowSymbol(pkgpath, name) || matchAny(sb.DenySymbols, pkgpath+"."+name+"."+mtd.Name) {
+		panic(&SandboxError{Violation: SandboxSymbol, Path: pkgpath, Name: name + "." + mtd.Name, Pos: c.Position()})
+	}
+}
+
+// return a *SandboxError if the sandbox denies the use of some symbol of imp:
+// a dot-import would make them accessible without a selector
+func (c *Comp) checkDotImport(imp *Import) error {
+	sb := c.Sandbox
+	if sb == nil {
+		return nil
+	}
+	var names []string
+	for name := range imp.Binds {
+		names = append(names, name)
+	}
+	for name := range imp.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !sb.AllowSymbol(imp.Path, name) {
+			return &SandboxError{Violation: SandboxSymbol, Path: imp.Path, Name: name, Pos: c.Position()}
+		}
+	}
+	return nil
+}
+
+// reset the number of executed statements. called before each top-level evaluation
+func (sb *Sandbox) resetSteps() {
+	atomic.StoreUint64(&sb.steps, 0)
+}
+
+// return a statement that counts the executed statements, then executes stmt
+func (sb *Sandbox) instrument(stmt Stmt, pos token.Pos) Stmt {
+	max := sb.MaxSteps
+	return func(env *Env) (Stmt, *Env) {
+		if atomic.AddUint64(&sb.in(env).steps, 1) > max {
+			panic(&SandboxError{Violation: SandboxSteps, Limit: max, Pos: env.Run.Fileset.Position(pos)})
+		}
+		return stmt(env)
+	}
+}
+
+// called before starting a goroutine. panics if too many are running.
+// returns the Sandbox that counted the goroutine
+func (sb *Sandbox) startGoroutine(env *Env, pos token.Pos) *Sandbox {
+	sb = sb.in(env)
+	n := atomic.AddInt32(&sb.goroutines, 1)
+	if max := sb.MaxGoroutines; max > 0 && n > max {
+		atomic.AddInt32(&sb.goroutines, -1)
+		panic(&SandboxError{Violation: SandboxGoroutines, Limit: uint64(max), Pos: env.Run.Fileset.Position(pos)})
+	}
+	return sb
+}
+
+// called when a goroutine started by interpreted code terminates
+func (sb *Sandbox) endGoroutine() {
+	atomic.AddInt32(&sb.goroutines, -1)
+}
+
+// return the copy of sb made by Interp.Fork() for the interpreter executing env,
+// or sb itself if env does not belong to a fork: each fork counts its own statements and goroutines
+func (sb *Sandbox) in(env *Env) *Sandbox {
+	fork := env.Run.Sandbox
+	if fork == sb || fork == nil {
+		return sb
+	}
+	for p := fork.parent; p != nil; p = p.parent {
+		if p == sb {
+			return fork
+		}
+	}
+	return sb
+}
+
+// return a copy of sb, with the same rules and its own counters
+func (sb *Sandbox) fork() *Sandbox {
+	return &Sandbox{
+		AllowImports:  append([]string(nil), sb.AllowImports...),
+		DenyImports:   append([]string(nil), sb.DenyImports...),
+		AllowSymbols:  append([]string(nil), sb.AllowSymbols...),
+		DenySymbols:   append([]string(nil), sb.DenySymbols...),
+		MaxSteps:      sb.MaxSteps,
+		MaxGoroutines: sb.MaxGoroutines,
+		parent:        sb,
+	}
+}
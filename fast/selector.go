@@ -37,6 +37,7 @@ func (c *Comp) SelectorExpr(node *ast.SelectorExpr) *Expr {
 	if t.Kind() == r.Ptr && t.ReflectType() == rtypeOfPtrImport && e.Const() {
 		// access symbol from imported package, for example fmt.Printf
 		imp := e.Value.(*Import)
+		c.checkSymbol(imp, name)
 		return imp.selector(name, &c.Stringer)
 	}
 	if t.Kind() == r.Ptr && t.Elem().Kind() == r.Struct {
@@ -77,6 +78,7 @@ func (c *Comp) selectorType(node *ast.SelectorExpr, t xr.Type) *Expr {
 	} else if count > 1 {
 		c.Errorf("type <%v> has %d wrapper methods %q all at the same depth=%d - expression is ambiguous: %v", t, count, node.Sel, len(mtd.FieldIndex), node)
 	}
+	c.checkMethod(mtd)
 	return c.compileMethodAsFunc(t, mtd)
 }
 
@@ -461,6 +463,7 @@ func (c *Comp) removeFirstParam(t xr.Type) xr.Type {
 // compileMethod compiles expr.method
 // relatively slow, but simple: return a closure with the receiver already bound
 func (c *Comp) compileMethod(node *ast.SelectorExpr, e *Expr, mtd xr.Method) *Expr {
+	c.checkMethod(mtd)
 	obj2method := c.compileObjGetMethod(e.Type, mtd)
 	fun := e.AsX1()
 	tclosure := c.removeFirstParam(mtd.Type)
@@ -952,6 +955,7 @@ func (c *Comp) SelectorPlace(node *ast.SelectorExpr, opt PlaceOption) *Place {
 	if te.ReflectType() == rtypeOfPtrImport && obje.Const() {
 		// access settable and/or addressable variable from imported package, for example os.Stdout
 		imp := obje.Value.(*Import)
+		c.checkSymbol(imp, name)
 		return imp.selectorPlace(c, name, opt)
 	}
 	ispointer := false
@@ -20,11 +20,14 @@ import (
 	"go/ast"
 	"go/token"
 	r "reflect"
+	"runtime"
 	"sort"
+	"strings"
 
 	. "github.com/steele232/zoumacro/base"
 	"github.com/steele232/zoumacro/base/output"
 	"github.com/steele232/zoumacro/gls"
+	xr "github.com/steele232/zoumacro/xreflect"
 )
 
 func stmtNop(env *Env) (Stmt, *Env) {
@@ -92,6 +95,7 @@ func (c *Comp) Stmt(in ast.Stmt) {
 			} else {
 				c.Labels[label] = &ip
 			}
+			c.resolveGotos(label, c.Labels[label])
 			in = node.Stmt
 			continue
 		case *ast.RangeStmt:
@@ -265,16 +269,142 @@ func (c *Comp) Goto(node *ast.BranchStmt) {
 	}
 	label := node.Label.Name
 	upn := 0
-	// do not cross function boundaries
-	for o := c; o != nil && o.Func == nil; o = o.Outer {
+	for o := c; o != nil; o = o.Outer {
 		if ip := o.Labels[label]; ip != nil {
 			// only keep a reference to the jump target, NOT TO THE WHOLE *Comp!
 			c.jumpOut(upn, ip)
 			return
 		}
+		if o.Func != nil {
+			// do not cross function boundaries
+			break
+		}
 		upn += o.UpCost // count how many Env:s we must exit at runtime
 	}
-	c.Errorf("goto label not found: %v", label)
+	info := c.gotoInfo()
+	if _, ok := info.labels[label]; ok {
+		c.Errorf("goto %s jumps into block", label)
+	}
+	// forward goto: remember the enclosing blocks, and compile the jump
+	// once the label is found in one of them. See Comp.resolveGotos()
+	g := &forwardGoto{label: label, pos: node.Pos()}
+	upn = 0
+	for o := c; o != nil; o = o.Outer {
+		g.scopes = append(g.scopes, gotoScope{o, upn, [2]int{o.BindNum, o.IntBindNum}})
+		if o.Func != nil || o.gotos == info {
+			break
+		}
+		upn += o.UpCost
+	}
+	// only keep a reference to the jump, NOT TO THE WHOLE *Comp!
+	var jump Stmt
+	g.jump = &jump
+	c.append(func(env *Env) (Stmt, *Env) {
+		return jump(env)
+	})
+	info.pending = append(info.pending, g)
+}
+
+// a goto whose label is not compiled yet
+type forwardGoto struct {
+	label  string
+	pos    token.Pos
+	scopes []gotoScope // enclosing blocks of the goto, innermost first
+	jump   *Stmt       // set by Comp.resolveGotos()
+}
+
+type gotoScope struct {
+	comp  *Comp
+	upn   int    // how many Env:s to exit at runtime to reach comp
+	nbind [2]int // # of binds and intbinds declared in comp before the goto
+}
+
+// labels and forward gotos of the function being compiled
+type gotoInfo struct {
+	labels  map[string]struct{} // all labels compiled so far
+	pending []*forwardGoto
+}
+
+// gotoInfo returns the labels and forward gotos of the function
+// (or top-level statement) being compiled
+func (c *Comp) gotoInfo() *gotoInfo {
+	o := c
+	for o.Func == nil && o.gotos == nil && o.Outer != nil {
+		o = o.Outer
+	}
+	if o.gotos == nil {
+		o.gotos = &gotoInfo{}
+	}
+	return o.gotos
+}
+
+// resolveGotos compiles the forward gotos waiting for label,
+// which was just compiled in c at address *ip
+func (c *Comp) resolveGotos(label string, ip *int) {
+	info := c.gotoInfo()
+	if info.labels == nil {
+		info.labels = make(map[string]struct{})
+	}
+	info.labels[label] = struct{}{}
+
+	var pending []*forwardGoto
+	for _, g := range info.pending {
+		if g.label != label {
+			pending = append(pending, g)
+			continue
+		}
+		var scope *gotoScope
+		for i := range g.scopes {
+			if g.scopes[i].comp == c {
+				scope = &g.scopes[i]
+				break
+			}
+		}
+		if scope == nil {
+			c.ErrorAt(g.pos, "goto %s jumps into block", label)
+		}
+		if name := c.bindsDeclaredAfter(scope.nbind); len(name) != 0 {
+			c.ErrorAt(g.pos, "goto %s jumps over declaration of %s", label, name)
+		}
+		*g.jump = jumpStmt(scope.upn, ip)
+	}
+	info.pending = pending
+}
+
+// return the name of a variable or function declared in c after the first nbind[0] binds
+// and nbind[1] intbinds, or "" if there are none
+func (c *Comp) bindsDeclaredAfter(nbind [2]int) string {
+	var names []string
+	for name, bind := range c.Binds {
+		switch bind.Desc.Class() {
+		case FuncBind, VarBind:
+			if bind.Desc.Index() >= nbind[0] {
+				names = append(names, name)
+			}
+		case IntBind:
+			if bind.Desc.Index() >= nbind[1] {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// checkGotos fails if some forward goto did not find its label
+func (c *Comp) checkGotos() {
+	info := c.gotos
+	if info == nil {
+		return
+	}
+	c.gotos = nil
+	if len(info.pending) != 0 {
+		g := info.pending[0]
+		c.ErrorAt(g.pos, "goto label not found: %v", g.label)
+	}
 }
 
 // Defer compiles a "defer" statement
@@ -283,6 +413,10 @@ func (c *Comp) Defer(node *ast.DeferStmt) {
 	fun := call.Fun.AsX1()
 	argfuns := call.MakeArgfunsX1()
 	ellipsis := call.Ellipsis
+	// compiled functions can call recover() only if deferred directly,
+	// which is possible only for func() without results
+	tfun := call.Fun.Type
+	direct := len(argfuns) == 0 && tfun.NumIn() == 0 && tfun.NumOut() == 0
 	c.Append(func(env *Env) (Stmt, *Env) {
 		// Go specs: arguments of a defer call are evaluated immediately.
 		// the call itself is executed when the function containing defer returns,
@@ -301,7 +435,14 @@ func (c *Comp) Defer(node *ast.DeferStmt) {
 		}
 		env.IP++
 		run := env.Run
-		if ellipsis {
+		var fgo func()
+		if direct {
+			fgo = compiledFunc0(f)
+		}
+		if fgo != nil {
+			run.InstallDefer = fgo
+			run.DeferGo = true
+		} else if ellipsis {
 			run.InstallDefer = func() {
 				f.CallSlice(args)
 			}
@@ -316,9 +457,35 @@ func (c *Comp) Defer(node *ast.DeferStmt) {
 	c.Code.WithDefers = true
 }
 
+var (
+	rtypeOfFunc0 = r.TypeOf(nop)
+	compPrefix   = r.TypeOf(Comp{}).PkgPath() + ".(*Comp)."
+)
+
+// compiledFunc0 returns f as a func() if it is a compiled function,
+// and nil if it is an interpreted function
+func compiledFunc0(f r.Value) func() {
+	if f.IsNil() || !f.CanInterface() {
+		return nil
+	}
+	if fun := runtime.FuncForPC(f.Pointer()); fun != nil {
+		// interpreted functions are closures created by *Comp methods or by reflect.MakeFunc()
+		name := fun.Name()
+		if strings.HasPrefix(name, compPrefix) || name == "reflect.makeFuncStub" {
+			return nil
+		}
+	}
+	return f.Convert(rtypeOfFunc0).Interface().(func())
+}
+
 // jumpOut compiles a break or continue statement
 // ip is a pointer because the jump target may not be known yet... it will be filled later
 func (c *Comp) jumpOut(upn int, ip *int) {
+	c.append(jumpStmt(upn, ip))
+}
+
+// return a statement that exits upn Env:s and jumps to *ip
+func jumpStmt(upn int, ip *int) Stmt {
 	var stmt Stmt
 	switch upn {
 	case 0:
@@ -352,7 +519,7 @@ func (c *Comp) jumpOut(upn int, ip *int) {
 			return env.Code[ip], env
 		}
 	}
-	c.append(stmt)
+	return stmt
 }
 
 // For compiles a "for" statement
@@ -447,6 +614,8 @@ func (c *Comp) Go(node *ast.GoStmt) {
 		// keep a reference to c2 only if needed
 		debugC = c2
 	}
+	sandbox := c.Sandbox
+	pos := node.Pos()
 
 	stmt := func(env *Env) (Stmt, *Env) {
 		tg := env.Run
@@ -463,13 +632,21 @@ func (c *Comp) Go(node *ast.GoStmt) {
 		for i, argfun := range argfunsX1 {
 			argv[i] = argfun(env2)
 		}
+		var sb *Sandbox
+		if sandbox != nil {
+			sb = sandbox.startGoroutine(env, pos)
+		}
 		// the call is executed in a new goroutine.
 		// make it easy and do not try to optimize this call.
 		go func() {
+			if sb != nil {
+				defer sb.endGoroutine()
+			}
 			tg2 := tg.new(gls.GoID())
 			env2.Run = tg2
 			tg2.glsStore()
 			defer tg2.glsDel()
+			defer tg2.enterFork()()
 
 			funv.Call(argv)
 		}()
@@ -477,7 +654,7 @@ func (c *Comp) Go(node *ast.GoStmt) {
 		env.IP++
 		return env.Code[env.IP], env
 	}
-	c2.Append(stmt, node.Pos())
+	c2.Append(stmt, pos)
 
 	// propagate back the compiled code
 	c.Code = c2.Code
@@ -609,9 +786,16 @@ func (c *Comp) Return(node *ast.ReturnStmt) {
 		return
 	}
 
-	exprs := c.Exprs(resultExprs)
+	types := make([]xr.Type, n)
+	for i := range types {
+		types[i] = resultBinds[i].Type
+	}
+	exprs := c.exprsContext(resultExprs, types)
 	for i := 0; i < n; i++ {
 		c.Pos = resultExprs[i].Pos()
+		if exprs[i].constNeedsConverter(types[i]) {
+			exprs[i].To(c, types[i])
+		}
 		c.SetVar(resultBinds[i].AsVar(upn, PlaceSettable), token.ASSIGN, exprs[i])
 	}
 	c.Append(stmtReturn, node.Pos())
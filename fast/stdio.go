@@ -0,0 +1,198 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * stdio.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	r "reflect"
+)
+
+// Stdio contains the standard streams used by interpreted code,
+// as opposed to Globals.Stdout and Globals.Stderr that receive the interpreter's own messages.
+// nil streams mean os.Stdin, os.Stdout and os.Stderr
+type Stdio struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// os.Stdin, os.Stdout and os.Stderr as seen by interpreted code
+	files [3]*os.File
+}
+
+// SetStdio sets the standard streams used by interpreted code:
+// fmt.Print, fmt.Printf and fmt.Println write to stdout, fmt.Scan, fmt.Scanf and fmt.Scanln
+// read from stdin, and the builtins print and println and the functions log.Print*, log.Fatal*
+// and log.Panic* of the standard logger write to stderr.
+// Streams that are *os.File also replace os.Stdin, os.Stdout and os.Stderr
+// in code compiled afterwards. Other streams cannot, because os.Stdout and friends are *os.File:
+// for them, interpreted code as fmt.Fprintln(os.Stdout, ...) still writes to the process' streams.
+// Pass the write end of an os.Pipe() if such code must be captured too.
+// Other interpreters and compiled code are not affected. nil streams mean the process' ones
+func (ir *Interp) SetStdio(stdin io.Reader, stdout io.Writer, stderr io.Writer) {
+	g := ir.Comp.CompGlobals
+	s := g.Stdio
+	if s == nil {
+		s = &Stdio{}
+		g.Stdio = s
+	}
+	s.Stdin, s.Stdout, s.Stderr = stdin, stdout, stderr
+	for i, stream := range [...]interface{}{stdin, stdout, stderr} {
+		f, _ := stream.(*os.File)
+		if f == nil {
+			f = processStdFile(i)
+		}
+		s.files[i] = f
+	}
+	for _, imp := range g.KnownImports {
+		g.rebindStdio(imp)
+	}
+}
+
+func processStdFile(i int) *os.File {
+	switch i {
+	case 0:
+		return os.Stdin
+	case 1:
+		return os.Stdout
+	default:
+		return os.Stderr
+	}
+}
+
+func (g *IrGlobals) stdin() io.Reader {
+	if s := g.Stdio; s != nil && s.Stdin != nil {
+		return s.Stdin
+	}
+	return os.Stdin
+}
+
+func (g *IrGlobals) stdout() io.Writer {
+	if s := g.Stdio; s != nil && s.Stdout != nil {
+		return s.Stdout
+	}
+	return os.Stdout
+}
+
+func (g *IrGlobals) stderr() io.Writer {
+	if s := g.Stdio; s != nil && s.Stderr != nil {
+		return s.Stderr
+	}
+	return os.Stderr
+}
+
+// write s with the standard logger's prefix and flags to the stderr set by SetStdio(), if any,
+// otherwise to the standard logger's output
+func (g *IrGlobals) logOutput(s string) {
+	if st := g.Stdio; st != nil && st.Stderr != nil {
+		log.New(st.Stderr, log.Prefix(), log.Flags()).Output(3, s)
+	} else {
+		log.Output(3, s)
+	}
+}
+
+// replace the symbols of imported packages "fmt", "log" and "os"
+// that access the standard streams with per-interpreter versions
+func (g *CompGlobals) rebindStdio(imp *Import) {
+	var binds map[string]r.Value
+	switch imp.Path {
+	case "fmt":
+		ig := g.IrGlobals
+		binds = map[string]r.Value{
+			"Print": r.ValueOf(func(a ...interface{}) (int, error) {
+				return fmt.Fprint(ig.stdout(), a...)
+			}),
+			"Printf": r.ValueOf(func(format string, a ...interface{}) (int, error) {
+				return fmt.Fprintf(ig.stdout(), format, a...)
+			}),
+			"Println": r.ValueOf(func(a ...interface{}) (int, error) {
+				return fmt.Fprintln(ig.stdout(), a...)
+			}),
+			"Scan": r.ValueOf(func(a ...interface{}) (int, error) {
+				return fmt.Fscan(ig.stdin(), a...)
+			}),
+			"Scanf": r.ValueOf(func(format string, a ...interface{}) (int, error) {
+				return fmt.Fscanf(ig.stdin(), format, a...)
+			}),
+			"Scanln": r.ValueOf(func(a ...interface{}) (int, error) {
+				return fmt.Fscanln(ig.stdin(), a...)
+			}),
+		}
+	case "log":
+		ig := g.IrGlobals
+		binds = map[string]r.Value{
+			"Print": r.ValueOf(func(a ...interface{}) {
+				ig.logOutput(fmt.Sprint(a...))
+			}),
+			"Printf": r.ValueOf(func(format string, a ...interface{}) {
+				ig.logOutput(fmt.Sprintf(format, a...))
+			}),
+			"Println": r.ValueOf(func(a ...interface{}) {
+				ig.logOutput(fmt.Sprintln(a...))
+			}),
+			"Fatal": r.ValueOf(func(a ...interface{}) {
+				ig.logOutput(fmt.Sprint(a...))
+				os.Exit(1)
+			}),
+			"Fatalf": r.ValueOf(func(format string, a ...interface{}) {
+				ig.logOutput(fmt.Sprintf(format, a...))
+				os.Exit(1)
+			}),
+			"Fatalln": r.ValueOf(func(a ...interface{}) {
+				ig.logOutput(fmt.Sprintln(a...))
+				os.Exit(1)
+			}),
+			"Panic": r.ValueOf(func(a ...interface{}) {
+				s := fmt.Sprint(a...)
+				ig.logOutput(s)
+				panic(s)
+			}),
+			"Panicf": r.ValueOf(func(format string, a ...interface{}) {
+				s := fmt.Sprintf(format, a...)
+				ig.logOutput(s)
+				panic(s)
+			}),
+			"Panicln": r.ValueOf(func(a ...interface{}) {
+				s := fmt.Sprintln(a...)
+				ig.logOutput(s)
+				panic(s)
+			}),
+		}
+	case "os":
+		s := g.Stdio
+		if s == nil {
+			return
+		}
+		binds = map[string]r.Value{
+			"Stdin":  r.ValueOf(&s.files[0]).Elem(),
+			"Stdout": r.ValueOf(&s.files[1]).Elem(),
+			"Stderr": r.ValueOf(&s.files[2]).Elem(),
+		}
+	default:
+		return
+	}
+	for name, val := range binds {
+		bind := imp.Binds[name]
+		if bind == nil {
+			continue
+		}
+		if idx := bind.Desc.Index(); idx != NoIndex && idx < len(imp.Vals) && imp.Vals[idx].IsValid() && imp.Vals[idx].Type() == val.Type() {
+			imp.Vals[idx] = val
+		}
+	}
+}
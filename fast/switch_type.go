@@ -223,10 +223,10 @@ func (c *Comp) typeswitchGotoMap(bind *Bind, seen *typecaseHelper, ip int) {
 	if seen.ConcreteMap.Len() <= 1 {
 		return
 	}
-	m := make(map[r.Type]int)
+	m := make(map[r.Type]typecaseEntry)
 	seen.ConcreteMap.Iterate(func(k types.Type, v interface{}) {
 		entry := v.(typecaseEntry)
-		m[entry.Type.ReflectType()] = entry.IP
+		m[entry.Type.ReflectType()] = entry
 	})
 	if len(m) != seen.ConcreteMap.Len() {
 		// one or more interpreted types are implemented by the same reflect.Type.
@@ -234,14 +234,15 @@ func (c *Comp) typeswitchGotoMap(bind *Bind, seen *typecaseHelper, ip int) {
 		return
 	}
 	idx := bind.Desc.Index()
+	g := c.CompGlobals
 
 	stmt := func(env *Env) (Stmt, *Env) {
 		var rtype r.Type
 		if v := env.Vals[idx]; v.IsValid() {
 			rtype = v.Type() // concrete reflect.Type already extracted by typeswitchTag
 		}
-		if ip, found := m[rtype]; found {
-			env.IP = ip
+		if entry, found := m[rtype]; found && g.hasType(rtype, typeswitchXType(env.Vals[idx+1]), entry.Type) {
+			env.IP = entry.IP
 		} else {
 			env.IP++
 		}
@@ -277,6 +278,7 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 	var iend int
 	var stmt Stmt
 	idx := bind.Desc.Index()
+	g := c.CompGlobals
 	switch len(node.List) {
 	case 0:
 		// compile anyway. reachable?
@@ -288,7 +290,6 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 		}
 	case 1:
 		t := ts[0]
-		rtype := rtypes[0]
 		if t == nil {
 			// case nil:
 			stmt = func(env *Env) (Stmt, *Env) {
@@ -303,51 +304,19 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 				env.IP = ip
 				return env.Code[ip], env
 			}
-		} else if t.Kind() == r.Interface && xr.IsEmulatedInterface(t) {
-			// case emulated_interface:
-			stmt = func(env *Env) (Stmt, *Env) {
-				v := env.Vals[idx]
-				// Debugf("typeswitchCase: comparing %v <%v> against interface type %v", v, Type(v), rtype)
-				ip := iend
-				if v.IsValid() {
-					// rtype may be an interpreted type:
-					// extract the concrete xr.Type and use it
-					xtv := env.Vals[idx+1]
-					if xtv.IsValid() && !xtv.IsNil() {
-						xt := xtv.Interface().(xr.Type)
-						if xt.Implements(t) {
-							ip = env.IP + 1
-							// need the compiler at run-time :(
-							conv := c.converterToEmulatedInterface(xt, t)
-							env.Vals[idx] = conv(v)
-						}
-					}
-				}
-				env.IP = ip
-				return env.Code[ip], env
-			}
 		} else if t.Kind() == r.Interface {
 			// case interface:
+			assert := c.interfaceAsserter(t)
 			stmt = func(env *Env) (Stmt, *Env) {
 				v := env.Vals[idx]
-				// Debugf("typeswitchCase: comparing %v <%v> against interface type %v", v, Type(v), rtype)
+				// Debugf("typeswitchCase: comparing %v <%v> against interface type %v", v, Type(v), t)
 				ip := iend
 				if v.IsValid() {
-					if v.Type().Implements(rtype) {
+					// v may have an interpreted type:
+					// extract the concrete xr.Type and use it
+					if v, ok := assert(v, typeswitchXType(env.Vals[idx+1])); ok {
 						ip = env.IP + 1
-					} else {
-						// rtype may be an interpreted type:
-						// extract the concrete xr.Type and use it
-						xtv := env.Vals[idx+1]
-						if xtv.IsValid() && !xtv.IsNil() {
-							xt := xtv.Interface().(xr.Type)
-							if xt.Implements(t) {
-								ip = env.IP + 1
-								// need the compiler at run-time :(
-								conv := c.converterToProxy(xt, t)
-								env.Vals[idx] = conv(v)
-							}
-						}
+						env.Vals[idx] = v
 					}
 				}
 				env.IP = ip
@@ -358,25 +327,22 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 			stmt = func(env *Env) (Stmt, *Env) {
 				v := env.Vals[idx]
 				ip := iend
-				if v.IsValid() && v.Type() == rtype {
-					// rtype may be an interpreted type:
-					// extract the concrete xr.Type and use it
-					xtv := env.Vals[idx+1]
-					if xtv.IsValid() && !xtv.IsNil() {
-						xt := xtv.Interface().(xr.Type)
-						if xt.IdenticalTo(t) {
-							ip = env.IP + 1
-						}
-					} else {
-						// cannot check exactly...
-						ip = env.IP + 1
-					}
+				// rtype may be an interpreted type:
+				// use the concrete xr.Type extracted by typeswitchTag, if available
+				if v.IsValid() && g.hasType(v.Type(), typeswitchXType(env.Vals[idx+1]), t) {
+					ip = env.IP + 1
 				}
 				env.IP = ip
 				return env.Code[ip], env
 			}
 		}
 	default:
+		asserts := make([]func(r.Value, xr.Type) (r.Value, bool), len(ts))
+		for i, t := range ts {
+			if t != nil && t.Kind() == r.Interface {
+				asserts[i] = c.interfaceAsserter(t)
+			}
+		}
 		stmt = func(env *Env) (Stmt, *Env) {
 			v := env.Vals[idx]
 			var vt r.Type
@@ -385,11 +351,17 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 			}
 			// Debugf("typeswitchCase: comparing %v <%v> against types %v", v, vt, rtypes)
 			ip := iend
-			for _, rtype := range rtypes {
+			for i, rtype := range rtypes {
 				switch {
-				case vt == rtype:
+				case asserts[i] != nil:
+					if !v.IsValid() {
+						continue
+					}
+					if _, ok := asserts[i](v, typeswitchXType(env.Vals[idx+1])); !ok {
+						continue
+					}
 				case rtype != nil:
-					if rtype.Kind() != r.Interface || !vt.Implements(rtype) {
+					if vt != rtype || !g.hasType(vt, typeswitchXType(env.Vals[idx+1]), ts[i]) {
 						continue
 					}
 				default: // rtype == nil
@@ -416,6 +388,14 @@ func (c *Comp) typeswitchCase(node *ast.CaseClause, varname string, bind *Bind,
 	iend = c.Code.Len()
 }
 
+// return the interpreted type saved by typeswitchTag, or nil if not available
+func typeswitchXType(xtv r.Value) xr.Type {
+	if xtv.IsValid() && !xtv.IsNil() {
+		return xtv.Interface().(xr.Type)
+	}
+	return nil
+}
+
 // typeswitchDefault compiles the default case in a type-switch.
 func (c *Comp) typeswitchDefault(node *ast.CaseClause, varname string, bind *Bind) {
 	var iend int
@@ -0,0 +1,255 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * template_constraint.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	r "reflect"
+
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// a term of a type set union, as int or ~string in int | ~string
+type constraintTerm struct {
+	t     xr.Type
+	tilde bool
+}
+
+// the constraint of a type parameter declared with Go generics syntax,
+// as comparable, fmt.Stringer, ~int | ~float64 or interface { ~[]byte; String() string }
+type constraint struct {
+	methods    []xr.Type          // interfaces the type argument must implement
+	unions     [][]constraintTerm // the type argument must match at least one term of each union
+	comparable bool
+}
+
+// return the constraint of each template param in a Go generics type parameter list.
+// returns nil for template params declared with template[] syntax, which have no constraints
+func typeParamConstraints(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+	var list []ast.Expr
+	for _, field := range fields.List {
+		for range field.Names {
+			list = append(list, field.Type)
+		}
+	}
+	return list
+}
+
+// return true if node is a type set element, as ~int or int | float64
+func isTypeSetElem(node ast.Expr) bool {
+	switch node := node.(type) {
+	case *ast.UnaryExpr:
+		return node.Op == token.TILDE
+	case *ast.BinaryExpr:
+		return node.Op == token.OR
+	case *ast.ParenExpr:
+		return isTypeSetElem(node.X)
+	}
+	return false
+}
+
+// compile a constraint
+func (c *Comp) constraint(node ast.Expr) *constraint {
+	cons := &constraint{}
+	c.addConstraint(cons, node)
+	return cons
+}
+
+func (c *Comp) addConstraint(cons *constraint, node ast.Expr) {
+	switch node := node.(type) {
+	case *ast.Ident:
+		if node.Name == "comparable" && c.TryResolveType(node.Name) == nil {
+			cons.comparable = true
+			return
+		}
+	case *ast.ParenExpr:
+		c.addConstraint(cons, node.X)
+		return
+	case *ast.UnaryExpr, *ast.BinaryExpr:
+		cons.unions = append(cons.unions, c.constraintUnion(nil, node))
+		return
+	case *ast.InterfaceType:
+		if node.Methods != nil {
+			for _, field := range node.Methods.List {
+				if len(field.Names) == 0 {
+					c.addConstraint(cons, field.Type)
+				}
+			}
+		}
+		cons.addMethods(c.TypeInterface(node))
+		return
+	}
+	t := c.Type(node)
+	if t.Kind() != r.Interface {
+		cons.unions = append(cons.unions, []constraintTerm{{t, false}})
+	} else if named := c.namedConstraint(t); named != nil {
+		cons.methods = append(cons.methods, named.methods...)
+		cons.unions = append(cons.unions, named.unions...)
+		cons.comparable = cons.comparable || named.comparable
+	} else {
+		cons.addMethods(t)
+	}
+}
+
+func (cons *constraint) addMethods(t xr.Type) {
+	if t.NumMethod() != 0 {
+		cons.methods = append(cons.methods, t)
+	}
+}
+
+// compile the terms of a type set union, as int | ~string
+func (c *Comp) constraintUnion(terms []constraintTerm, node ast.Expr) []constraintTerm {
+	switch node := node.(type) {
+	case *ast.BinaryExpr:
+		if node.Op == token.OR {
+			terms = c.constraintUnion(terms, node.X)
+			return c.constraintUnion(terms, node.Y)
+		}
+	case *ast.UnaryExpr:
+		if node.Op == token.TILDE {
+			return append(terms, constraintTerm{c.Type(node.X), true})
+		}
+	case *ast.ParenExpr:
+		return c.constraintUnion(terms, node.X)
+	}
+	t := c.Type(node)
+	if t.Kind() == r.Interface {
+		// a named constraint used as union term, as Signed | Unsigned
+		named := c.namedConstraint(t)
+		if named == nil || len(named.methods) != 0 || named.comparable || len(named.unions) > 1 {
+			c.Errorf("cannot use interface <%v> in union: %v", t, node)
+		} else if len(named.unions) == 1 {
+			return append(terms, named.unions[0]...)
+		}
+	}
+	return append(terms, constraintTerm{t, false})
+}
+
+// return the constraint declared by named interface t, as Number in
+// type Number interface { ~int | ~float64 }.
+// returns nil if t contains no type set elements
+func (c *Comp) namedConstraint(t xr.Type) *constraint {
+	return c.constraints[t.GoType()]
+}
+
+// remember that named interface t declared by node contains type set elements
+func (c *Comp) declNamedConstraint(t xr.Type, node *ast.InterfaceType) {
+	if node.Methods == nil {
+		return
+	}
+	found := false
+	for _, field := range node.Methods.List {
+		if len(field.Names) == 0 {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+	cons := c.constraint(node)
+	if len(cons.unions) == 0 && !cons.comparable {
+		return
+	}
+	if c.constraints == nil {
+		c.constraints = make(map[types.Type]*constraint)
+	}
+	c.constraints[t.GoType()] = cons
+}
+
+// return the reason why t does not satisfy the constraint, or "" if it does
+func (cons *constraint) check(t xr.Type) string {
+	if cons.comparable && !t.Comparable() {
+		return fmt.Sprintf("<%v> is not comparable", t)
+	}
+	for _, union := range cons.unions {
+		if !unionContains(union, t) {
+			return fmt.Sprintf("<%v> is not in the type set", t)
+		}
+	}
+	for _, iface := range cons.methods {
+		if t.Implements(iface) {
+			continue
+		}
+		for i, n := 0, iface.NumMethod(); i < n; i++ {
+			mtd := iface.Method(i)
+			var pkgpath string
+			if mtd.Pkg != nil {
+				pkgpath = mtd.Pkg.Path()
+			}
+			if _, count := t.MethodByName(mtd.Name, pkgpath); count == 0 {
+				return fmt.Sprintf("<%v> is missing method %s", t, mtd.Name)
+			}
+		}
+		return fmt.Sprintf("<%v> does not implement <%v>", t, iface)
+	}
+	return ""
+}
+
+func unionContains(union []constraintTerm, t xr.Type) bool {
+	for _, term := range union {
+		if term.tilde {
+			if types.Identical(t.GoType().Underlying(), term.t.GoType().Underlying()) {
+				return true
+			}
+		} else if t.IdenticalTo(term.t) {
+			return true
+		}
+	}
+	return false
+}
+
+// check that template arguments satisfy the constraints of template params.
+// constraints[i] == nil means no constraint
+func (maker *templateMaker) checkConstraints(params []string, constraints []ast.Expr) {
+	if len(constraints) == 0 {
+		return
+	}
+	// compile constraints where the template was declared,
+	// with template arguments injected: constraints can refer to them
+	c := NewComp(maker.comp, nil)
+	c.UpCost = 0
+	c.Depth--
+	c.injectTemplateArgs(params, maker.vals, maker.types)
+
+	for i, node := range constraints {
+		if node == nil || i >= len(maker.types) || maker.vals[i] != nil {
+			continue
+		}
+		if reason := c.constraint(node).check(maker.types[i]); len(reason) != 0 {
+			c.ErrorAt(maker.pos, "cannot instantiate %v: type argument <%v> for %s does not satisfy %v: %s",
+				maker, maker.types[i], params[i], node, reason)
+		}
+	}
+}
+
+// declare template arguments in c, as constants or type aliases
+func (c *Comp) injectTemplateArgs(params []string, vals []I, targs []xr.Type) {
+	for i, name := range params {
+		if val := vals[i]; val != nil {
+			c.DeclConst0(name, targs[i], val)
+		} else {
+			c.declTypeAlias(name, targs[i])
+		}
+	}
+}
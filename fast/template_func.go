@@ -35,9 +35,10 @@ type TemplateFuncInstance struct {
 // a template function declaration.
 // either general, or partially specialized or fully specialized
 type TemplateFuncDecl struct {
-	Decl   *ast.FuncLit // template function declaration. use a *ast.FuncLit because we will compile it with Comp.FuncLit()
-	Params []string     // template param names
-	For    []ast.Expr   // partial or full specialization
+	Decl        *ast.FuncLit // template function declaration. use a *ast.FuncLit because we will compile it with Comp.FuncLit()
+	Params      []string     // template param names
+	Constraints []ast.Expr   // template param constraints, only for Go generics syntax
+	For         []ast.Expr   // partial or full specialization
 }
 
 // template function
@@ -102,8 +103,9 @@ func (c *Comp) DeclTemplateFunc(decl *ast.FuncDecl) {
 			Type: decl.Type,
 			Body: decl.Body,
 		},
-		Params: params,
-		For:    fors,
+		Params:      params,
+		Constraints: typeParamConstraints(decl.Type.TypeParams),
+		For:         fors,
 	}
 	name := decl.Name.Name
 
@@ -170,6 +172,7 @@ func (c *Comp) templateFunc(maker *templateMaker, node ast.Node) *Expr {
 		if debug {
 			g.Debugf("instantiating template function %v", maker)
 		}
+		maker.checkConstraints(fun.Master.Params, fun.Master.Constraints)
 		// hard part: instantiate the template function.
 		// must be instantiated in the same *Comp where it was declared!
 		instance = maker.instantiateFunc(fun, node)
@@ -92,30 +92,24 @@ func (c *Comp) inferTemplateFunc(call *ast.CallExpr, fun *Expr, args []*Expr) *E
 	master := tfun.Master
 	typ := master.Decl.Type
 
-	var patterns []ast.Expr
 	ellipsis := call.Ellipsis != token.NoPos
 	variadic := false
 	// collect template function param types expressions
+	patterns := fieldPatterns(typ.Params)
 	if fields := typ.Params; fields != nil {
 		if n := len(fields.List); n != 0 {
 			_, variadic = fields.List[n-1].Type.(*ast.Ellipsis)
-			for _, field := range fields.List {
-				for _ = range field.Names {
-					patterns = append(patterns, field.Type)
-				}
-			}
 		}
 	}
-	if variadic && !ellipsis {
-		c.Errorf("unimplemented type inference on variadic template function: %v", call)
-	} else if !variadic && ellipsis {
+	if !variadic && ellipsis {
 		c.Errorf("invalid use of ... in call to non-variadic template function: %v", call)
 	}
 
 	// collect call arg types
 	nargs := len(args)
 	var targs []inferType
-	if nargs == 1 {
+	if nargs == 1 && args[0].NumOut() != 1 {
+		// a single multi-valued argument
 		arg := args[0]
 		nargs = arg.NumOut()
 		targs = make([]inferType, nargs)
@@ -132,6 +126,14 @@ func (c *Comp) inferTemplateFunc(call *ast.CallExpr, fun *Expr, args []*Expr) *E
 			}
 		}
 	}
+	if variadic && !ellipsis && nargs >= len(patterns)-1 {
+		// match each variadic argument against the element type of the last param
+		last := patterns[len(patterns)-1].(*ast.ArrayType).Elt
+		patterns = patterns[:len(patterns)-1]
+		for len(patterns) < nargs {
+			patterns = append(patterns, last)
+		}
+	}
 	if nargs != len(patterns) {
 		c.Errorf("template function %v has %d params, cannot call with %d values: %v", tfun, len(patterns), nargs, call)
 	}
@@ -163,11 +165,12 @@ func (inf *inferFuncType) args() (vals []I, types []xr.Type) {
 			// skip untyped constant, handled below
 		} else if targ.Value != nil {
 			inf.constant(node, targ.Value, exact)
-		} else {
-			inf.fail(node, targ)
 		}
+		// otherwise the argument is nil: nothing to infer
 	}
 
+	inf.constraints()
+
 	// second pass: untyped constants
 	for i, targ := range inf.targs {
 		if targ.Type == nil && targ.Untyped != untyped.None {
@@ -175,6 +178,8 @@ func (inf *inferFuncType) args() (vals []I, types []xr.Type) {
 		}
 	}
 
+	inf.constraints()
+
 	params := inf.tfun.Master.Params
 	n := len(params)
 	vals = make([]I, n)
@@ -190,6 +195,74 @@ func (inf *inferFuncType) args() (vals []I, types []xr.Type) {
 	return vals, types
 }
 
+// constraint type inference: match the core type of each constraint,
+// as ~map[K]V in func Keys[M ~map[K]V, K comparable, V any](m M) []K,
+// against the type already inferred for its template param.
+// Repeat until no more template params are inferred
+func (inf *inferFuncType) constraints() {
+	master := inf.tfun.Master
+	for {
+		n := inf.ninferred()
+		for i, node := range master.Constraints {
+			if i >= len(master.Params) {
+				break
+			}
+			inferred := inf.inferred[master.Params[i]]
+			if inferred.Type == nil || inferred.Value != nil {
+				continue
+			}
+			// Kind(), Elem(), Key()... of named types are the ones of their underlying type,
+			// so they also match ~[]E. Constraints without ~ are checked after instantiation
+			if pattern := coreTypePattern(node); pattern != nil {
+				inf.arg(pattern, inferred.Type, true)
+			}
+		}
+		if inf.ninferred() == n {
+			break
+		}
+	}
+}
+
+// return the number of template params whose type is inferred
+func (inf *inferFuncType) ninferred() int {
+	n := 0
+	for _, inferred := range inf.inferred {
+		if inferred.Type != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// return the core type of a constraint, as []E in ~[]E or interface{ ~[]E },
+// or nil if the constraint has no core type that can be used for type inference
+func coreTypePattern(node ast.Expr) ast.Expr {
+	tilde := false
+	for {
+		switch n := node.(type) {
+		case *ast.ParenExpr:
+			node = n.X
+			continue
+		case *ast.UnaryExpr:
+			if n.Op != token.TILDE || tilde {
+				return nil
+			}
+			node, tilde = n.X, true
+			continue
+		case *ast.InterfaceType:
+			// interface{ ~[]E } with a single type set element and no methods
+			if tilde || n.Methods == nil || len(n.Methods.List) != 1 || len(n.Methods.List[0].Names) != 0 {
+				return nil
+			}
+			node = n.Methods.List[0].Type
+			continue
+		case *ast.ArrayType, *ast.ChanType, *ast.FuncType, *ast.MapType, *ast.StarExpr:
+			return node
+		}
+		return nil
+	}
+}
+
 // partially infer type of template function for a single parameter
 func (inf *inferFuncType) arg(pattern ast.Expr, targ xr.Type, exact bool) {
 	stars := 0
@@ -283,15 +356,62 @@ func (inf *inferFuncType) chanType(node *ast.ChanType, targ xr.Type, exact bool)
 
 // partially infer type of template function for a constant parameter
 func (inf *inferFuncType) constant(node ast.Expr, val I, exact bool) {
-	// TODO
-	inf.comp.ErrorAt(node.Pos(), "unimplemented type inference: template function with parameter type %v and argument %v: %v",
-		node, val, inf.call)
+	ident, ok := node.(*ast.Ident)
+	if !ok {
+		// a constant expression, as [N+1]T: checked when calling the instantiated function
+		return
+	}
+	inferred, ok := inf.inferred[ident.Name]
+	if !ok {
+		// an existing constant, as [N]T: checked when calling the instantiated function
+		return
+	}
+	if inferred.Value != nil && inferred.Value != val {
+		inf.comp.ErrorAt(node.Pos(), "type inference: in %v, parameter %v cannot match both %v and %v: %v",
+			inf, node, inferred.Value, val, inf.call)
+	}
+	inferred.Value = val
+	inferred.Type = inf.comp.Universe.FromReflectType(r.TypeOf(val))
+	inf.inferred[ident.Name] = inferred
 }
 
 // partially infer type of template function for a func parameter
 func (inf *inferFuncType) funcType(node *ast.FuncType, targ xr.Type, exact bool) (ast.Expr, xr.Type, bool) {
-	// TODO
-	return inf.unimplemented(node, targ)
+	inf.is(node, targ, r.Func)
+	params, results := fieldPatterns(node.Params), fieldPatterns(node.Results)
+	if len(params) != targ.NumIn() || len(results) != targ.NumOut() {
+		inf.fail(node, targ)
+	}
+	for i, param := range params {
+		inf.arg(param, targ.In(i), true)
+	}
+	for i, result := range results {
+		inf.arg(result, targ.Out(i), true)
+	}
+	return nil, nil, false
+}
+
+// return the type of each param or result in fields,
+// converting the variadic ...T to []T
+func fieldPatterns(fields *ast.FieldList) []ast.Expr {
+	var patterns []ast.Expr
+	if fields == nil {
+		return patterns
+	}
+	for _, field := range fields.List {
+		pattern := field.Type
+		if ellipsis, ok := pattern.(*ast.Ellipsis); ok {
+			pattern = &ast.ArrayType{Lbrack: ellipsis.Pos(), Elt: ellipsis.Elt}
+		}
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
 }
 
 // partially infer type of template function for an identifier parameter
@@ -323,7 +443,32 @@ func (inf *inferFuncType) untyped(node ast.Expr, kind untyped.Kind, exact bool)
 	if !ok {
 		inf.fail(node, kind)
 	}
-	inf.unimplemented(ident, kind)
+	inferred, ok := inf.inferred[ident.Name]
+	if !ok {
+		// not a template parameter: the untyped constant will be converted to the parameter type
+		return
+	}
+	if inferred.Type == nil || (inferred.Untyped != untyped.None && untypedRank(kind) > untypedRank(inferred.Untyped)) {
+		// as Go does, use the default type of the "largest" untyped constant
+		// among the arguments that have no typed counterpart: 1 and 2.5 infer float64
+		inferred.Type = inf.comp.Universe.BasicTypes[kind]
+		inferred.Untyped = kind
+		inf.inferred[ident.Name] = inferred
+	}
+}
+
+func untypedRank(kind untyped.Kind) int {
+	switch kind {
+	case untyped.Int:
+		return 1
+	case untyped.Rune:
+		return 2
+	case untyped.Float:
+		return 3
+	case untyped.Complex:
+		return 4
+	}
+	return 0
 }
 
 func (inf *inferFuncType) combine(node ast.Expr, inferred *inferType, with inferType) {
@@ -392,8 +537,36 @@ func (inf *inferFuncType) structType(node *ast.StructType, targ xr.Type, exact b
 
 // partially infer type of template function for a template parameter
 func (inf *inferFuncType) templateType(node *ast.IndexExpr, targ xr.Type, exact bool) (ast.Expr, xr.Type, bool) {
-	// TODO
-	return inf.unimplemented(node, targ)
+	name, args, ok := splitTemplateArgs(node)
+	var typ *TemplateType
+	if ok {
+		if sym, _ := inf.comp.tryResolve(name); sym != nil && sym.Desc.Class() == TemplateTypeBind {
+			typ, _ = sym.Value.(*TemplateType)
+		}
+	}
+	if typ == nil {
+		return inf.unimplemented(node, targ)
+	}
+	// find the template arguments that instantiated targ
+	for key, t := range typ.Instances {
+		if !t.IdenticalTo(targ) {
+			continue
+		}
+		vals, types := inf.comp.templateKeyArgs(key)
+		if len(types) != len(args) {
+			break
+		}
+		for i, arg := range args {
+			if vals[i] != nil {
+				inf.constant(arg, vals[i], true)
+			} else {
+				inf.arg(arg, types[i], true)
+			}
+		}
+		return nil, nil, false
+	}
+	inf.fail(node, targ)
+	return nil, nil, false
 }
 
 func (inf *inferFuncType) is(node ast.Expr, targ xr.Type, kind r.Kind) {
@@ -56,25 +56,11 @@ type templateFuncCandidate struct {
 }
 
 func (special *templateFuncCandidate) injectBinds(c *Comp) {
-	for i, name := range special.decl.Params {
-		t := special.types[i]
-		if val := special.vals[i]; val != nil {
-			c.DeclConst0(name, t, val)
-		} else {
-			c.declTypeAlias(name, t)
-		}
-	}
+	c.injectTemplateArgs(special.decl.Params, special.vals, special.types)
 }
 
 func (special *templateTypeCandidate) injectBinds(c *Comp) {
-	for i, name := range special.decl.Params {
-		t := special.types[i]
-		if val := special.vals[i]; val != nil {
-			c.DeclConst0(name, t, val)
-		} else {
-			c.declTypeAlias(name, t)
-		}
-	}
+	c.injectTemplateArgs(special.decl.Params, special.vals, special.types)
 }
 
 // return the qualified name of the function or type to instantiate, for example "Pair#[int,string]"
@@ -130,6 +116,10 @@ func (c *Comp) templateMaker(node *ast.IndexExpr, which BindClass) *templateMake
 		}
 	}
 	if !ok {
+		if _, hash := node.Index.(*ast.CompositeLit); !hash {
+			// name[index] is an ordinary index expression
+			return nil
+		}
 		c.Errorf("symbol is not a %v, cannot use #[...] on it: %s", which, name)
 	}
 	if n != len(params) {
@@ -216,10 +206,15 @@ func (c *Comp) constToAstExpr(val interface{}, pos token.Pos) ast.Expr {
 	}
 }
 
+// split name#[T1, T2...] or name[T1, T2...] into name and template arguments.
+// The third return value is false if node is not a template instantiation,
+// and true if it may be one: name[T] could also be an index expression
 func splitTemplateArgs(node *ast.IndexExpr) (string, []ast.Expr, bool) {
 	if ident, _ := node.X.(*ast.Ident); ident != nil {
 		cindex, _ := node.Index.(*ast.CompositeLit)
-		if cindex != nil && cindex.Type == nil {
+		if cindex == nil {
+			return ident.Name, []ast.Expr{node.Index}, true
+		} else if cindex.Type == nil {
 			return ident.Name, cindex.Elts, true
 		}
 	}
@@ -0,0 +1,170 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * template_method.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"go/ast"
+	r "reflect"
+
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// a method declared on a template type, as func (l *List[T]) Len() int
+type TemplateMethodDecl struct {
+	Decl   *ast.FuncDecl
+	Params []string // template param names used by the receiver, as T in List[T]
+}
+
+// return the template type of a method receiver as l *List[T], and the template param names it uses.
+// return nil if the receiver is not a template type
+func (c *Comp) templateMethodRecv(recv *ast.Field) (*TemplateType, []string) {
+	node := recv.Type
+	for {
+		switch expr := node.(type) {
+		case *ast.StarExpr:
+			node = expr.X
+			continue
+		case *ast.ParenExpr:
+			node = expr.X
+			continue
+		}
+		break
+	}
+	index, ok := node.(*ast.IndexExpr)
+	if !ok {
+		return nil, nil
+	}
+	name, args, ok := splitTemplateArgs(index)
+	if !ok {
+		return nil, nil
+	}
+	sym, _ := c.tryResolve(name)
+	if sym == nil || sym.Desc.Class() != TemplateTypeBind {
+		return nil, nil
+	}
+	typ, _ := sym.Value.(*TemplateType)
+	if typ == nil {
+		return nil, nil
+	}
+	if n := len(typ.Master.Params); len(args) != n {
+		c.Errorf("%v expects exactly %d template parameters %v, found %d: %v", TemplateTypeBind, n, typ.Master.Params, len(args), index)
+	}
+	params := make([]string, len(args))
+	for i, arg := range args {
+		ident, ok := arg.(*ast.Ident)
+		if !ok {
+			c.Errorf("invalid method receiver: template parameter %d should be an identifier, found %v: %v", i, arg, recv.Type)
+		}
+		params[i] = ident.Name
+	}
+	return typ, params
+}
+
+// DeclTemplateMethod stores a method declaration on a template type,
+// and declares the method on the existing instances of the template type.
+// Instances created later declare it when instantiated
+func (c *Comp) DeclTemplateMethod(decl *ast.FuncDecl, typ *TemplateType, params []string) {
+	mdecl := TemplateMethodDecl{Decl: decl, Params: params}
+	redefined := false
+	for i := range typ.Methods {
+		if typ.Methods[i].Decl.Name.Name == decl.Name.Name {
+			typ.Methods[i] = mdecl
+			redefined = true
+		}
+	}
+	if !redefined {
+		typ.Methods = append(typ.Methods, mdecl)
+	}
+	for key, t := range typ.Instances {
+		if t.Named() { // aliases cannot have methods
+			vals, types := c.templateKeyArgs(key)
+			c.instantiateMethods(typ, []TemplateMethodDecl{mdecl}, vals, types)
+		}
+	}
+}
+
+// return the template arguments of an instance, given its key in TemplateType.Instances
+func (c *Comp) templateKeyArgs(key I) ([]I, []xr.Type) {
+	vkey := r.ValueOf(key)
+	n := vkey.Len()
+	vals := make([]I, n)
+	types := make([]xr.Type, n)
+	for i := 0; i < n; i++ {
+		switch k := vkey.Index(i).Interface().(type) {
+		case xr.Key:
+			types[i] = k.Type()
+		default:
+			vals[i] = k
+			types[i] = c.Universe.FromReflectType(r.TypeOf(k))
+		}
+	}
+	return vals, types
+}
+
+// instantiateMethods declares and compiles the methods decls on the instance of typ
+// with template arguments vals and types.
+// Must be invoked on the same *Comp where the template type was declared
+func (c *Comp) instantiateMethods(typ *TemplateType, decls []TemplateMethodDecl, vals []I, types []xr.Type) {
+	type method struct {
+		comp                    *Comp
+		decl                    *ast.FuncDecl
+		t                       xr.Type
+		paramnames, resultnames []string
+		index                   int
+		methods                 *[]r.Value
+	}
+	list := make([]method, len(decls))
+
+	// first declare all the methods, then compile their bodies: allows methods to call each other
+	for i, mdecl := range decls {
+		// create a new nested Comp and inject template arguments into it
+		cm := NewComp(c, nil)
+		cm.UpCost = 0
+		cm.Depth--
+		cm.injectTemplateArgs(mdecl.Params, vals, types)
+		decl := mdecl.Decl
+		t, paramnames, resultnames := cm.TypeFunctionOrMethod(decl.Recv.List[0], decl.Type)
+		index, methods := cm.methodAdd(decl, t)
+		list[i] = method{cm, decl, t, paramnames, resultnames, index, methods}
+	}
+	for _, m := range list {
+		f := m.comp.methodBody(m.decl, m.t, m.paramnames, m.resultnames)
+		index, methods := m.index, m.methods
+		typ.setMethod(func(env *Env) {
+			(*methods)[index] = f(env)
+		})
+	}
+}
+
+// setMethod creates a method of an instance in the runtime environment of the template type declaration,
+// or as soon as the declaration is executed
+func (typ *TemplateType) setMethod(set func(*Env)) {
+	if typ.env != nil {
+		set(typ.env)
+	} else {
+		typ.pending = append(typ.pending, set)
+	}
+}
+
+// setEnv is executed by the template type declaration
+func (typ *TemplateType) setEnv(env *Env) {
+	typ.env = env
+	for _, set := range typ.pending {
+		set(env)
+	}
+	typ.pending = nil
+}
@@ -30,16 +30,20 @@ import (
 // a template type declaration.
 // either general, or partially specialized or fully specialized
 type TemplateTypeDecl struct {
-	Decl   ast.Expr   // type declaration body. use an ast.Expr because we will compile it with Comp.Type()
-	Alias  bool       // true if declaration is an alias: 'type Foo = ...'
-	Params []string   // template param names
-	For    []ast.Expr // for partial or full specialization
+	Decl        ast.Expr   // type declaration body. use an ast.Expr because we will compile it with Comp.Type()
+	Alias       bool       // true if declaration is an alias: 'type Foo = ...'
+	Params      []string   // template param names
+	Constraints []ast.Expr // template param constraints, only for Go generics syntax
+	For         []ast.Expr // for partial or full specialization
 }
 
 type TemplateType struct {
 	Master    TemplateTypeDecl            // master (i.e. non specialized) declaration
 	Special   map[string]TemplateTypeDecl // partially or fully specialized declarations. key is TemplateTypeDecl.For converted to string
 	Instances map[I]xr.Type               // cache of instantiated types. key is [N]interface{}{T1, T2...}
+	Methods   []TemplateMethodDecl        // methods declared on the template type, as func (l *List[T]) Len() int
+	env       *Env                        // runtime environment of the template type declaration. used to create the methods of instances
+	pending   []func(*Env)                // methods of instances created before env was known
 }
 
 func (t *TemplateType) String() string {
@@ -101,10 +105,11 @@ func (c *Comp) DeclTemplateType(spec *ast.TypeSpec) {
 	params, fors := c.templateParams(lit.Elts, "type", spec)
 
 	tdecl := TemplateTypeDecl{
-		Decl:   lit.Type,
-		Alias:  spec.Assign != token.NoPos,
-		Params: params,
-		For:    fors,
+		Decl:        lit.Type,
+		Alias:       spec.Assign != token.NoPos,
+		Params:      params,
+		Constraints: typeParamConstraints(spec.TypeParams),
+		For:         fors,
 	}
 	name := spec.Name.Name
 
@@ -115,14 +120,20 @@ func (c *Comp) DeclTemplateType(spec *ast.TypeSpec) {
 		}
 
 		bind := c.NewBind(name, TemplateTypeBind, c.TypeOfPtrTemplateType())
-		// a template type declaration has no runtime effect:
-		// it merely creates the bind for on-demand instantiation by other code
-
-		bind.Value = &TemplateType{
+		// a template type declaration creates the bind for on-demand instantiation by other code.
+		// Its only runtime effect is to remember the runtime environment,
+		// needed to create the methods of instances
+		typ := &TemplateType{
 			Master:    tdecl,
 			Special:   make(map[string]TemplateTypeDecl),
 			Instances: make(map[I]xr.Type),
 		}
+		bind.Value = typ
+		c.Append(func(env *Env) (Stmt, *Env) {
+			typ.setEnv(env)
+			env.IP++
+			return env.Code[env.IP], env
+		}, spec.Pos())
 		return
 	}
 
@@ -166,9 +177,13 @@ func (c *Comp) TemplateType(node *ast.IndexExpr) xr.Type {
 		if debug {
 			g.Debugf("instantiating template type %v", maker)
 		}
+		maker.checkConstraints(typ.Master.Params, typ.Master.Constraints)
 		// hard part: instantiate the template type.
 		// must be instantiated in the same *Comp where it was declared!
 		instance = maker.instantiateType(typ, node)
+		if len(typ.Methods) != 0 && instance.Named() {
+			maker.comp.instantiateMethods(typ, typ.Methods, maker.vals, maker.types)
+		}
 	}
 	return instance
 }
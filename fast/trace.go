@@ -0,0 +1,236 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * trace.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"bytes"
+	"io"
+	"path"
+	r "reflect"
+	"sync"
+	"time"
+
+	. "github.com/steele232/zoumacro/base"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// Tracer logs the entry and exit of interpreted functions
+// whose name matches one of its patterns.
+// A Tracer is never modified after creation: Interp.Trace and Interp.Untrace replace it.
+type Tracer struct {
+	Patterns []string // function names, optionally qualified by package or receiver type, or path.Match() patterns
+	Out      io.Writer
+	lock     *sync.Mutex // shared by all Tracers writing to the same Interp, to avoid interleaving lines
+}
+
+// Trace starts logging calls to interpreted functions whose name matches one of the patterns.
+// Patterns are function names as fib, main.fib or T.String, or path.Match() patterns as "T.*".
+// Only functions compiled with OptDebugger are traced.
+func (ir *Interp) Trace(patterns ...string) {
+	g := ir.env.Run.IrGlobals
+	old := g.Tracer
+	t := &Tracer{Out: g.Stderr}
+	if old != nil {
+		t.Patterns = append(t.Patterns, old.Patterns...)
+		t.Out = old.Out
+		t.lock = old.lock
+	} else {
+		t.lock = &sync.Mutex{}
+	}
+	for _, pattern := range patterns {
+		if !t.has(pattern) {
+			t.Patterns = append(t.Patterns, pattern)
+		}
+	}
+	if len(t.Patterns) == 0 {
+		t = nil
+	}
+	g.Tracer = t
+}
+
+// Untrace stops logging calls to functions matching the patterns previously passed to Trace.
+// Without arguments, it stops tracing all functions
+func (ir *Interp) Untrace(patterns ...string) {
+	g := ir.env.Run.IrGlobals
+	old := g.Tracer
+	if old == nil {
+		return
+	} else if len(patterns) == 0 {
+		g.Tracer = nil
+		return
+	}
+	t := &Tracer{Out: old.Out, lock: old.lock}
+	for _, pattern := range old.Patterns {
+		remove := false
+		for _, p := range patterns {
+			if p == pattern {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			t.Patterns = append(t.Patterns, pattern)
+		}
+	}
+	if len(t.Patterns) == 0 {
+		t = nil
+	}
+	g.Tracer = t
+}
+
+func (t *Tracer) has(pattern string) bool {
+	for _, p := range t.Patterns {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if name or qualified match one of the patterns
+func (t *Tracer) Match(name string, qualified string) bool {
+	for _, p := range t.Patterns {
+		if p == name || p == qualified {
+			return true
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, qualified); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// return the name of the function created by m, and its name qualified by package or receiver type
+func (c *Comp) traceNames(t xr.Type, m *funcMaker) (string, string) {
+	name := m.Name
+	if t.IsMethod() {
+		trecv := t.In(0)
+		if trecv.Kind() == r.Ptr && len(trecv.Name()) == 0 {
+			name = "(*" + trecv.Elem().Name() + ")." + name
+		} else {
+			name = trecv.Name() + "." + name
+		}
+	}
+	pkg := c.FileComp().Name
+	if len(pkg) == 0 {
+		pkg = "main"
+	}
+	return name, pkg + "." + name
+}
+
+// wrap m.funcbody to log its entry and exit when a Tracer matches the function name.
+// closures have no name and are never traced
+func (c *Comp) traceFuncBody(t xr.Type, m *funcMaker) {
+	if len(m.Name) == 0 || c.Globals.Options&OptDebugger == 0 {
+		return
+	}
+	name, qualified := c.traceNames(t, m)
+	funcbody := m.funcbody
+	param, result := m.Param, m.Result
+
+	m.funcbody = func(env *Env) {
+		tracer := env.Run.Tracer
+		if tracer == nil || !tracer.Match(name, qualified) {
+			if funcbody != nil {
+				funcbody(env)
+			}
+			return
+		}
+		tracer.enter(env, name, param)
+		returned := false
+		defer func() {
+			tracer.exit(env, name, result, returned)
+		}()
+		if funcbody != nil {
+			funcbody(env)
+		}
+		returned = true
+	}
+}
+
+func (t *Tracer) enter(env *Env, name string, param []*Bind) {
+	var buf bytes.Buffer
+	t.header(&buf, env, "-> ")
+	buf.WriteString(name)
+	buf.WriteByte('(')
+	t.binds(&buf, env, param)
+	buf.WriteString(")\n")
+	t.write(buf.Bytes())
+}
+
+func (t *Tracer) exit(env *Env, name string, result []*Bind, returned bool) {
+	var buf bytes.Buffer
+	t.header(&buf, env, "<- ")
+	buf.WriteString(name)
+	if !returned {
+		buf.WriteString(" panicking\n")
+	} else if len(result) == 0 {
+		buf.WriteByte('\n')
+	} else {
+		buf.WriteString(" = ")
+		if len(result) > 1 {
+			buf.WriteByte('(')
+		}
+		t.binds(&buf, env, result)
+		if len(result) > 1 {
+			buf.WriteByte(')')
+		}
+		buf.WriteByte('\n')
+	}
+	t.write(buf.Bytes())
+}
+
+// write timestamp, and indentation proportional to the call depth
+func (t *Tracer) header(buf *bytes.Buffer, env *Env, arrow string) {
+	buf.WriteString(time.Now().Format("15:04:05.000000 "))
+	for i := 1; i < env.CallDepth; i++ {
+		buf.WriteString("  ")
+	}
+	buf.WriteString(arrow)
+}
+
+func (t *Tracer) binds(buf *bytes.Buffer, env *Env, binds []*Bind) {
+	st := &env.Run.Stringer
+	for i, bind := range binds {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		if name := bind.Name; len(name) != 0 && name != "_" {
+			buf.WriteString(name)
+			buf.WriteByte('=')
+		}
+		if bind.Desc.Index() == NoIndex {
+			buf.WriteString("_")
+			continue
+		}
+		value := bind.RuntimeValue(env)
+		if !value.IsValid() || value == None {
+			buf.WriteString("nil")
+		} else {
+			st.Fprintf(buf, "%v", value)
+		}
+	}
+}
+
+func (t *Tracer) write(line []byte) {
+	t.lock.Lock()
+	t.Out.Write(line)
+	t.lock.Unlock()
+}
@@ -64,6 +64,9 @@ func (c *Comp) DeclType(spec ast.Spec) {
 	u := c.Type(node.Type)
 	if t != nil { // t == nil means name == "_", discard the result of type declaration
 		c.SetUnderlyingType(t, u)
+		if iface, ok := node.Type.(*ast.InterfaceType); ok {
+			c.declNamedConstraint(t, iface)
+		}
 	}
 	panicking = false
 }
@@ -131,6 +134,14 @@ func (c *Comp) DeclNamedType(name string) xr.Type {
 
 func (c *Comp) SetUnderlyingType(t, underlying xr.Type) {
 	t.SetUnderlying(underlying)
+	c.addNamedType(t)
+	// interpreted named structs are implemented by unnamed reflect.Type:
+	// remember their name, to show it when printing values
+	if rtype := t.ReflectType(); rtype.Kind() == r.Struct && len(rtype.Name()) == 0 {
+		if _, ok := c.Globals.NamedTypes[rtype]; !ok {
+			c.Globals.NamedTypes[rtype] = t.String()
+		}
+	}
 }
 
 // DeclType0 declares a type
@@ -217,6 +228,9 @@ func (c *Comp) compileType2(node ast.Expr, allowEllipsis bool) (t xr.Type, ellip
 		t = c.ResolveType(node.Name)
 	case *ast.IndexExpr:
 		t = c.TemplateType(node)
+		if t == nil {
+			c.Errorf("not a type: %v <%v>", node, r.TypeOf(node))
+		}
 	case *ast.InterfaceType:
 		t = c.TypeInterface(node)
 	case *ast.MapType:
@@ -247,6 +261,7 @@ func (c *Comp) compileType2(node ast.Expr, allowEllipsis bool) (t xr.Type, ellip
 			c.Errorf("not a package: %q in %v <%v>", name, node, r.TypeOf(node))
 		}
 		name = node.Sel.Name
+		c.checkSymbol(imp, name)
 		t, ok = imp.Types[name]
 		if !ok || t == nil {
 			c.Errorf("not a type: %v <%v>", node, r.TypeOf(node))
@@ -457,6 +472,7 @@ func (c *Comp) TypeAssert2(node *ast.TypeAssertExpr) *Expr {
 	}
 	// extractor to unwrap value from proxy or emulated interface
 	extractor := c.extractor(tin)
+	g := c.CompGlobals
 
 	fun := val.Fun.(func(*Env) r.Value) // val returns an interface... must be already wrapped in a reflect.Value
 
@@ -467,7 +483,7 @@ func (c *Comp) TypeAssert2(node *ast.TypeAssertExpr) *Expr {
 	case reflect.IsOptimizedKind(kout):
 		ret = func(env *Env) (r.Value, []r.Value) {
 			v, t := extractor(fun(env))
-			if reflect.Type(v) != rtout || (t != nil && !t.AssignableTo(tout)) {
+			if !g.hasType(reflect.Type(v), t, tout) {
 				return fail[0], fail
 			}
 			return v, []r.Value{v, True}
@@ -487,36 +503,14 @@ func (c *Comp) TypeAssert2(node *ast.TypeAssertExpr) *Expr {
 			}
 			break
 		}
-		if tin.Implements(tout) {
-			// type assertion to interface.
-			// expression type implements such interface, can only fail if value is nil
-			ret = func(env *Env) (r.Value, []r.Value) {
-				v, _ := extractor(fun(env))
-				// nil is not a valid tout, check for it.
-				// IsNil() can be invoked only on nillable types...
-				if reflect.IsNillableKind(v.Kind()) && (v == Nil || v.IsNil()) {
-					return fail[0], fail
-				}
-				v = convert(v, rtout)
-				return v, []r.Value{v, True}
-			}
-			break
-		}
-		// type assertion to interface
+		// type assertion to interface.
 		// must check at runtime whether concrete type implements asserted interface
+		assert := c.interfaceAsserter(tout)
 		ret = func(env *Env) (r.Value, []r.Value) {
-			v, t := extractor(fun(env))
-			// nil is not a valid tout, check for it.
-			// IsNil() can be invoked only on nillable types...
-			if reflect.IsNillableKind(v.Kind()) && (v == Nil || v.IsNil()) {
-				return fail[0], fail
-			}
-			rt := rtypeof(v, t)
-			if (rt != rtout && !rt.Implements(rtout)) ||
-				(t != nil && !t.IdenticalTo(tout) && !t.Implements(tout)) {
+			v, ok := assert(extractor(fun(env)))
+			if !ok {
 				return fail[0], fail
 			}
-			v = convert(v, rtout)
 			return v, []r.Value{v, True}
 		}
 
@@ -529,8 +523,7 @@ func (c *Comp) TypeAssert2(node *ast.TypeAssertExpr) *Expr {
 			if reflect.IsNillableKind(v.Kind()) && (v == Nil || v.IsNil()) {
 				return fail[0], fail
 			}
-			rt := rtypeof(v, t)
-			if rt != rtout || (t != nil && !t.IdenticalTo(tout)) {
+			if !g.hasType(rtypeof(v, t), t, tout) {
 				return fail[0], fail
 			}
 			return v, []r.Value{v, True}
@@ -539,8 +532,7 @@ func (c *Comp) TypeAssert2(node *ast.TypeAssertExpr) *Expr {
 		// type assertion to concrete (non-nillable) type
 		ret = func(env *Env) (r.Value, []r.Value) {
 			v, t := extractor(fun(env))
-			rt := rtypeof(v, t)
-			if rt != rtout || (t != nil && !t.IdenticalTo(tout)) {
+			if !g.hasType(rtypeof(v, t), t, tout) {
 				return fail[0], fail
 			}
 			return v, []r.Value{v, True}
@@ -569,6 +561,7 @@ func (c *Comp) TypeAssert1(node *ast.TypeAssertExpr) *Expr {
 	}
 	// extractor to unwrap value from proxy or emulated interface
 	extractor := c.extractor(tin)
+	g := c.CompGlobals
 
 	fun := val.Fun.(func(*Env) r.Value) // val returns an interface... must be already wrapped in a reflect.Value
 
@@ -578,103 +571,103 @@ func (c *Comp) TypeAssert1(node *ast.TypeAssertExpr) *Expr {
 	case r.Bool:
 		ret = func(env *Env) bool {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return v.Bool()
 		}
 	case r.Int:
 		ret = func(env *Env) int {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return int(v.Int())
 		}
 	case r.Int8:
 		ret = func(env *Env) int8 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return int8(v.Int())
 		}
 	case r.Int16:
 		ret = func(env *Env) int16 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return int16(v.Int())
 		}
 	case r.Int32:
 		ret = func(env *Env) int32 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return int32(v.Int())
 		}
 	case r.Int64:
 		ret = func(env *Env) int64 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return v.Int()
 		}
 	case r.Uint:
 		ret = func(env *Env) uint {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return uint(v.Uint())
 		}
 	case r.Uint8:
 		ret = func(env *Env) uint8 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return uint8(v.Uint())
 		}
 	case r.Uint16:
 		ret = func(env *Env) uint16 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return uint16(v.Uint())
 		}
 	case r.Uint32:
 		ret = func(env *Env) uint32 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return uint32(v.Uint())
 		}
 	case r.Uint64:
 		ret = func(env *Env) uint64 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return v.Uint()
 		}
 	case r.Uintptr:
 		ret = func(env *Env) uintptr {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return uintptr(v.Uint())
 		}
 	case r.Float32:
 		ret = func(env *Env) float32 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return float32(v.Float())
 		}
 	case r.Float64:
 		ret = func(env *Env) float64 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return v.Float()
 		}
 	case r.Complex64:
 		ret = func(env *Env) complex64 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return complex64(v.Complex())
 		}
 	case r.Complex128:
 		ret = func(env *Env) complex128 {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return v.Complex()
 		}
 	case r.String:
 		ret = func(env *Env) string {
 			v, t := extractor(fun(env))
-			v = typeassert(v, t, tin, tout)
+			v = g.typeassert(v, t, tin, tout)
 			return v.String()
 		}
 	case r.Interface:
@@ -688,34 +681,20 @@ func (c *Comp) TypeAssert1(node *ast.TypeAssertExpr) *Expr {
 				}
 				return convert(v, rtout)
 			}
-		} else if tin.Implements(tout) {
-			// type assertion to interface.
-			// expression type implements such interface, can only fail if value is nil
-			ret = func(env *Env) r.Value {
-				v, _ := extractor(fun(env))
-				// nil is not a valid tout, check for it.
-				// IsNil() can be invoked only on nillable types...
-				if reflect.IsNillableKind(v.Kind()) && (v == Nil || v.IsNil()) {
-					typeassertpanic(nil, nil, tin, tout)
-				}
-				return convert(v, rtout)
-			}
 		} else {
 			// type assertion to interface.
 			// must check at runtime whether concrete type implements asserted interface
+			assert := c.interfaceAsserter(tout)
 			ret = func(env *Env) r.Value {
 				v, t := extractor(fun(env))
-				// nil is not a valid tout, check for it.
-				// IsNil() can be invoked only on nillable types...
-				if reflect.IsNillableKind(v.Kind()) && (v == Nil || v.IsNil()) {
-					typeassertpanic(nil, nil, tin, tout)
-				}
-				rt := rtypeof(v, t)
-				if (rt != rtout && !rt.AssignableTo(rtout) && !rt.Implements(rtout)) ||
-					(t != nil && !t.AssignableTo(tout) && !t.Implements(tout)) {
-					typeassertpanic(rt, t, tin, tout)
+				vout, ok := assert(v, t)
+				if !ok {
+					if !v.IsValid() || v == None {
+						typeassertpanic(nil, nil, tin, tout)
+					}
+					typeassertpanic(rtypeof(v, t), t, tin, tout)
 				}
-				return convert(v, rtout)
+				return vout
 			}
 		}
 	default:
@@ -729,7 +708,7 @@ func (c *Comp) TypeAssert1(node *ast.TypeAssertExpr) *Expr {
 					typeassertpanic(nil, nil, tin, tout)
 				}
 				rt := rtypeof(v, t)
-				if rt != rtout || (t != nil && !t.IdenticalTo(tout)) {
+				if !g.hasType(rt, t, tout) {
 					panic(&TypeAssertionError{
 						Interface:       tin,
 						Concrete:        t,
@@ -744,7 +723,7 @@ func (c *Comp) TypeAssert1(node *ast.TypeAssertExpr) *Expr {
 			ret = func(env *Env) r.Value {
 				v, t := extractor(fun(env))
 				rt := rtypeof(v, t)
-				if rt != rtout || (t != nil && !t.IdenticalTo(tout)) {
+				if !g.hasType(rt, t, tout) {
 					panic(&TypeAssertionError{
 						Interface:       tin,
 						Concrete:        t,
@@ -761,9 +740,9 @@ func (c *Comp) TypeAssert1(node *ast.TypeAssertExpr) *Expr {
 	return e
 }
 
-func typeassert(v r.Value, t xr.Type, tin xr.Type, tout xr.Type) r.Value {
+func (g *CompGlobals) typeassert(v r.Value, t xr.Type, tin xr.Type, tout xr.Type) r.Value {
 	rt := rtypeof(v, t)
-	if rt != tout.ReflectType() || t != nil && !t.IdenticalTo(tout) {
+	if !g.hasType(rt, t, tout) {
 		panic(&TypeAssertionError{
 			Interface:       tin,
 			Concrete:        t,
@@ -774,6 +753,25 @@ func typeassert(v r.Value, t xr.Type, tin xr.Type, tout xr.Type) r.Value {
 	return v
 }
 
+// hasType returns true if a value with reflect.Type rt and interpreted type t has type tout.
+// t is nil if unknown, as for interpreted values stored in compiled interfaces:
+// in such case, panics with *AmbiguousTypeError if tout is one of several interpreted types implemented by rt
+func (g *CompGlobals) hasType(rt r.Type, t xr.Type, tout xr.Type) bool {
+	if rt != tout.ReflectType() {
+		return false
+	} else if t != nil {
+		return t.IdenticalTo(tout)
+	}
+	if list := g.interpretedTypes(rt); len(list) > 1 {
+		for _, other := range list {
+			if other.IdenticalTo(tout) {
+				panic(&AmbiguousTypeError{ReflectType: rt, Types: list})
+			}
+		}
+	}
+	return true
+}
+
 func typeassertpanic(rt r.Type, t xr.Type, tin xr.Type, tout xr.Type) {
 	var missingmethod *xr.Method
 	if t != nil && tout.Kind() == r.Interface {
@@ -788,6 +786,21 @@ func typeassertpanic(rt r.Type, t xr.Type, tin xr.Type, tout xr.Type) {
 	})
 }
 
+// An AmbiguousTypeError reports that the interpreted type of a value
+// extracted from a compiled interface cannot be determined:
+// several interpreted types are implemented by the same reflect.Type
+type AmbiguousTypeError struct {
+	ReflectType r.Type
+	Types       []xr.Type
+}
+
+func (*AmbiguousTypeError) RuntimeError() {}
+
+func (e *AmbiguousTypeError) Error() string {
+	return fmt.Sprintf("cannot determine the type of value extracted from interface: <%v> may be any of the interpreted types %v",
+		e.ReflectType, e.Types)
+}
+
 func (g *CompGlobals) TypeOfBool() xr.Type {
 	return g.Universe.BasicTypes[r.Bool]
 }
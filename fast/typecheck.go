@@ -0,0 +1,552 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * typecheck.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	r "reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/steele232/zoumacro/ast2"
+	"github.com/steele232/zoumacro/base/reflect"
+	"github.com/steele232/zoumacro/base/untyped"
+)
+
+// strict mode: if Options contain OptTypeCheck, each input is type-checked with go/types
+// before compiling it. Errors are reported with the same diagnostics as Go compilers,
+// and programs rejected by Go compilers are rejected too, even if Comp would accept them.
+//
+// Packages are imported from the same bindings used to compile them, i.e. imports.Packages,
+// and the declarations already executed by the interpreter are visible to the checked code.
+// Gomacro extensions, as macros, quasiquote and template[] syntax, are not valid Go
+// and are rejected in strict mode.
+
+// maximum number of diagnostics reported at once, as Go compilers do
+const typeCheckMaxErrors = 10
+
+// TypeCheckError is wrapped in the *CompileError returned by Interp.EvalErr
+// when strict mode is active and go/types rejects the source code
+type TypeCheckError struct {
+	Pos    token.Position // position of the first error
+	Errors []string       // diagnostics, each prefixed by its position
+}
+
+func (err *TypeCheckError) Error() string {
+	return strings.Join(err.Errors, "\n")
+}
+
+// typeCheck type-checks form with go/types. If files is true, form contains whole files:
+// all diagnostics are reported, including unused imports.
+// Otherwise form is REPL input: imports and top-level variables may be used by later inputs,
+// and statements are type-checked as if they were inside a function
+func (c *Comp) typeCheck(form ast2.Ast, files bool) {
+	b := batch{seen: make(map[string]bool)}
+	b.addAst(form)
+
+	var undo []func()
+	defer func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}()
+	file := &ast.File{Name: ast.NewIdent("_")}
+	if fc := c.FileComp(); len(fc.Name) != 0 {
+		file.Name.Name = fc.Name
+	}
+	if len(b.imports) != 0 {
+		file.Decls = append(file.Decls, &ast.GenDecl{Tok: token.IMPORT, Specs: b.imports})
+	}
+	for _, node := range b.decls {
+		if decl, ok := node.(ast.Decl); ok {
+			file.Decls = append(file.Decls, decl)
+		}
+	}
+	pkg := types.NewPackage(c.FileComp().Path, file.Name.Name)
+	// generics declared by previous inputs are type-checked again together with their users
+	file.Decls = append(file.Decls, c.typeCheckDeclarePrevious(pkg, typeCheckDeclaredNames(file))...)
+
+	for _, decl := range file.Decls {
+		typeCheckUnlower(r.ValueOf(decl), &undo)
+	}
+	var errs []types.Error
+	typenames := typeCheckDeclaredTypes(file.Decls)
+	decls := file.Decls[:0]
+	for _, decl := range file.Decls {
+		if fun, ok := decl.(*ast.FuncDecl); ok {
+			if recv := fun.Recv; recv != nil && (len(recv.List) == 0 || recv.List[0] == nil) {
+				// see Comp.DeclFunc
+				kind := "macro"
+				if len(recv.List) != 0 {
+					kind = "template"
+				}
+				errs = append(errs, types.Error{Pos: fun.Pos(), Msg: kind + " declaration is not valid Go"})
+				continue
+			} else if fun = typeCheckMethod(fun, typenames); fun == nil {
+				continue
+			}
+			decl = fun
+		} else if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+			// see Comp.DeclTemplateType
+			specs := make([]ast.Spec, 0, len(gen.Specs))
+			for _, spec := range gen.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok {
+					if _, ok := spec.Type.(*ast.CompositeLit); ok {
+						errs = append(errs, types.Error{Pos: spec.Pos(), Msg: "template declaration is not valid Go"})
+						continue
+					}
+				}
+				specs = append(specs, spec)
+			}
+			if len(specs) == 0 {
+				continue
+			} else if len(specs) != len(gen.Specs) {
+				decl = &ast.GenDecl{TokPos: gen.TokPos, Tok: gen.Tok, Lparen: gen.Lparen, Specs: specs, Rparen: gen.Rparen}
+			}
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+
+	// REPL variables declared by top-level statements: they can be used by later inputs
+	toplevel := make(map[token.Pos]bool)
+	// REPL expressions evaluated by top-level statements: their value is printed
+	values := make(map[token.Pos]bool)
+	if stmts := append(b.inits, b.stmts...); len(stmts) != 0 {
+		body := &ast.BlockStmt{}
+		for _, node := range stmts {
+			stmt := typeCheckStmt(node)
+			if stmt == nil {
+				continue
+			}
+			typeCheckUnlower(r.ValueOf(stmt), &undo)
+			body.List = append(body.List, stmt)
+			if !files {
+				collectTopLevelVars(toplevel, stmt)
+				if expr, ok := stmt.(*ast.ExprStmt); ok {
+					values[expr.X.Pos()] = true
+				}
+			}
+		}
+		file.Decls = append(file.Decls, &ast.FuncDecl{
+			Name: ast.NewIdent("_"),
+			Type: &ast.FuncType{Params: &ast.FieldList{}},
+			Body: body,
+		})
+	}
+
+	conf := types.Config{
+		Importer: typeCheckImporter{c},
+		Error: func(err error) {
+			if terr, ok := err.(types.Error); ok {
+				errs = append(errs, terr)
+			}
+		},
+		Sizes: types.SizesFor("gc", runtime.GOARCH),
+	}
+	types.NewChecker(&conf, &c.Fileset.FileSet, pkg, nil).Files([]*ast.File{file})
+
+	var list []string
+	var pos token.Position
+	for _, err := range errs {
+		if !files && (toplevel[err.Pos] && strings.Contains(err.Msg, "declared and not used") ||
+			values[err.Pos] && strings.Contains(err.Msg, "is not used") ||
+			strings.Contains(err.Msg, "imported and not used")) {
+			// printed by REPL, or used by later inputs
+			continue
+		}
+		errpos := c.Fileset.Position(err.Pos)
+		if len(list) == 0 {
+			pos = errpos
+		}
+		if len(list) == typeCheckMaxErrors {
+			list = append(list, prefixPosition(errpos, "too many errors"))
+			break
+		}
+		list = append(list, prefixPosition(errpos, err.Msg))
+	}
+	if len(list) != 0 {
+		panic(&TypeCheckError{Pos: pos, Errors: list})
+	}
+}
+
+// convert a top-level REPL statement or expression to a statement valid inside a function
+func typeCheckStmt(node ast.Node) ast.Stmt {
+	switch node := node.(type) {
+	case *ast.ExprStmt:
+		return typeCheckStmt(node.X)
+	case ast.Stmt:
+		return node
+	case ast.Expr:
+		// REPL prints the value of expressions: Go would complain it is not used
+		return &ast.ExprStmt{X: node}
+	}
+	return nil
+}
+
+var (
+	rtypeOfAstObject = r.TypeOf((*ast.Object)(nil))
+	rtypeOfAstScope  = r.TypeOf((*ast.Scope)(nil))
+	rtypeOfAstExpr   = r.TypeOf((*ast.Expr)(nil)).Elem()
+)
+
+// the parser lowers Go generics onto templates, see parser/template.go.
+// Restore in place the standard representation expected by go/types,
+// appending to undo the functions that revert each change:
+// Comp compiles the lowered representation
+func typeCheckUnlower(v r.Value, undo *[]func()) {
+	switch v.Kind() {
+	case r.Interface:
+		if v.IsNil() {
+			return
+		}
+		if x, ok := v.Interface().(*ast.IndexExpr); ok && v.Type() == rtypeOfAstExpr && v.CanSet() {
+			if lit, ok := x.Index.(*ast.CompositeLit); ok && lit.Type == nil {
+				// x[T1, T2...] is parsed as x[CompositeLit{T1, T2...}]
+				v.Set(r.ValueOf(&ast.IndexListExpr{X: x.X, Lbrack: x.Lbrack, Indices: lit.Elts, Rbrack: x.Rbrack}))
+				*undo = append(*undo, func() { v.Set(r.ValueOf(x)) })
+			}
+		}
+		typeCheckUnlower(v.Elem(), undo)
+	case r.Ptr:
+		if v.IsNil() || v.Type() == rtypeOfAstObject || v.Type() == rtypeOfAstScope {
+			return
+		}
+		switch node := v.Interface().(type) {
+		case *ast.TypeSpec:
+			if lit, ok := node.Type.(*ast.CompositeLit); ok && node.TypeParams != nil && lit.Type != nil {
+				// template parameters are stored in CompositeLit
+				node.Type = lit.Type
+				*undo = append(*undo, func() { node.Type = lit })
+			}
+		case *ast.FuncDecl:
+			if recv := node.Recv; recv != nil && len(recv.List) == 2 && node.Type.TypeParams != nil {
+				// template parameters are stored as second receiver
+				if recv.List[0] == nil {
+					node.Recv = nil
+				} else {
+					node.Recv = &ast.FieldList{Opening: recv.Opening, List: recv.List[:1], Closing: recv.Closing}
+				}
+				*undo = append(*undo, func() { node.Recv = recv })
+			}
+		}
+		typeCheckUnlower(v.Elem(), undo)
+	case r.Struct:
+		for i, n := 0, v.NumField(); i < n; i++ {
+			typeCheckUnlower(v.Field(i), undo)
+		}
+	case r.Slice:
+		for i, n := 0, v.Len(); i < n; i++ {
+			typeCheckUnlower(v.Index(i), undo)
+		}
+	}
+}
+
+// return the names of the types declared by decls
+func typeCheckDeclaredTypes(decls []ast.Decl) map[string]bool {
+	m := make(map[string]bool)
+	for _, decl := range decls {
+		if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.TYPE {
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok {
+					m[spec.Name.Name] = true
+				}
+			}
+		}
+	}
+	return m
+}
+
+// go/types only accepts methods on types declared in the same package, i.e. in the checked input.
+// Convert a method of a type declared by previous inputs to a function
+// that takes the receiver as first parameter, so that at least its body is type-checked.
+// Return nil if the receiver is generic and not declared in the checked file
+func typeCheckMethod(decl *ast.FuncDecl, typenames map[string]bool) *ast.FuncDecl {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl
+	}
+	recv := decl.Recv.List[0].Type
+	for {
+		switch x := recv.(type) {
+		case *ast.ParenExpr:
+			recv = x.X
+			continue
+		case *ast.StarExpr:
+			recv = x.X
+			continue
+		case *ast.IndexExpr:
+			// generic receiver
+			if ident, ok := x.X.(*ast.Ident); ok && typenames[ident.Name] {
+				return decl
+			}
+			return nil
+		case *ast.IndexListExpr:
+			if ident, ok := x.X.(*ast.Ident); ok && typenames[ident.Name] {
+				return decl
+			}
+			return nil
+		}
+		break
+	}
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return nil
+	} else if typenames[ident.Name] {
+		return decl
+	}
+	params := &ast.FieldList{List: append(decl.Recv.List[:1:1], decl.Type.Params.List...)}
+	return &ast.FuncDecl{
+		Name: &ast.Ident{NamePos: decl.Name.Pos(), Name: "_"},
+		Type: &ast.FuncType{Func: decl.Type.Func, Params: params, Results: decl.Type.Results},
+		Body: decl.Body,
+	}
+}
+
+// add to m the positions of variables declared by stmt at top level
+func collectTopLevelVars(m map[token.Pos]bool, stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		if stmt.Tok == token.DEFINE {
+			for _, lhs := range stmt.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					m[ident.Pos()] = true
+				}
+			}
+		}
+	case *ast.DeclStmt:
+		if decl, ok := stmt.Decl.(*ast.GenDecl); ok && decl.Tok == token.VAR {
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					for _, ident := range spec.Names {
+						m[ident.Pos()] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// return the package-level names declared in file, including imports
+func typeCheckDeclaredNames(file *ast.File) map[string]bool {
+	m := make(map[string]bool)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				m[decl.Name.Name] = true
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.ImportSpec:
+					if spec.Name != nil {
+						m[spec.Name.Name] = true
+					} else if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+						m[path[1+strings.LastIndexByte(path, '/'):]] = true
+					}
+				case *ast.TypeSpec:
+					m[spec.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						m[ident.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+// declare in pkg the types, constants, functions, variables and imports
+// already declared by the interpreter, except the ones redeclared by the checked code.
+// Gomacro builtins are not declared: Go builtins are already in go/types universe.
+// Generic functions and types have no go/types equivalent until instantiated:
+// return their declarations, to be type-checked together with the code that uses them
+func (c *Comp) typeCheckDeclarePrevious(pkg *types.Package, skip map[string]bool) []ast.Decl {
+	var decls []ast.Decl
+	imp := typeCheckImporter{c}
+	scope := pkg.Scope()
+	for o := c; o != nil && o.Outer != nil; o = o.Outer {
+		for name, t := range o.Types {
+			if skip[name] || scope.Lookup(name) != nil {
+				continue
+			}
+			if gnamed, ok := t.GoType().(*types.Named); ok && gnamed.Obj().Name() == name {
+				scope.Insert(gnamed.Obj())
+			} else {
+				scope.Insert(types.NewTypeName(token.NoPos, pkg, name, t.GoType()))
+			}
+		}
+		for name, bind := range o.Binds {
+			if skip[name] || scope.Lookup(name) != nil {
+				continue
+			}
+			var obj types.Object
+			if decl := typeCheckTemplateDecls(name, bind); decl != nil {
+				decls = append(decls, decl...)
+				continue
+			} else if i, ok := bind.Value.(*Import); ok && bind.Desc.Class() == ConstBind {
+				if ipkg, err := imp.Import(i.Path); err == nil {
+					obj = types.NewPkgName(token.NoPos, pkg, name, ipkg)
+				}
+			} else {
+				obj = typeCheckObject(pkg, bind)
+			}
+			if obj != nil {
+				scope.Insert(obj)
+			}
+		}
+	}
+	return decls
+}
+
+// return the declaration of a generic function or type, including its methods.
+// Return nil if bind is not a generic, or if it was declared with template[] syntax
+func typeCheckTemplateDecls(name string, bind *Bind) []ast.Decl {
+	switch bind.Desc.Class() {
+	case TemplateFuncBind:
+		if fun, ok := bind.Value.(*TemplateFunc); ok && fun.Master.Constraints != nil {
+			decl := fun.Master.Decl
+			return []ast.Decl{&ast.FuncDecl{Name: ast.NewIdent(name), Type: decl.Type, Body: decl.Body}}
+		}
+	case TemplateTypeBind:
+		if typ, ok := bind.Value.(*TemplateType); ok && typ.Master.Constraints != nil {
+			decl := typ.Master
+			tparams := &ast.FieldList{}
+			for i, param := range decl.Params {
+				tparams.List = append(tparams.List, &ast.Field{Names: []*ast.Ident{ast.NewIdent(param)}, Type: decl.Constraints[i]})
+			}
+			spec := &ast.TypeSpec{Name: ast.NewIdent(name), TypeParams: tparams, Type: decl.Decl}
+			if decl.Alias {
+				spec.Assign = decl.Decl.Pos()
+			}
+			decls := []ast.Decl{&ast.GenDecl{Tok: token.TYPE, Specs: []ast.Spec{spec}}}
+			for _, method := range typ.Methods {
+				decls = append(decls, method.Decl)
+			}
+			return decls
+		}
+	}
+	return nil
+}
+
+// return the go/types object corresponding to bind, or nil if it's not valid Go
+func typeCheckObject(pkg *types.Package, bind *Bind) types.Object {
+	name, t := bind.Name, bind.Type
+	if t == nil {
+		return nil
+	}
+	switch bind.Desc.Class() {
+	case ConstBind:
+		if lit, ok := bind.Value.(UntypedLit); ok {
+			return types.NewConst(token.NoPos, pkg, name, types.Typ[typeCheckUntypedKind(lit.Kind)], lit.Val)
+		} else if val := typeCheckConstant(bind.Value); val != nil {
+			return types.NewConst(token.NoPos, pkg, name, t.GoType(), val)
+		}
+	case FuncBind:
+		if sig, ok := t.GoType().(*types.Signature); ok {
+			return types.NewFunc(token.NoPos, pkg, name, sig)
+		}
+	case VarBind, IntBind:
+		return types.NewVar(token.NoPos, pkg, name, t.GoType())
+	}
+	return nil
+}
+
+// return the exact value of a typed constant, or nil if value is not a constant
+func typeCheckConstant(value I) constant.Value {
+	if val := constantOf(value); val != nil {
+		return val
+	}
+	v := r.ValueOf(value)
+	switch reflect.Category(v.Kind()) {
+	case r.Bool:
+		return constant.MakeBool(v.Bool())
+	case r.String:
+		return constant.MakeString(v.String())
+	}
+	return nil
+}
+
+func typeCheckUntypedKind(kind untyped.Kind) types.BasicKind {
+	switch kind {
+	case untyped.Bool:
+		return types.UntypedBool
+	case untyped.Rune:
+		return types.UntypedRune
+	case untyped.Float:
+		return types.UntypedFloat
+	case untyped.Complex:
+		return types.UntypedComplex
+	case untyped.String:
+		return types.UntypedString
+	case untyped.None:
+		return types.UntypedNil
+	default:
+		return types.UntypedInt
+	}
+}
+
+// typeCheckImporter implements types.Importer for packages in imports.Packages
+type typeCheckImporter struct {
+	c *Comp
+}
+
+func (imp typeCheckImporter) Import(path string) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	c := imp.c
+	i, err := c.loadImport(path)
+	if err != nil {
+		return nil, err
+	}
+	g := c.CompGlobals
+	if pkg := g.typesPackages[i]; pkg != nil {
+		return pkg, nil
+	}
+	pkg := i.typesPackage()
+	if g.typesPackages == nil {
+		g.typesPackages = make(map[*Import]*types.Package)
+	}
+	g.typesPackages[i] = pkg
+	return pkg, nil
+}
+
+// create a go/types package containing the declarations of imp
+func (imp *Import) typesPackage() *types.Package {
+	pkg := types.NewPackage(imp.Path, imp.Name)
+	scope := pkg.Scope()
+	for name, t := range imp.Types {
+		if gnamed, ok := t.GoType().(*types.Named); ok && gnamed.Obj().Name() == name {
+			scope.Insert(gnamed.Obj())
+		} else {
+			scope.Insert(types.NewTypeName(token.NoPos, pkg, name, t.GoType()))
+		}
+	}
+	for _, bind := range imp.Binds {
+		if obj := typeCheckObject(pkg, bind); obj != nil && scope.Lookup(bind.Name) == nil {
+			scope.Insert(obj)
+		}
+	}
+	pkg.MarkComplete()
+	return pkg
+}
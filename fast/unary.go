@@ -23,9 +23,16 @@ import (
 
 	"github.com/steele232/zoumacro/base"
 	mt "github.com/steele232/zoumacro/token"
+	xr "github.com/steele232/zoumacro/xreflect"
 )
 
 func (c *Comp) UnaryExpr(node *ast.UnaryExpr) *Expr {
+	return c.unaryExpr(node, nil)
+}
+
+// unaryExpr compiles a unary expression.
+// t is the type expected by the context, see Comp.exprContext()
+func (c *Comp) unaryExpr(node *ast.UnaryExpr, t xr.Type) *Expr {
 	switch node.Op {
 	case mt.QUOTE:
 		// surprisingly easy :)
@@ -44,7 +51,13 @@ func (c *Comp) UnaryExpr(node *ast.UnaryExpr) *Expr {
 		return c.AddressOf(node)
 	}
 
-	xe := c.Expr1(node.X, nil)
+	var xe *Expr
+	switch node.Op {
+	case token.ADD, token.SUB, token.XOR:
+		xe = c.exprContext(node.X, numericContext(t))
+	default:
+		xe = c.Expr1(node.X, nil)
+	}
 	if xe.Type == nil {
 		return c.invalidUnaryExpr(node, xe)
 	}
@@ -52,6 +65,12 @@ func (c *Comp) UnaryExpr(node *ast.UnaryExpr) *Expr {
 		return c.UnaryExprUntyped(node, xe)
 	}
 	isConst := xe.Const()
+	if isConst {
+		if val := c.constantUnaryOp(node, node.Op, xe.Value, xe.Type); val != nil {
+			return c.exprValue(xe.Type, val)
+		}
+	}
+	flags := xe.EFlags & EIsUntypedShift
 	xe.WithFun()
 	var z *Expr
 
@@ -76,6 +95,7 @@ func (c *Comp) UnaryExpr(node *ast.UnaryExpr) *Expr {
 		// constant propagation
 		z.EvalConst(COptKeepUntyped)
 	}
+	z.EFlags |= flags
 	return z
 }
 
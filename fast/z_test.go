@@ -0,0 +1,1697 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * z_test.go
+ *
+ *  Created on Oct 19, 2026
+ *      Author Massimiliano Ghilardi
+ */
+
+package fast
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	r "reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/steele232/zoumacro/base"
+	"github.com/steele232/zoumacro/base/inspect"
+	"github.com/steele232/zoumacro/base/output"
+	"github.com/steele232/zoumacro/imports"
+	xr "github.com/steele232/zoumacro/xreflect"
+)
+
+// evaluate src, which must return a single value, and compare it with expected
+func isEval(t *testing.T, ir *Interp, src string, expected interface{}) {
+	t.Helper()
+	v, _ := ir.Eval1(src)
+	if v.Kind() == r.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || !r.DeepEqual(v.Interface(), expected) {
+		t.Errorf("%s: expecting %v <%T>, found %v", src, expected, expected, v)
+	}
+}
+
+// evaluate src, which must fail to compile or panic
+func isEvalPanic(t *testing.T, ir *Interp, src string) {
+	t.Helper()
+	defer func() {
+		if rec := recover(); rec == nil {
+			t.Errorf("%s: expecting a panic, found none", src)
+		}
+	}()
+	ir.Eval(src)
+}
+
+func TestForkVariables(t *testing.T) {
+	ir := New()
+	ir.Eval(`var x = 1; var s = []int{1, 2}; var m = map[string]int{"a": 1}; var str = "abc"`)
+	fork := ir.Fork()
+
+	fork.Eval(`x = 2; s[0] = 10; m["a"] = 5; str += "def"`)
+	isEval(t, ir, "x", 1)
+	isEval(t, ir, "s[0]", 1)
+	isEval(t, ir, `m["a"]`, 1)
+	isEval(t, ir, "str", "abc")
+	isEval(t, fork, "x", 2)
+	isEval(t, fork, "s[0]", 10)
+	isEval(t, fork, `m["a"]`, 5)
+	isEval(t, fork, "str", "abcdef")
+
+	ir.Eval(`x = 3; s = append(s, 3)`)
+	isEval(t, fork, "x", 2)
+	isEval(t, fork, "len(s)", 2)
+}
+
+func TestForkAliasing(t *testing.T) {
+	ir := New()
+	ir.Eval(`type P struct { X int; Next *P }`)
+	ir.Eval(`var p = &P{X: 1}; var q = p; p.Next = p`)
+	ir.Eval(`var i int; var pi = &i`)
+	ir.Eval(`var a = []int{1, 2, 3}; var b = a[1:]`)
+	fork := ir.Fork()
+
+	fork.Eval(`q.X = 5; *pi = 7; b[0] = 20`)
+	isEval(t, fork, "p.X", 5)
+	isEval(t, fork, "p.Next.X", 5)
+	isEval(t, fork, "i", 7)
+	isEval(t, ir, "p.X", 1)
+	isEval(t, ir, "p.Next.X", 1)
+	isEval(t, ir, "i", 0)
+	isEval(t, ir, "a[1]", 2)
+}
+
+func TestForkFunctions(t *testing.T) {
+	ir := New()
+	ir.Eval(`var n int; func inc() int { n++; return n }`)
+	isEval(t, ir, "inc()", 1)
+	fork := ir.Fork()
+
+	isEval(t, fork, "inc()", 2)
+	isEval(t, fork, "inc()", 3)
+	isEval(t, ir, "n", 1)
+	isEval(t, ir, "inc()", 2)
+	isEval(t, fork, "n", 3)
+}
+
+func TestForkMethodsAndClosures(t *testing.T) {
+	ir := New()
+	ir.Eval(`var n int; type C struct{}; func (C) Inc() { n += 10 }`)
+	ir.Eval(`var add = func(d int) { n += d }`)
+	ir.Eval(`func counter() func() int { k := 0; return func() int { k++; return k } }; var next = counter()`)
+	isEval(t, ir, "next()", 1)
+	fork := ir.Fork()
+
+	fork.Eval(`C{}.Inc()`)
+	isEval(t, fork, "n", 10)
+	isEval(t, ir, "n", 0)
+
+	fork.Eval(`var i interface{ Inc() } = C{}; i.Inc(); add(5)`)
+	isEval(t, fork, "n", 25)
+	isEval(t, ir, "n", 0)
+
+	isEval(t, fork, "next()", 2)
+	isEval(t, fork, "next()", 3)
+	isEval(t, ir, "next()", 2)
+
+	ir.Eval(`C{}.Inc()`)
+	isEval(t, ir, "n", 10)
+	isEval(t, fork, "n", 25)
+}
+
+func TestForkDeclarations(t *testing.T) {
+	ir := New()
+	ir.Eval(`var x = 1`)
+	fork := ir.Fork()
+
+	fork.Eval(`var y = 2; func f() int { return x + y }; type T int`)
+	isEval(t, fork, "f()", 3)
+	isEvalPanic(t, ir, "y")
+	isEvalPanic(t, ir, "f()")
+	isEvalPanic(t, ir, "T(0)")
+
+	// redeclaring x in the parent does not affect the fork
+	ir.Eval(`var x = "parent"`)
+	isEval(t, fork, "x", 1)
+}
+
+func TestForkSharedChannels(t *testing.T) {
+	ir := New()
+	ir.Eval(`var ch = make(chan int, 1)`)
+	fork := ir.Fork()
+
+	fork.Eval(`ch <- 42`)
+	isEval(t, ir, "<-ch", 42)
+}
+
+func TestForkConcurrent(t *testing.T) {
+	ir := New()
+	ir.Eval(`var total int; func add(n int) int { for i := 1; i <= n; i++ { total += i }; return total }`)
+	const nforks = 8
+	forks := make([]*Interp, nforks)
+	for i := range forks {
+		forks[i] = ir.Fork()
+	}
+	var wg sync.WaitGroup
+	wg.Add(nforks)
+	for _, fork := range forks {
+		go func(fork *Interp) {
+			defer wg.Done()
+			fork.Eval("add(100)")
+		}(fork)
+	}
+	wg.Wait()
+	for _, fork := range forks {
+		isEval(t, fork, "total", 5050)
+	}
+	isEval(t, ir, "total", 0)
+}
+
+func TestForkTracking(t *testing.T) {
+	ir := New()
+	ir.Eval(`var n int; func inc() int { n++; return n }`)
+	other := New()
+	run := ir.env.Run
+
+	fork := ir.Fork()
+	fork2 := fork.Fork()
+	isEval(t, fork2, "inc()", 1)
+	isEval(t, fork, "inc()", 1)
+	isEval(t, ir, "inc()", 1)
+	if n := run.forking.count(); n != 2 {
+		t.Errorf("expecting 2 forks tracked, found %d", n)
+	}
+	// interpreters without forks do not track goroutines
+	if other.env.Run.forking.count() != 0 || other.env.Run.forking == run.forking {
+		t.Errorf("unrelated interpreter shares the fork tracker")
+	}
+
+	// dropped forks are no longer tracked
+	fork, fork2 = nil, nil
+	for i := 0; i < 50 && run.forking.count() != 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if n := run.forking.count(); n != 0 {
+		t.Errorf("expecting no forks tracked after dropping them, found %d", n)
+	}
+	if n := atomic.LoadInt32(&run.forks.forked); n != 0 {
+		t.Errorf("expecting interpreter not forked after dropping its forks, found %d forks", n)
+	}
+	isEval(t, ir, "inc()", 2)
+}
+
+func TestForkSandbox(t *testing.T) {
+	ir := New()
+	ir.SetSandbox(&Sandbox{MaxSteps: 1000, MaxGoroutines: 1, DenyImports: []string{"os"}})
+	ir.Eval(`func loop(n int) int { s := 0; for i := 0; i < n; i++ { s += i }; return s }`)
+	ir.Eval(`var block = make(chan bool); func wait() { <-block }`)
+	ir.Eval(`go wait()`)
+	fork := ir.Fork()
+
+	// the fork has the same rules
+	isSandboxError(t, fork, `import "os"`, SandboxImport)
+	isSandboxError(t, fork, `loop(10000)`, SandboxSteps)
+	isEval(t, fork, `loop(10)`, 45)
+
+	// and its own counters: the goroutine running in ir does not count
+	isEval(t, fork, `go wait(); 1`, 1)
+	isSandboxError(t, ir, `go wait()`, SandboxGoroutines)
+	isSandboxError(t, fork, `go wait()`, SandboxGoroutines)
+	// channels are shared with the fork: this also stops the goroutine started by the fork
+	ir.Eval(`close(block)`)
+}
+
+type definePoint struct {
+	X, Y int
+}
+
+type defineGreeter interface {
+	String() string
+}
+
+func TestDefinePackage(t *testing.T) {
+	counter := 10
+	ir := New()
+	ir.DefinePackage("example.com/host/api", "", map[string]interface{}{
+		"Point":   r.TypeOf((*definePoint)(nil)).Elem(),
+		"Greeter": r.TypeOf((*defineGreeter)(nil)).Elem(),
+		"Counter": &counter,
+		"Version": "1.0",
+		"Add":     func(a, b int) int { return a + b },
+		"Greet":   func(g defineGreeter) string { return "hello " + g.String() },
+	})
+	ir.Eval(`import "example.com/host/api"`)
+	isEval(t, ir, "api.Add(2, 3)", 5)
+	isEval(t, ir, "api.Version", "1.0")
+	isEval(t, ir, "api.Counter", 10)
+	isEval(t, ir, "api.Point{1, 2}", definePoint{1, 2})
+
+	ir.Eval("api.Counter++")
+	if counter != 11 {
+		t.Errorf("api.Counter++: expecting 11, found %d", counter)
+	}
+
+	// api.Greeter has the same methods as fmt.Stringer, thus it reuses its proxy
+	ir.Eval(`type World struct{}; func (World) String() string { return "world" }`)
+	isEval(t, ir, "api.Greet(World{})", "hello world")
+
+	// the package is only visible to ir
+	isEvalPanic(t, New(), `import "example.com/host/api"`)
+}
+
+func TestDefinePackageProxy(t *testing.T) {
+	pkg := imports.Package{}
+	pkg.LazyInit()
+	pkg.Types["Stringer"] = r.TypeOf((*fmt.Stringer)(nil)).Elem()
+	pkg.Types["Fooer"] = r.TypeOf((*interface{ Foo(int) })(nil)).Elem()
+	findProxies(pkg)
+	if pkg.Proxies["Stringer"] == nil {
+		t.Errorf("no proxy found for fmt.Stringer")
+	}
+	if proxy := pkg.Proxies["Fooer"]; proxy != nil {
+		t.Errorf("unexpected proxy found for interface{ Foo(int) }: %v", proxy)
+	}
+}
+
+func TestEvalErr(t *testing.T) {
+	ir := New()
+	ir.Eval(`func f(s []int, i int) int { return s[i] }`)
+	ir.Eval(`func g(v interface{}) { panic(v) }`)
+
+	_, _, err := ir.EvalErr("1 +")
+	var perr *ParseError
+	if !errors.As(err, &perr) || !perr.Pos.IsValid() {
+		t.Errorf("expecting *ParseError with position, found %v <%T>", err, err)
+	}
+
+	_, _, err = ir.EvalErr("undefinedVar + 1")
+	var cerr *CompileError
+	if !errors.As(err, &cerr) || !cerr.Pos.IsValid() {
+		t.Errorf("expecting *CompileError with position, found %v <%T>", err, err)
+	}
+
+	_, _, err = ir.EvalErr("f(nil, 3)")
+	var rerr *RuntimeError
+	if !errors.As(err, &rerr) {
+		t.Errorf("expecting *RuntimeError, found %v <%T>", err, err)
+	} else if len(rerr.Backtrace) != 2 || !rerr.Pos.IsValid() || rerr.Pos != rerr.Backtrace[0].Pos {
+		t.Errorf("unexpected backtrace: %v", rerr.Backtrace)
+	}
+
+	_, _, err = ir.EvalErr("var z int; 1 / z")
+	var rterr runtime.Error
+	if !errors.As(err, &rerr) || !errors.As(err, &rterr) {
+		t.Errorf("expecting *RuntimeError wrapping a runtime.Error, found %v <%T>", err, err)
+	}
+
+	_, _, err = ir.EvalErr("g(io.EOF)")
+	if err == nil {
+		// io is not imported: expect a compile error
+		t.Errorf("expecting an error, found nil")
+	}
+	ir.Eval(`import "io"`)
+	_, _, err = ir.EvalErr("g(io.EOF)")
+	var panicerr *PanicError
+	if !errors.As(err, &panicerr) || panicerr.Value != io.EOF || !errors.Is(err, io.EOF) {
+		t.Errorf("expecting *PanicError wrapping io.EOF, found %v <%T>", err, err)
+	} else if len(panicerr.Backtrace) != 2 || !panicerr.Pos.IsValid() {
+		t.Errorf("unexpected backtrace: %v", panicerr.Backtrace)
+	}
+
+	_, _, err = ir.EvalErr(`g("boom")`)
+	if !errors.As(err, &panicerr) || panicerr.Value != "boom" {
+		t.Errorf(`expecting *PanicError with value "boom", found %v <%T>`, err, err)
+	}
+
+	// function names are known only to the debugger
+	ir = New()
+	ir.Comp.Globals.Options |= OptDebugger
+	ir.Eval(`func h() { var m map[int]int; m[0] = 1 }`)
+	_, _, err = ir.EvalErr("h()")
+	if !errors.As(err, &rerr) || len(rerr.Backtrace) != 2 || rerr.Backtrace[0].Func != "main.h" {
+		t.Errorf("expecting *RuntimeError in main.h, found %v <%T>", err, err)
+	}
+	ir.Eval(`func f(s []int, i int) int { return s[i] }`)
+
+	// the interpreter is still usable after errors
+	vals, _, err := ir.EvalErr("f([]int{7, 8}, 1)")
+	if err != nil || len(vals) != 1 || vals[0].Interface() != 8 {
+		t.Errorf("expecting 8, found %v, %v", vals, err)
+	}
+}
+
+// run EvalContext in a goroutine different from the one that created ir
+func evalContext(t *testing.T, ctx context.Context, ir *Interp, src string) error {
+	t.Helper()
+	done := make(chan error)
+	go func() {
+		_, _, err := ir.EvalContext(ctx, src)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s: not interrupted", src)
+		return nil
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	ir := New()
+	ir.Eval(`func spin() { for {} }; func spin2() { spin() }`)
+	ir.Eval(`func sum(n int) int { s := 0; for i := 0; i < n; i++ { s += i }; return s }`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := evalContext(t, ctx, ir, "spin()")
+	var cerr *ContextError
+	if !errors.As(err, &cerr) || !errors.Is(err, context.Canceled) || !cerr.Pos.IsValid() {
+		t.Errorf("expecting *ContextError wrapping context.Canceled, found %v <%T>", err, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = evalContext(t, ctx, ir, "spin2()")
+	if !errors.As(err, &cerr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting *ContextError wrapping context.DeadlineExceeded, found %v <%T>", err, err)
+	}
+
+	err = evalContext(t, context.Background(), ir, "1 +")
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("expecting *ParseError, found %v <%T>", err, err)
+	}
+	err = evalContext(t, context.Background(), ir, "spin(1)")
+	var comperr *CompileError
+	if !errors.As(err, &comperr) {
+		t.Errorf("expecting *CompileError, found %v <%T>", err, err)
+	}
+
+	// runtime errors are returned, not panicked
+	err = evalContext(t, context.Background(), ir, `panic("boom")`)
+	var panicerr *PanicError
+	if !errors.As(err, &panicerr) || panicerr.Value != "boom" {
+		t.Errorf("expecting *PanicError with value boom, found %v <%T>", err, err)
+	}
+	_, _, err = ir.EvalContext(context.Background(), `var s []int; s[1]`)
+	var rterr *RuntimeError
+	if !errors.As(err, &rterr) {
+		t.Errorf("expecting *RuntimeError, found %v <%T>", err, err)
+	}
+
+	// the interpreter is still usable, and not interrupted, after a cancelled execution
+	vals, _, err := ir.EvalContext(context.Background(), "sum(100000)")
+	if err != nil || len(vals) != 1 || vals[0].Interface() != 4999950000 {
+		t.Errorf("expecting 4999950000, found %v, %v", vals, err)
+	}
+}
+
+// evaluate src, which must fail with a *SandboxError for the given violation
+func isSandboxError(t *testing.T, ir *Interp, src string, violation SandboxViolation) {
+	t.Helper()
+	_, _, err := ir.EvalErr(src)
+	var serr *SandboxError
+	if !errors.As(err, &serr) || serr.Violation != violation {
+		t.Errorf("%s: expecting *SandboxError for %v, found %v <%T>", src, violation, err, err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	ir := New()
+	ir.SetSandbox(&Sandbox{
+		DenyImports: []string{"os/exec", "net/*"},
+		DenySymbols: []string{"os.Exit", "os.File", "strings.Builder.Reset"},
+	})
+	isSandboxError(t, ir, `import "os/exec"`, SandboxImport)
+	isSandboxError(t, ir, `import "net/http"`, SandboxImport)
+	isSandboxError(t, ir, `import "os"; os.Exit(1)`, SandboxSymbol)
+	isSandboxError(t, ir, `var exit = os.Exit`, SandboxSymbol)
+	isSandboxError(t, ir, `var f *os.File`, SandboxSymbol)
+	// escape routes: dot import, method expressions and method values
+	isSandboxError(t, ir, `import . "os"`, SandboxSymbol)
+	isSandboxError(t, ir, `var close = (*os.File).Close`, SandboxSymbol)
+	isSandboxError(t, ir, `var close = os.Stdout.Close`, SandboxSymbol)
+	isSandboxError(t, ir, `os.Stdout.Name()`, SandboxSymbol)
+	ir.Eval(`import "strings"; var b strings.Builder`)
+	isSandboxError(t, ir, `b.Reset()`, SandboxSymbol)
+	isEval(t, ir, `b.WriteString("ok"); b.String()`, "ok")
+	isEval(t, ir, `os.Getpagesize() > 0`, true)
+
+	ir = New()
+	ir.SetSandbox(&Sandbox{
+		AllowImports: []string{"strings"},
+		AllowSymbols: []string{"strings.To*"},
+	})
+	isSandboxError(t, ir, `import "fmt"`, SandboxImport)
+	isEval(t, ir, `import "strings"; strings.ToUpper("a")`, "A")
+	isSandboxError(t, ir, `strings.Repeat("a", 2)`, SandboxSymbol)
+	isSandboxError(t, ir, `import . "strings"`, SandboxSymbol)
+	isEval(t, ir, `type T int; func (t T) Twice() T { return t * 2 }; int(T(2).Twice())`, 4)
+
+	ir = New()
+	ir.SetSandbox(&Sandbox{MaxSteps: 1000, MaxGoroutines: 2})
+	ir.Eval(`func spin() { for {} }`)
+	isSandboxError(t, ir, `for {}`, SandboxSteps)
+	isSandboxError(t, ir, `spin()`, SandboxSteps)
+	// steps are counted again from zero by each evaluation
+	isEval(t, ir, `func sum(n int) int { s := 0; for i := 0; i < n; i++ { s += i }; return s }; sum(10)`, 45)
+	isEval(t, ir, `sum(10)`, 45)
+	// goroutines started inside closures are counted too
+	ir.Eval(`var stop = make(chan bool)`)
+	ir.Eval(`func spawn(n int) { for i := 0; i < n; i++ { func() { go func() { <-stop }() }() } }`)
+	isSandboxError(t, ir, `spawn(3)`, SandboxGoroutines)
+	ir.Eval(`close(stop)`)
+}
+
+func TestProgram(t *testing.T) {
+	ir := New()
+	ir.Eval(`func square(x int) int { return x * x }`)
+	src := `
+var total int
+func add(n int) { total += n }
+for i := 0; i < n; i++ { add(square(i)) }
+total + len(name)`
+	prog, err := ir.CompileProgram(src, map[string]interface{}{"n": 0, "name": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(n int, name string) int {
+		vals, _, err := prog.Run(map[string]interface{}{"n": n, "name": name})
+		if err != nil {
+			t.Error(err)
+			return -1
+		}
+		return int(vals[0].Int())
+	}
+	// results are the same as Eval
+	ir2 := New()
+	ir2.Eval(`func square(x int) int { return x * x }; var n = 10; var name = "abc"`)
+	expected, _ := ir2.Eval1(src)
+	if actual := run(10, "abc"); actual != int(expected.Int()) {
+		t.Errorf("Program.Run: expecting %v, found %v", expected, actual)
+	}
+	// each run has its own globals
+	if actual := run(10, "abc"); actual != 288 {
+		t.Errorf("Program.Run: expecting 288, found %v", actual)
+	}
+	// program declarations are not visible to the interpreter
+	isEvalPanic(t, ir, "total")
+
+	// concurrent runs
+	var wg sync.WaitGroup
+	results := make([]int, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = run(i*10, "")
+		}(i)
+	}
+	wg.Wait()
+	for i, actual := range results {
+		var expected int
+		for j := 0; j < i*10; j++ {
+			expected += j * j
+		}
+		if actual != expected {
+			t.Errorf("concurrent Program.Run(%d): expecting %d, found %d", i*10, expected, actual)
+		}
+	}
+
+	// errors
+	if _, _, err := prog.Run(map[string]interface{}{"n": "x"}); err == nil {
+		t.Errorf("Program.Run with mismatched variable type: expecting an error, found nil")
+	}
+	if _, _, err := prog.Run(map[string]interface{}{"m": 1}); err == nil {
+		t.Errorf("Program.Run with unknown variable: expecting an error, found nil")
+	}
+	if _, err := ir.CompileProgram("undefined + 1", nil); err == nil {
+		t.Errorf("CompileProgram with undefined identifier: expecting an error, found nil")
+	}
+	prog, _ = ir.CompileProgram("var s []int; s[n]", map[string]interface{}{"n": 0})
+	var rerr *RuntimeError
+	if _, _, err := prog.Run(nil); !errors.As(err, &rerr) {
+		t.Errorf("Program.Run with index out of range: expecting *RuntimeError, found %v <%T>", err, err)
+	}
+}
+
+func TestProgramFiles(t *testing.T) {
+	dir := t.TempDir()
+	file1, file2 := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")
+	if err := ioutil.WriteFile(file1, []byte("package main\nfunc twice(x int) int { return 2 * x }\nfunc store(p *int, v int) int { *p = v; return v }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// files cannot end with an expression: the result is stored through the program variable out
+	if err := ioutil.WriteFile(file2, []byte("package main\nvar result = twice(x)\nvar _ = store(out, result)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ir := New()
+	prog, err := ir.CompileProgramFiles(map[string]interface{}{"x": 0, "out": new(int)}, file1, file2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []int{21, 5} {
+		var out int
+		if _, _, err = prog.Run(map[string]interface{}{"x": x, "out": &out}); err != nil {
+			t.Error(err)
+		} else if out != 2*x {
+			t.Errorf("Program.Run with x = %d: expecting result %d, found %d", x, 2*x, out)
+		}
+	}
+	if _, err = ir.CompileProgramFiles(nil, filepath.Join(dir, "missing.go")); err == nil {
+		t.Errorf("CompileProgramFiles with missing file: expecting an error, found nil")
+	}
+}
+
+func TestGenericFunc(t *testing.T) {
+	ir := New()
+	ir.Eval(`import "fmt"`)
+	ir.Eval(`func Max[T int | float64](a, b T) T { if a > b { return a }; return b }`)
+	ir.Eval(`func Map[T, U any](xs []T, f func(T) U) []U { var out []U; for _, x := range xs { out = append(out, f(x)) }; return out }`)
+	ir.Eval(`func Count[T any](xs ...T) int { return len(xs) }`)
+	ir.Eval(`func Keys[K comparable, V any](m map[K]V) []K { var ks []K; for k := range m { ks = append(ks, k) }; return ks }`)
+
+	isEval(t, ir, `Max[int](3, 4)`, 4)
+	isEval(t, ir, `Max(5, 2)`, 5)
+	isEval(t, ir, `Max(1, 2.5)`, 2.5)
+	isEval(t, ir, `x := 1.5; Max(x, 1)`, 1.5)
+	isEval(t, ir, `Map([]int{1, 2}, func(x int) string { return fmt.Sprint(x * 2) })`, []string{"2", "4"})
+	isEval(t, ir, `Map[int, bool]([]int{0}, func(x int) bool { return x == 0 })`, []bool{true})
+	isEval(t, ir, `Count("a", "b", "c")`, 3)
+	isEval(t, ir, `Count([]int{1, 2}...)`, 2)
+	isEval(t, ir, `Keys(map[string]bool{"k": true})`, []string{"k"})
+	isEvalPanic(t, ir, `Max[int, int](1, 2)`)
+}
+
+func TestGenericType(t *testing.T) {
+	ir := New()
+	ir.Eval(`type Pair[K comparable, V any] struct { Key K; Val V }`)
+	ir.Eval(`type List[T any] struct { items []T }`)
+	ir.Eval(`var strings List[string]`) // instantiated before its methods are declared
+	ir.Eval(`func (l *List[T]) Push(x T) { l.items = append(l.items, x) }`)
+	ir.Eval(`func (l *List[U]) Len() int { return len(l.items) }`)
+	ir.Eval(`func (l List[T]) Get(i int) T { return l.items[i] }`)
+	ir.Eval(`func NewList[T any](xs ...T) *List[T] { l := &List[T]{}; for _, x := range xs { l.Push(x) }; return l }`)
+	ir.Eval(`func First[T any](l List[T]) T { return l.Get(0) }`)
+
+	isEval(t, ir, `Pair[string, int]{"a", 1}.Val`, 1)
+	isEval(t, ir, `var p Pair[string, int]; p.Key = "x"; p.Key`, "x")
+	isEval(t, ir, `strings.Push("a"); strings.Len()`, 1)
+	isEval(t, ir, `var l List[int]; l.Push(3); l.Push(4); l.Get(1)`, 4)
+	isEval(t, ir, `NewList[int](1, 2, 3).Len()`, 3)
+	isEval(t, ir, `First(*NewList("z"))`, "z")
+
+	// array types and parameters are not confused with instantiations
+	isEval(t, ir, `const N = 2; type A [N * 2]int; len(A{})`, 4)
+	isEval(t, ir, `type S struct { a [2]int; List[bool] }; var s S; s.Len()`, 0)
+	isEval(t, ir, `func arr(a [2]int, b []int) int { return len(a) + len(b) }; arr([2]int{}, nil)`, 2)
+	isEval(t, ir, `xs := []int{7, 8}; xs[1]`, 8)
+
+	// template syntax also supports methods
+	ir.Eval(`template[T, U] type Couple struct { First T; Second U }`)
+	ir.Eval(`func (c Couple#[T, U]) Swap() Couple#[U, T] { return Couple#[U, T]{c.Second, c.First} }`)
+	isEval(t, ir, `Couple#[int, string]{1, "a"}.Swap().First`, "a")
+}
+
+func TestGenericConstraint(t *testing.T) {
+	ir := New()
+	ir.Eval(`type Number interface { ~int | ~float64 }`)
+	ir.Eval(`type Ordered interface { Number | ~string }`)
+	ir.Eval(`type Stringer interface { String() string }`)
+	ir.Eval(`type MyInt int`)
+	ir.Eval(`type Name string`)
+	ir.Eval(`func (n Name) String() string { return "name:" + string(n) }`)
+	ir.Eval(`func Sum[T Number](xs ...T) T { var s T; for _, x := range xs { s += x }; return s }`)
+	ir.Eval(`func Min[T Ordered](a, b T) T { if a < b { return a }; return b }`)
+	ir.Eval(`func Eq[T comparable](a, b T) bool { return a == b }`)
+	ir.Eval(`func Str[T Stringer](x T) string { return x.String() }`)
+	ir.Eval(`func Show[T interface{ ~string; String() string }](x T) string { return x.String() }`)
+	ir.Eval(`type Set[T comparable] struct { m map[T]bool }`)
+	ir.Eval(`func Keys[M ~map[K]V, K comparable, V any](m M) []K { var ks []K; for k := range m { ks = append(ks, k) }; return ks }`)
+	ir.Eval(`func First[S interface{ ~[]E }, E any](s S) E { return s[0] }`)
+	ir.Eval(`type Names []Name`)
+
+	isEval(t, ir, `Sum(1.5, 2.5)`, 4.0)
+	isEval(t, ir, `int(Sum(MyInt(1), MyInt(2)))`, 3)
+	isEval(t, ir, `Min("b", "a")`, "a")
+	isEval(t, ir, `Eq(1, 1)`, true)
+	isEval(t, ir, `Str(Name("x"))`, "name:x")
+	isEval(t, ir, `Show(Name("y"))`, "name:y")
+	isEval(t, ir, `var s Set[string]; len(s.m)`, 0)
+	// constraint type inference: K and V are inferred from the core type of M's constraint
+	isEval(t, ir, `Keys(map[string]int{"k": 1})`, []string{"k"})
+	isEval(t, ir, `len(Keys(map[string]int{}))`, 0)
+	isEval(t, ir, `First(Names{"n"}).String()`, "name:n")
+
+	isEvalPanic(t, ir, `Sum[uint8](1, 2)`)
+	isEvalPanic(t, ir, `Min(true, false)`)
+	isEvalPanic(t, ir, `Eq([]int{}, nil)`)
+	isEvalPanic(t, ir, `Str(3)`)
+	isEvalPanic(t, ir, `Show("z")`)
+	isEvalPanic(t, ir, `var s2 Set[[]int]`)
+	isEvalPanic(t, ir, `Keys([]int{1})`)
+
+	// errors name the unsatisfied constraint
+	_, _, err := ir.EvalErr(`Sum("a", "b")`)
+	if err == nil || !strings.Contains(err.Error(), "does not satisfy Number") {
+		t.Errorf("expecting error naming constraint Number, found: %v", err)
+	}
+}
+
+func TestGoto(t *testing.T) {
+	ir := New()
+	ir.Eval(`func skip() int { i := 0; goto L; i = 5; L: return i }`)
+	ir.Eval(`func loop() int { i := 0; again: i++; if i < 5 { goto again }; return i }`)
+	ir.Eval(`func out() (n int) { for i := 0; i < 10; i++ { if i == 3 { goto done }; n += i }; done: return n * 10 }`)
+	ir.Eval(`func next() (s int) { for i := 0; i < 3; i++ { x := i; goto L; s += 100 * x; L: s++ }; return s }`)
+
+	isEval(t, ir, `skip()`, 0)
+	isEval(t, ir, `loop()`, 5)
+	isEval(t, ir, `out()`, 30)
+	isEval(t, ir, `next()`, 3)
+	isEval(t, ir, `f := func() int { goto L; return 0; L: return 9 }; f()`, 9)
+
+	isEvalPanic(t, ir, `func overVar() int { goto L; x := 1; L: return x }`)
+	isEvalPanic(t, ir, `func intoBlock() int { goto L; if true { L: return 1 }; return 0 }`)
+	isEvalPanic(t, ir, `func intoBlockBack() int { { println(); L: return 1 }; goto L }`)
+	isEvalPanic(t, ir, `func intoBareBlock() int { goto L; { L: return 1 }; return 2 }`)
+	isEvalPanic(t, ir, `func intoBareBlockBack() int { { L: return 1 }; goto L }`)
+	isEvalPanic(t, ir, `func noLabel() { goto nowhere }`)
+}
+
+func TestInterfaceAssert(t *testing.T) {
+	ir := New()
+	ir.Eval(`import ("bytes"; "fmt"; "io"; "time")`)
+	ir.Eval(`type I interface { String() string }`)
+	ir.Eval(`type J interface { String() string; Len() int }`)
+	ir.Eval(`type T struct { n int }`)
+	ir.Eval(`func (t T) String() string { return fmt.Sprint("T", t.n) }`)
+	ir.Eval(`func (t T) Len() int { return t.n }`)
+	ir.Eval(`type P struct { s string }`)
+	ir.Eval(`func (p *P) String() string { return p.s }`)
+
+	// interpreted value stored in compiled interface
+	isEval(t, ir, `var e interface{} = T{3}; e.(I).String()`, "T3")
+	isEval(t, ir, `e.(J).Len()`, 3)
+	isEval(t, ir, `e.(fmt.Stringer).String()`, "T3")
+	isEval(t, ir, `var p interface{} = &P{"p"}; p.(I).String()`, "p")
+	isEval(t, ir, `_, ok := p.(J); ok`, false)
+	isEval(t, ir, `var pnil interface{} = (*P)(nil); _, ok := pnil.(J); ok`, false)
+	// interpreted value stored in emulated interface
+	isEval(t, ir, `var i I = T{4}; i.(J).Len()`, 4)
+	isEval(t, ir, `i.(fmt.Stringer).String()`, "T4")
+	isEval(t, ir, `var inil I; _, ok := inil.(J); ok`, false)
+	isEval(t, ir, `var ie interface{} = i; ie.(J).Len()`, 4)
+	// interpreted value stored in proxy
+	isEval(t, ir, `var s fmt.Stringer = T{5}; s.(J).Len()`, 5)
+	// compiled values
+	isEval(t, ir, `var d interface{} = time.Second; d.(I).String()`, "1s")
+	isEval(t, ir, `_, ok := d.(J); ok`, false)
+	isEval(t, ir, `var r io.Reader = &bytes.Buffer{}; _, ok := r.(I); ok`, true)
+	isEval(t, ir, `var n interface{} = 7; _, ok := n.(I); ok`, false)
+	isEvalPanic(t, ir, `d.(J).Len()`)
+
+	ir.Eval(`func kind(x interface{}) string { switch v := x.(type) { case J: return fmt.Sprint("J", v.Len()); case I: return "I" + v.String(); default: return "none" } }`)
+	ir.Eval(`func kinds(x fmt.Stringer) string { switch x.(type) { case *bytes.Buffer, J: return "J"; case io.Reader, I: return "I" }; return "none" }`)
+	isEval(t, ir, `kind(T{7})`, "J7")
+	isEval(t, ir, `kind(&P{"q"})`, "Iq")
+	isEval(t, ir, `kind(time.Second)`, "I1s")
+	isEval(t, ir, `kind(3)`, "none")
+	isEval(t, ir, `kind(i)`, "J4")
+	isEval(t, ir, `kinds(T{1})`, "J")
+	isEval(t, ir, `kinds(time.Second)`, "I")
+
+	// interpreted named type implemented by a named reflect.Type
+	isEval(t, ir, `type MyInt int; func (m MyInt) String() string { return fmt.Sprint("my", int(m)) }; 0`, 0)
+	isEval(t, ir, `var em interface{} = MyInt(2); em.(I).String()`, "my2")
+	isEval(t, ir, `kind(em)`, "Imy2")
+
+	// several interpreted types implemented by the same reflect.Type, as T, A and B:
+	// their values stored in interface{} are ambiguous
+	ir.Eval(`type A struct { n int }; func (A) String() string { return "A" }`)
+	ir.Eval(`type B struct { n int }; func (B) String() string { return "B" }`)
+	ir.Eval(`func which(x interface{}) string { switch x.(type) { case A: return "A"; case B: return "B" }; return "none" }`)
+	ir.Eval(`var eb interface{} = B{}`)
+	var aerr *AmbiguousTypeError
+	for _, src := range []string{`eb.(I).String()`, `which(eb)`, `eb.(A)`} {
+		if _, _, err := ir.EvalErr(src); !errors.As(err, &aerr) || len(aerr.Types) != 3 {
+			t.Errorf("%s: expecting *AmbiguousTypeError, found %v <%T>", src, err, err)
+		}
+	}
+	// values stored in interpreted interfaces keep their type
+	isEval(t, ir, `var ib I = B{}; ib.String()`, "B")
+	isEval(t, ir, `_, ok := ib.(A); ok`, false)
+	isEval(t, ir, `ib.(B).n`, 0)
+}
+
+func TestRecoverMixed(t *testing.T) {
+	var recovered interface{}
+	ir := New()
+	ir.DefinePackage("example.com/host/rec", "", map[string]interface{}{
+		// compiled function that recovers when deferred
+		"Recover": func() { recovered = recover() },
+		"Recovered": func() interface{} {
+			rec := recovered
+			recovered = nil
+			return rec
+		},
+		"Panic": func(v interface{}) { panic(v) },
+		// compiled function that defers f, then panics
+		"DeferPanic": func(f func(), v interface{}) { defer f(); panic(v) },
+		// compiled function that recovers panics raised by f
+		"Safe": func(f func()) (rec interface{}) {
+			defer func() { rec = recover() }()
+			f()
+			return nil
+		},
+	})
+	ir.Eval(`import ("fmt"; "example.com/host/rec")`)
+	ir.Eval(`var got interface{}`)
+	ir.Eval(`func last() string { s := fmt.Sprintf("%v %v", got, rec.Recovered()); got = nil; return s }`)
+
+	// interpreted function defers compiled function
+	ir.Eval(`func deferCompiled() (x int) { defer rec.Recover(); x = 1; panic("a") }`)
+	isEval(t, ir, `deferCompiled()`, 1)
+	isEval(t, ir, `last()`, "<nil> a")
+	ir.Eval(`func deferCompiledVar() { f := rec.Recover; defer f(); panic("b") }`)
+	isEval(t, ir, `deferCompiledVar(); last()`, "<nil> b")
+	ir.Eval(`func deferCompiledNoPanic() { defer rec.Recover() }`)
+	isEval(t, ir, `deferCompiledNoPanic(); last()`, "<nil> <nil>")
+	// compiled recover() works only if called directly by a deferred function
+	isEvalPanic(t, ir, `func indirect() { defer func() { rec.Recover() }(); panic("c") }; indirect()`)
+
+	// mixed defers run in order, and only the first one recovers
+	ir.Eval(`func mixed1() { defer func() { got = recover() }(); defer rec.Recover(); panic("d") }`)
+	isEval(t, ir, `mixed1(); last()`, "<nil> d")
+	ir.Eval(`func mixed2() { defer rec.Recover(); defer func() { got = recover() }(); panic("e") }`)
+	isEval(t, ir, `mixed2(); last()`, "e <nil>")
+	ir.Eval(`func mixed3() (x int) { defer func() { x = 3 }(); defer rec.Recover(); panic("f") }`)
+	isEval(t, ir, `mixed3()`, 3)
+	isEval(t, ir, `last()`, "<nil> f")
+	// a panic inside a deferred function replaces the current one
+	ir.Eval(`func mixed4() { defer rec.Recover(); defer func() { panic("g2") }(); panic("g1") }`)
+	isEval(t, ir, `mixed4(); last()`, "<nil> g2")
+
+	// interpreted function recovers a panic raised by compiled code
+	ir.Eval(`func recoverCompiled() { defer func() { got = recover() }(); rec.Panic("h") }`)
+	isEval(t, ir, `recoverCompiled(); last()`, "h <nil>")
+	ir.Eval(`func recoverCompiledDefer() { defer func() { got = recover() }(); rec.DeferPanic(func() {}, "i") }`)
+	isEval(t, ir, `recoverCompiledDefer(); last()`, "i <nil>")
+
+	// compiled code defers interpreted function
+	isEval(t, ir, `rec.DeferPanic(func() { got = recover() }, "j"); last()`, "j <nil>")
+	isEval(t, ir, `fmt.Sprint(rec.Safe(func() { rec.DeferPanic(func() {}, "k") }))`, "k")
+	isEval(t, ir, `fmt.Sprint(rec.Safe(func() { rec.DeferPanic(func() { panic("l2") }, "l1") }))`, "l2")
+	isEval(t, ir, `fmt.Sprint(rec.Safe(func() { rec.DeferPanic(func() { defer func() { got = recover() }() }, "m") })); last()`, "<nil> <nil>")
+
+	// compiled code recovers a panic raised by interpreted code
+	isEval(t, ir, `fmt.Sprint(rec.Safe(func() { panic("n") }))`, "n")
+
+	// recovering a nested panic does not recover the outer one
+	ir.Eval(`func inner() { defer func() { recover() }(); panic("inner") }`)
+	isEvalPanic(t, ir, `func outer() { defer func() { inner() }(); panic("o") }; outer()`)
+	ir.Eval(`func outer2() { defer func() { inner(); got = recover() }(); panic("p") }`)
+	isEval(t, ir, `outer2(); last()`, "p <nil>")
+	isEval(t, ir, `rec.DeferPanic(func() { inner(); got = recover() }, "q"); last()`, "q <nil>")
+}
+
+// records the panics for which the debugger is entered
+type panicDebugger struct {
+	stubDebugger
+	panics []interface{}
+}
+
+func (d *panicDebugger) Panic(ir *Interp, env *Env, rec interface{}) DebugOp {
+	d.panics = append(d.panics, rec)
+	return DebugOpContinue
+}
+
+func TestBreakOnPanic(t *testing.T) {
+	d := &panicDebugger{}
+	ir := New()
+	ir.Comp.Options |= OptDebugger | OptBreakOnPanic
+	ir.SetDebugger(d)
+	ir.DefinePackage("example.com/host/brk", "", map[string]interface{}{
+		// compiled function that recovers panics raised by f
+		"Safe": func(f func()) (rec interface{}) {
+			defer func() { rec = recover() }()
+			f()
+			return nil
+		},
+	})
+	ir.Eval(`import ("fmt"; "example.com/host/brk")`)
+	ir.Eval(`func inner(v int) { panic(v) }`)
+	ir.Eval(`func outer(v int) { inner(v) }`)
+
+	// the debugger is entered once per panic, not once per unwound function
+	if _, _, err := ir.EvalErr(`outer(1)`); err == nil {
+		t.Errorf("outer(1): expecting a panic, found nil error")
+	}
+	if !r.DeepEqual(d.panics, []interface{}{1}) {
+		t.Errorf("outer(1): expecting debugger entered for panics [1], found %v", d.panics)
+	}
+
+	// after compiled code recovers a panic, the next one enters the debugger again
+	d.panics = nil
+	ir.Eval(`func twice() { brk.Safe(func() { outer(2) }); outer(3) }`)
+	ir.EvalErr(`twice()`)
+	if !r.DeepEqual(d.panics, []interface{}{2, 3}) {
+		t.Errorf("twice(): expecting debugger entered for panics [2 3], found %v", d.panics)
+	}
+	d.panics = nil
+	ir.Eval(`func again() { brk.Safe(func() { inner(4) }); inner(4) }`)
+	ir.EvalErr(`again()`)
+	if !r.DeepEqual(d.panics, []interface{}{4, 4}) {
+		t.Errorf("again(): expecting debugger entered for panics [4 4], found %v", d.panics)
+	}
+
+	// interpreted recover() ends the panic
+	d.panics = nil
+	ir.Eval(`func rec5() (x interface{}) { defer func() { x = recover() }(); outer(5); return }`)
+	isEval(t, ir, `rec5(); fmt.Sprint(rec5())`, "5")
+	if !r.DeepEqual(d.panics, []interface{}{5, 5}) {
+		t.Errorf("rec5(): expecting debugger entered for panics [5 5], found %v", d.panics)
+	}
+}
+
+// a Debugger that does not implement PanicDebugger
+type plainDebugger struct{}
+
+func (plainDebugger) Breakpoint(ir *Interp, env *Env) DebugOp { return DebugOpContinue }
+func (plainDebugger) At(ir *Interp, env *Env) DebugOp         { return DebugOpContinue }
+
+// replaces the value of each panic
+type replaceDebugger struct {
+	stubDebugger
+	with interface{}
+}
+
+func (d *replaceDebugger) Panic(ir *Interp, env *Env, rec interface{}) DebugOp {
+	return DebugOp{Panic: &d.with}
+}
+
+func TestPanicDebugger(t *testing.T) {
+	ir := New()
+	ir.Comp.Options |= OptDebugger | OptBreakOnPanic
+	ir.Eval(`import "fmt"`)
+	ir.Eval(`func inner(v int) { panic(v) }`)
+	ir.Eval(`func outer(v int) { inner(v) }`)
+	ir.Eval(`func catch(v int) (x interface{}) { defer func() { x = recover() }(); outer(v); return }`)
+
+	// debuggers without a Panic method are accepted, and panics unwind as usual
+	ir.SetDebugger(plainDebugger{})
+	isEvalPanic(t, ir, `outer(1)`)
+	isEval(t, ir, `fmt.Sprint(catch(2))`, "2")
+
+	// the debugger is entered once per panic, not once per unwound function
+	d := &panicDebugger{}
+	ir.SetDebugger(d)
+	isEvalPanic(t, ir, `outer(3)`)
+	isEval(t, ir, `fmt.Sprint(catch(4))`, "4")
+	if !r.DeepEqual(d.panics, []interface{}{3, 4}) {
+		t.Errorf("expecting debugger entered for panics [3 4], found %v", d.panics)
+	}
+
+	// DebugOp.Panic replaces the panic value
+	ir.SetDebugger(&replaceDebugger{with: "replaced"})
+	isEval(t, ir, `fmt.Sprint(catch(5))`, "replaced")
+}
+
+func TestUntypedConstants(t *testing.T) {
+	ir := New()
+	ir.Eval(`var s uint = 8`)
+	ir.Eval(`func f16(x uint16) uint16 { return x }`)
+	ir.Eval(`func r8() uint8 { return 1 << s }`)
+
+	// exact arithmetic on untyped constants
+	isEval(t, ir, `1 << 100 >> 98`, 4)
+	isEval(t, ir, `2.0 << 70 >> 69`, 4)
+	isEval(t, ir, `1.0 << 3`, 8)
+	isEval(t, ir, `const big = 1 << 100; big >> 98`, 4)
+	isEval(t, ir, `^uint8(1)`, uint8(254))
+
+	// non-constant shifts of untyped constants take their type from the context
+	isEval(t, ir, `var u8 uint8 = 1 << s; u8`, uint8(0))
+	isEval(t, ir, `var u64 uint64 = 1 << 63 >> s; u64`, uint64(1)<<55)
+	isEval(t, ir, `var i64 int64 = 1 << s; i64`, int64(256))
+	isEval(t, ir, `x := 1 << s; x`, 256)
+	isEval(t, ir, `u8 + 1<<s`, uint8(0))
+	isEval(t, ir, `uint8(1 << s)`, uint8(0))
+	isEval(t, ir, `f16(1<<s + 1<<s)`, uint16(512))
+	isEval(t, ir, `r8()`, uint8(0))
+	isEval(t, ir, `i64 = 1 << s; i64`, int64(256))
+
+	// overflow and truncation are compile-time errors
+	isEvalPanic(t, ir, `int8(100) + 100`)
+	isEvalPanic(t, ir, `-uint8(1)`)
+	isEvalPanic(t, ir, `int8(1) << 10`)
+	isEvalPanic(t, ir, `float32(1e100)`)
+	isEvalPanic(t, ir, `uint(1.5)`)
+	isEvalPanic(t, ir, `var i8 int8 = 1.5`)
+	isEvalPanic(t, ir, `1.5 << 3`)
+	isEvalPanic(t, ir, `1 << -1`)
+	isEvalPanic(t, ir, `var f64 float64 = 1 << s`)
+	isEvalPanic(t, ir, `1 / 0`)
+	isEvalPanic(t, ir, `int32(1) / 0`)
+
+	// typed constants of named types
+	ir.Eval(`type I int; type Celsius float64; type Weekday int`)
+	isEval(t, ir, `const i1 I = 7; i1`, 7)
+	isEval(t, ir, `const Boiling Celsius = 100; Boiling`, 100.0)
+	isEval(t, ir, `const ( Sunday Weekday = iota; Monday; Tuesday ); Tuesday`, 2)
+	isEval(t, ir, `var vi I = 3; vi`, 3)
+	isEvalPanic(t, ir, `const big I = 1 << 70`)
+
+	// overflow errors report the position of the constant expression
+	ir.Eval(`const c8 int8 = 100`)
+	for _, test := range []struct{ src, pos string }{
+		{`var o int8 = 127 + 1`, "repl.go:1:14: constant 128 overflows <int8>"},
+		{`c8 * 2`, "repl.go:1:1: constant 200 overflows <int8>: c8 * 2"},
+		{`x8 := -int8(-128)`, "repl.go:1:7: constant 128 overflows <int8>: -int8(-128)"},
+	} {
+		_, _, err := ir.EvalErr(test.src)
+		if err == nil || !strings.HasPrefix(err.Error(), test.pos) {
+			t.Errorf("%s: expecting error %q, found %v", test.src, test.pos, err)
+		}
+	}
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	ir := New()
+	ir.Eval(`import ("encoding/json"; "fmt")`)
+	ir.Eval(`type List struct { V int; Next *List }`)
+	ir.Eval(`type Tree struct { Name string; Kids []Tree; ByName map[string]*Tree }`)
+	ir.Eval(`type Pair struct { A int; B string; c bool }`)
+
+	// self-referencing types are real reflect types
+	isEval(t, ir, `l := &List{1, &List{2, nil}}; l.Next.V`, 2)
+	isEval(t, ir, `b, _ := json.Marshal(List{1, &List{V: 2}}); string(b)`, `{"V":1,"Next":{"V":2,"Next":null}}`)
+	isEval(t, ir, `tr := Tree{Name: "a", Kids: []Tree{{Name: "b"}}}; tr.Kids[0].Name`, "b")
+	isEval(t, ir, `tr.ByName = map[string]*Tree{"b": &tr.Kids[0]}; tr.ByName["b"].Name`, "b")
+
+	// their reflect.Type string shows the type name in place of the self-reference
+	isEval(t, ir, `import "reflect"; reflect.TypeOf(List{}).String()`, "struct { V int; Next *main.List }")
+	isEval(t, ir, `reflect.TypeOf(&Tree{}).String()`, "*struct { Name string; Kids []main.Tree; ByName map[string]*main.Tree }")
+	isEval(t, ir, `reflect.TypeOf(List{}).Field(1).Type.Elem() == reflect.TypeOf(List{})`, true)
+
+	// unexported fields are hidden from encoding/json
+	isEval(t, ir, `b2, _ := json.Marshal(Pair{1, "x", true}); string(b2)`, `{"A":1,"B":"x"}`)
+
+	// fmt shows the names of interpreted types
+	isEval(t, ir, `fmt.Sprintf("%T %v %+v", Pair{}, Pair{1, "x", true}, Pair{1, "x", true})`,
+		`main.Pair {1 x true} {A:1 B:x c:true}`)
+	isEval(t, ir, `fmt.Sprintf("%#v", Pair{1, "x", true})`, `main.Pair{A:1, B:"x", c:true}`)
+	isEval(t, ir, `fmt.Sprintf("%[2]T %[1]d", 7, &List{})`, `*main.List 7`)
+	isEval(t, ir, `fmt.Sprint(Pair{2, "y", false})`, `{2 y false}`)
+
+	// documented limit: only arguments whose static type is the named type are wrapped
+	for _, src := range []string{
+		`var ip interface{} = Pair{}; fmt.Sprintf("%T", ip)`,
+		`fmt.Sprintf("%T", []Pair{})`,
+		`fmt.Sprintf("%T", map[string]Pair{})`,
+	} {
+		if v, _ := ir.Eval1(src); !strings.Contains(v.String(), "struct {") {
+			t.Errorf("%s: expecting unnamed struct type, found %v", src, v)
+		}
+	}
+}
+
+func TestInterfaceProxies(t *testing.T) {
+	ir := New()
+	ir.Eval(`import ("encoding/json"; "fmt"; "io"; "io/ioutil"; "net/http"; "net/http/httptest"; "sort")`)
+	ir.Eval(`type C float64; func (c C) String() string { return fmt.Sprintf("%.1fC", float64(c)) }`)
+	ir.Eval(`type E struct { msg string }; func (e *E) Error() string { return "E:" + e.msg }`)
+	ir.Eval(`type Both int; func (Both) Error() string { return "err" }; func (Both) String() string { return "str" }`)
+	ir.Eval(`type J struct { A int }; func (j J) MarshalJSON() ([]byte, error) { return []byte("42"), nil }`)
+	ir.Eval(`type U struct { N int }; func (u *U) UnmarshalJSON(b []byte) error { u.N = len(b); return nil }`)
+	ir.Eval(`type Ints []int; func (x Ints) Len() int { return len(x) }; func (x Ints) Less(i, j int) bool { return x[i] < x[j] }; func (x Ints) Swap(i, j int) { x[i], x[j] = x[j], x[i] }`)
+	ir.Eval(`type R struct { n int }; func (r *R) Read(p []byte) (int, error) { if r.n == 0 { return 0, io.EOF }; r.n--; p[0] = 'a'; return 1, nil }`)
+	ir.Eval(`type H struct { n int }; func (h *H) ServeHTTP(w http.ResponseWriter, req *http.Request) { h.n++; fmt.Fprint(w, "hi ", h.n) }`)
+
+	// methods are visible to compiled functions accepting interface{}
+	isEval(t, ir, `fmt.Sprint(C(1.5))`, "1.5C")
+	isEval(t, ir, `fmt.Sprintf("%v %s %5.1f %T", C(1), C(2), C(3), C(4))`, "1.0C 2.0C   3.0 main.C")
+	isEval(t, ir, `fmt.Sprint(&E{"x"})`, "E:x")
+	isEval(t, ir, `fmt.Sprint(Both(0))`, "err")
+	isEval(t, ir, `b, _ := json.Marshal(J{1}); string(b)`, "42")
+	isEval(t, ir, `var u U; json.Unmarshal([]byte("[1,2]"), &u); u.N`, 5)
+
+	// typed constants and values converted to compiled interfaces
+	isEval(t, ir, `var s fmt.Stringer = C(2); s.String()`, "2.0C")
+	isEval(t, ir, `s = C(3); fmt.Sprint(s)`, "3.0C")
+	isEval(t, ir, `func stringer() fmt.Stringer { return C(4) }; stringer().String()`, "4.0C")
+	isEval(t, ir, `fmt.Sprint([]fmt.Stringer{C(5), C(6)})`, "[5.0C 6.0C]")
+	isEval(t, ir, `func fail() error { return &E{"y"} }; fmt.Sprint(fail())`, "E:y")
+	isEval(t, ir, `fail().(*E).msg`, "y")
+	isEval(t, ir, `v := Ints{3, 1, 2}; sort.Sort(v); fmt.Sprint(v)`, "[1 2 3]")
+	isEval(t, ir, `d, _ := ioutil.ReadAll(&R{3}); string(d)`, "aaa")
+	isEval(t, ir, `mux := http.NewServeMux(); mux.Handle("/", &H{}); rec := httptest.NewRecorder();
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil)); rec.Body.String()`, "hi 1")
+}
+
+func TestInterfaceProxiesNotWrapped(t *testing.T) {
+	ir := New()
+	ir.Eval(`import ("encoding/json"; "fmt"; "reflect")`)
+	ir.Eval(`type Temp float64; func (t Temp) String() string { return fmt.Sprintf("%.1f deg", float64(t)) }`)
+	ir.Eval(`type E struct { Code int }; func (e E) Error() string { return fmt.Sprint("code ", e.Code) }`)
+
+	// reflect sees the value itself, not a proxy
+	isEval(t, ir, `reflect.ValueOf(Temp(2.5)).Kind() == reflect.Float64`, true)
+	isEval(t, ir, `reflect.ValueOf(Temp(2.5)).Float()`, 2.5)
+	isEval(t, ir, `reflect.TypeOf(E{}).Kind() == reflect.Struct`, true)
+
+	// encoding/json ignores String() and Error(), as in compiled code
+	isEval(t, ir, `b1, _ := json.Marshal(E{1}); string(b1)`, `{"Code":1}`)
+	isEval(t, ir, `b2, _ := json.Marshal(Temp(1.5)); string(b2)`, `1.5`)
+	isEval(t, ir, `var tt Temp; json.Unmarshal([]byte("3.5"), &tt); float64(tt)`, 3.5)
+	isEval(t, ir, `var e E; json.Unmarshal(b1, &e); e.Code`, 1)
+
+	// fmt still sees the methods
+	isEval(t, ir, `fmt.Sprint(Temp(1.5), E{2})`, "1.5 deg code 2")
+}
+
+func TestOutOfOrder(t *testing.T) {
+	ir := New()
+	isEval(t, ir, `var a = b; var b = 42; a`, 42)
+	isEval(t, ir, `func even(n int) bool { return n == 0 || odd(n-1) }; func odd(n int) bool { return n != 0 && even(n-1) }; even(10)`, true)
+	isEval(t, ir, `var m = map[string]int{key: 1}; const key = "k"; m["k"]`, 1)
+	isEval(t, ir, `var arr = [...]string{idx: "x"}; const idx = 2; len(arr)`, 3)
+	isEval(t, ir, `func (p Pair) Sum() int { return p.A + p.B }; type Pair struct { A, B int }; Pair{1, 2}.Sum()`, 3)
+	isEval(t, ir, `var x1 = f1(); func f1() int { return y1 * 2 }; var y1 = 21; x1`, 42)
+	// initialization cycles are errors, as in Go
+	isEvalPanic(t, ir, `var z1 = g1(); func g1() int { return z1 }`)
+}
+
+func TestBatchMode(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": `// package demo
+package demo
+
+import "fmt"
+
+var total = sum(values)
+
+var msg = fmt.Sprint("total ", total)
+
+var label = name + "!"
+
+func (l List) Len() int {
+	if l.Rest == nil {
+		return 1
+	}
+	return 1 + l.Rest.Len()
+}
+
+func init() { order = append(order, "a") }
+`,
+		"b.go": `package demo
+
+import "fmt"
+
+type List struct {
+	First int
+	Rest  *List
+}
+
+var values = []int{first, second, 3}
+
+const first, second = 1, 2
+
+type Weekday int
+
+const (
+	Sunday Weekday = iota
+	Monday
+)
+
+var order []string
+
+var count, name = split()
+
+func split() (int, string) { return len(values), "demo" }
+
+func init() { order = append(order, fmt.Sprint("b", len(values))) }
+
+func sum(xs []int) int {
+	n := 0
+	for _, x := range xs {
+		n += x
+	}
+	return n
+}
+`,
+		"c_test.go":  "package demo\nthis is not Go code\n",
+		"d_other.go": "// +build ignore\n\npackage main\nthis is not Go code\n",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ir := New()
+	comments, err := ir.EvalDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comments != "// package demo\n" {
+		t.Errorf("EvalDir: expecting comments %q, found %q", "// package demo\n", comments)
+	}
+	isEval(t, ir, `msg`, "total 6")
+	isEval(t, ir, `order`, []string{"a", "b3"})
+	isEval(t, ir, `List{1, &List{2, nil}}.Len()`, 2)
+	isEval(t, ir, `label`, "demo!")
+	isEval(t, ir, `count`, 3)
+	isEval(t, ir, `int(Monday)`, 1)
+
+	file := filepath.Join(dir, "e.go")
+	if err := ioutil.WriteFile(file, []byte("package other\nvar e = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ir.EvalFiles(filepath.Join(dir, "a.go"), file); err == nil {
+		t.Errorf("EvalFiles with different packages: expecting an error, found nil")
+	}
+}
+
+func TestTypeCheck(t *testing.T) {
+	ir := New()
+	ir.Comp.Options |= OptTypeCheck
+
+	// programs accepted by Comp but rejected by Go compilers
+	for _, item := range []struct{ src, msg string }{
+		{`type List2 struct { First int; Rest List2 }`, "invalid recursive type"},
+		{`func unused() { y := 2 }`, "declared and not used: y"},
+		{`var s []int = 1`, "cannot use 1"},
+		{`macro m(x interface{}) interface{} { return x }`, "macro declaration is not valid Go"},
+	} {
+		_, _, err := ir.EvalErr(item.src)
+		var terr *TypeCheckError
+		if !errors.As(err, &terr) || !strings.Contains(err.Error(), item.msg) {
+			t.Errorf("%s: expecting TypeCheckError %q, found: %v", item.src, item.msg, err)
+		}
+	}
+	// previous declarations, imports and REPL variables are visible to later inputs
+	ir.Eval(`import "fmt"`)
+	ir.Eval(`type T struct{ A int }`)
+	ir.Eval(`func (t T) Get() int { return t.A }`)
+	ir.Eval(`z := 7`)
+	isEval(t, ir, `fmt.Sprint(T{5}.Get() * z)`, "35")
+	isEval(t, ir, `var arr [3]int; len(arr)`, 3)
+
+	// generics declared by previous inputs
+	ir.Eval(`type Pair[A, B any] struct { a A; b B }`)
+	ir.Eval(`func Sum[T int | float64](xs ...T) T { var s T; for _, x := range xs { s += x }; return s }`)
+	isEval(t, ir, `Pair[int, string]{1, "x"}.b`, "x")
+	isEval(t, ir, `Sum(1.5, 2)`, 3.5)
+	if _, _, err := ir.EvalErr(`Sum("a")`); err == nil || !strings.Contains(err.Error(), "does not satisfy") {
+		t.Errorf(`Sum("a"): expecting error "does not satisfy", found: %v`, err)
+	}
+
+	// files are checked as Go compilers do, including unused imports
+	file := filepath.Join(t.TempDir(), "main.go")
+	src := "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfmt.Println()\n}\n"
+	if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ir.EvalFiles(file); err == nil || !strings.Contains(err.Error(), `main.go:5:2: "os" imported and not used`) {
+		t.Errorf("EvalFiles: expecting unused import error, found: %v", err)
+	}
+}
+
+// decode a protobuf varint at the start of buf. returns the remaining bytes
+func protoVarint(t *testing.T, buf []byte) (uint64, []byte) {
+	t.Helper()
+	var x uint64
+	for i, shift := 0, uint(0); i < len(buf); i, shift = i+1, shift+7 {
+		x |= uint64(buf[i]&0x7f) << shift
+		if buf[i] < 0x80 {
+			return x, buf[i+1:]
+		}
+	}
+	t.Fatal("truncated varint")
+	return 0, nil
+}
+
+// decode the protobuf message buf into its fields: varints as uint64, length-delimited fields as []byte
+func decodeProto(t *testing.T, buf []byte) map[int][]interface{} {
+	t.Helper()
+	fields := make(map[int][]interface{})
+	for len(buf) != 0 {
+		var key, x uint64
+		key, buf = protoVarint(t, buf)
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			x, buf = protoVarint(t, buf)
+			fields[field] = append(fields[field], x)
+		case 2:
+			x, buf = protoVarint(t, buf)
+			if x > uint64(len(buf)) {
+				t.Fatalf("truncated field %d", field)
+			}
+			fields[field] = append(fields[field], buf[:x])
+			buf = buf[x:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func TestCPUProfile(t *testing.T) {
+	ir := New()
+	// function names are known only to the debugger
+	ir.Comp.Globals.Options |= OptDebugger
+	ir.Eval(`func spin(n int) int { s := 0; for i := 0; i < n; i++ { s += i }; return s }`)
+	var buf bytes.Buffer
+	if err := ir.StartCPUProfile(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for start := time.Now(); time.Since(start) < 200*time.Millisecond; {
+		ir.Eval(`spin(10000)`)
+	}
+	if err := ir.StopCPUProfile(); err != nil {
+		t.Fatal(err)
+	}
+	zin, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prof := decodeProto(t, data)
+
+	// Profile.string_table
+	var strs []string
+	for _, s := range prof[6] {
+		strs = append(strs, string(s.([]byte)))
+	}
+	if len(strs) == 0 || strs[0] != "" || !strings.Contains(strings.Join(strs, " "), "main.spin") {
+		t.Errorf("expecting string table containing main.spin, found %q", strs)
+	}
+	// Profile.sample: count the samples from their values
+	if len(prof[2]) == 0 {
+		t.Fatal("expecting some samples, found none")
+	}
+	var count uint64
+	for _, s := range prof[2] {
+		sample := decodeProto(t, s.([]byte))
+		values := sample[2]
+		if len(values) == 0 || len(sample[1]) == 0 {
+			t.Fatalf("sample without locations or values: %v", sample)
+		}
+		// packed repeated int64: the first value is the number of samples
+		n, _ := protoVarint(t, values[0].([]byte))
+		count += n
+	}
+	if count < 5 {
+		t.Errorf("expecting at least 5 samples in 200ms, found %d", count)
+	}
+	// Profile.period
+	if len(prof[12]) != 1 || prof[12][0].(uint64) != uint64(ProfilePeriod) {
+		t.Errorf("expecting period %d, found %v", ProfilePeriod, prof[12])
+	}
+}
+
+// parse a cover profile written by Interp.WriteCoverProfile into "line.col" -> count
+func parseCoverProfile(t *testing.T, profile string, mode string) map[string]int {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(profile), "\n")
+	if len(lines) == 0 || lines[0] != "mode: "+mode {
+		t.Fatalf("expecting cover profile with mode %s, found %q", mode, profile)
+	}
+	counts := make(map[string]int)
+	for _, line := range lines[1:] {
+		// FILE:LINE.COL,LINE.COL NUMSTMT COUNT
+		var start, end string
+		var nstmt, count int
+		colon := strings.LastIndexByte(line, ':')
+		if _, err := fmt.Sscanf(strings.Replace(line[colon+1:], ",", " ", 1), "%s %s %d %d", &start, &end, &nstmt, &count); err != nil {
+			t.Fatalf("invalid cover profile line %q: %v", line, err)
+		}
+		counts[start] = count
+	}
+	return counts
+}
+
+func TestCoverage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cover.go")
+	src := `package main
+
+func count(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	if s < 0 {
+		return -1
+	}
+	return s
+}
+`
+	if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []string{"count", "set"} {
+		ir := New()
+		if err := ir.StartCoverage(mode); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ir.EvalFile(file); err != nil {
+			t.Fatal(err)
+		}
+		isEval(t, ir, "count(5)", 10)
+		isEval(t, ir, "count(2)", 1)
+		var buf bytes.Buffer
+		if err := ir.WriteCoverProfile(&buf); err != nil {
+			t.Fatal(err)
+		}
+		counts := parseCoverProfile(t, buf.String(), mode)
+		expected := map[string]int{
+			"4.2":  2, // s := 0
+			"6.3":  7, // s += i
+			"8.5":  2, // s < 0
+			"9.10": 0, // return -1
+			"11.9": 2, // return s
+		}
+		for pos, n := range expected {
+			if mode == "set" && n > 1 {
+				n = 1
+			}
+			if count, ok := counts[pos]; !ok || count != n {
+				t.Errorf("mode %s: expecting count %d at %s, found %d (present: %v)", mode, n, pos, count, ok)
+			}
+		}
+	}
+}
+
+func TestCoverPath(t *testing.T) {
+	wd := filepath.FromSlash("/home/user/project")
+	tests := map[string]string{
+		"/home/user/project/main.go":     "./main.go",
+		"/home/user/project/pkg/util.go": "./pkg/util.go",
+		"/home/user/other/main.go":       filepath.FromSlash("/home/user/other/main.go"),
+		"/home/user/project..go":         filepath.FromSlash("/home/user/project..go"),
+	}
+	for file, expected := range tests {
+		if path := coverPath(filepath.FromSlash(file), wd); path != expected {
+			t.Errorf("coverPath(%q, %q): expecting %q, found %q", file, wd, expected, path)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	ir := New()
+	var buf bytes.Buffer
+	ir.Comp.Globals.Stderr = &buf
+	// only functions compiled with the debugger are traced
+	ir.Comp.Globals.Options |= OptDebugger
+	ir.Eval(`func fib(n int) int { if n < 2 { return n }; return fib(n-1) + fib(n-2) }`)
+	ir.Eval(`type T struct{}; func (T) Name() string { return "t" }; func (*T) Ptr() {}; func other() {}`)
+	ir.Trace("fib", "T.*", "(*T).*")
+	ir.Eval(`fib(2); T{}.Name(); new(T).Ptr(); other()`)
+
+	// strip the timestamps
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			line = line[i+1:]
+		}
+		lines = append(lines, line)
+	}
+	expected := []string{
+		"-> fib(n=2)",
+		"  -> fib(n=1)",
+		"  <- fib = 1",
+		"  -> fib(n=0)",
+		"  <- fib = 0",
+		"<- fib = 1",
+		"-> T.Name({})",
+		"<- T.Name = t",
+		"-> (*T).Ptr(&{})",
+		"<- (*T).Ptr",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected trace output:\n%s\nexpecting:\n%s", strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+
+	buf.Reset()
+	ir.Untrace("fib")
+	ir.Eval(`fib(3)`)
+	if buf.Len() != 0 {
+		t.Errorf("expecting no trace output after Untrace, found %q", buf.String())
+	}
+}
+
+func TestInspectorSet(t *testing.T) {
+	ir := New()
+	var buf bytes.Buffer
+	ir.Comp.Globals.Stdout = &buf
+	ir.Eval(`import "time"`)
+	ir.Eval(`type P struct { x int; Y string; m map[string]int; t time.Time }`)
+	ir.Eval(`var p = &P{1, "a", map[string]int{"k": 1}, time.Time{}}`)
+	val, xtyp := ir.Eval1("p")
+
+	ip := &inspect.Inspector{}
+	ip.SetEval(ir.inspectEval)
+	ip.Init("p", val, val.Type(), xtyp, &ir.Comp.Globals)
+
+	cmds := []struct {
+		cmd, out string
+	}{
+		{"set x = 5", "x\t= 5\t// int\n"},
+		{`set Y = "b"`, "Y\t= b\t// string\n"},
+		{"2", ""},
+		{`set ["k"] = 7`, "[\"k\"]\t= 7\t// int\n"},
+		{`set ["z"] = 8`, "[\"z\"]\t= 8\t// int\n"},
+		{"up", ""},
+		{"3", ""},
+		{"set wall = 3", "set: cannot set wall: unexported field of compiled type <time.Time>\n"},
+	}
+	for _, c := range cmds {
+		buf.Reset()
+		ip.Eval(c.cmd)
+		if len(c.out) != 0 && buf.String() != c.out {
+			t.Errorf("inspector command %q: expecting output %q, found %q", c.cmd, c.out, buf.String())
+		}
+	}
+	isEval(t, ir, "p.x", 5)
+	isEval(t, ir, "p.Y", "b")
+	isEval(t, ir, `p.m["k"]`, 7)
+	isEval(t, ir, `p.m["z"]`, 8)
+	isEval(t, ir, "len(p.m)", 2)
+}
+
+func TestInspectorCollections(t *testing.T) {
+	ir := New()
+	var buf bytes.Buffer
+	ir.Comp.Globals.Stdout = &buf
+	ir.Eval(`var m = map[string][]int{"a": make([]int, 30)}`)
+	ir.Eval(`var c = make(chan int, 3); c <- 1`)
+
+	val, xtyp := ir.Eval1("m")
+	ip := &inspect.Inspector{}
+	ip.SetEval(ir.inspectEval)
+	ip.Init("m", val, val.Type(), xtyp, &ir.Comp.Globals)
+
+	cmds := []struct {
+		cmd, out string
+	}{
+		{`["a"]`, "// showing elements 0...19 of 30"},
+		{"next", "// showing elements 20...29 of 30"},
+		{"set [25] = 7", "[25]\t= 7\t// int\n"},
+		{"set [30] = 1", "contains 30 elements, cannot inspect element 30"},
+	}
+	for _, c := range cmds {
+		buf.Reset()
+		ip.Eval(c.cmd)
+		if !strings.Contains(buf.String(), c.out) {
+			t.Errorf("inspector command %q: expecting output to contain %q, found %q", c.cmd, c.out, buf.String())
+		}
+	}
+	isEval(t, ir, `m["a"][25]`, 7)
+
+	buf.Reset()
+	val, xtyp = ir.Eval1("c")
+	ip.Init("c", val, val.Type(), xtyp, &ir.Comp.Globals)
+	ip.Show()
+	if out := buf.String(); !strings.Contains(out, "len = 1, cap = 3, direction = chan") {
+		t.Errorf("inspecting channel: unexpected output %q", out)
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	ir := New()
+	g := &ir.Comp.Globals
+	ir.Eval(`type P struct { x int; s []int; str string }`)
+	ir.Eval(`type L struct { Next *L }`)
+	ir.Eval(`var p = P{1, []int{1, 2, 3, 4, 5}, "hello, world"}`)
+	ir.Eval(`var l = &L{}; l.Next = l`)
+	format := func(src string) string {
+		v, xt := ir.Eval1(src)
+		return g.Sprintf("%v", g.TypedValue(v, xt))
+	}
+	if v, _ := ir.Eval1("p"); format("p") != g.Sprintf("%v", v) {
+		t.Errorf("default print options: expecting plain %%v output %q, found %q", g.Sprintf("%v", v), format("p"))
+	}
+	// cycles are detected also at default print options
+	ir.Eval(`m := map[string]interface{}{}; m["self"] = m`)
+	if s, expected := format("m"), "map[self:<cycle "; !strings.HasPrefix(s, expected) {
+		t.Errorf("default print options: expecting prefix %q, found %q", expected, s)
+	}
+	if s, expected := format("l"), "&main.L{Next:<cycle "; !strings.HasPrefix(s, expected) {
+		t.Errorf("default print options: expecting prefix %q, found %q", expected, s)
+	}
+	var buf bytes.Buffer
+	g.Stdout = &buf
+	g.Options |= OptShowEval
+	g.Print(ir.Eval("m"))
+	if s := buf.String(); !strings.Contains(s, "<cycle ") {
+		t.Errorf("default print options: expecting REPL output to contain a cycle, found %q", s)
+	}
+	for _, opt := range []string{"MaxElems=3", "MaxStringLen=5"} {
+		if err := g.PrintOptions.Set(opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, expected := format("p"), "main.P{x:1 s:[1 2 3 ...(2 more)] str:hello...(7 more bytes)}"; s != expected {
+		t.Errorf("expecting %q, found %q", expected, s)
+	}
+	if s, expected := format("l"), "&main.L{Next:<cycle "; !strings.HasPrefix(s, expected) {
+		t.Errorf("expecting prefix %q, found %q", expected, s)
+	}
+	g.PrintOptions = output.PrintOptions{MaxDepth: 1}
+	if s, expected := format("[][]int{{1}}"), "[[...]]"; s != expected {
+		t.Errorf("expecting %q, found %q", expected, s)
+	}
+	g.PrintOptions = output.PrintOptions{MultiLine: true}
+	if s, expected := format("[]int{1, 2}"), "[\n    1\n    2\n]"; s != expected {
+		t.Errorf("expecting %q, found %q", expected, s)
+	}
+}
+
+func TestRender(t *testing.T) {
+	ir := New()
+	ir.Eval(`import ("image"; "time")`)
+	ir.Eval(`type T struct { X int }`)
+
+	bundle := ir.EvalRender(`3`)[0]
+	if string(bundle[MIMEText]) != "3" || len(bundle) != 1 {
+		t.Errorf("rendering 3: unexpected bundle %q", bundle)
+	}
+	bundle = ir.EvalRender(`[]int{1, 2}`)[0]
+	if string(bundle[MIMEText]) != "[1 2]" || string(bundle[MIMEJSON]) != "[1,2]" {
+		t.Errorf("rendering []int{1, 2}: unexpected bundle %q", bundle)
+	}
+	bundle = ir.EvalRender(`image.NewGray(image.Rect(0, 0, 2, 2))`)[0]
+	if !bytes.HasPrefix(bundle[MIMEPNG], []byte("\x89PNG")) {
+		t.Errorf("rendering image.Image: expecting %s, found bundle %q", MIMEPNG, bundle)
+	}
+
+	// renderers registered for a type take precedence over interfaces and defaults
+	_, tt := ir.Eval1(`T{}`)
+	ir.RegisterRenderer(tt, MIMEHTML, func(v r.Value, t xr.Type) ([]byte, bool) {
+		return []byte(fmt.Sprintf("<b>%d</b>", v.Field(0).Int())), true
+	})
+	tstringer := ir.TypeOf((*fmt.Stringer)(nil)).Elem()
+	ir.RegisterRenderer(tstringer, MIMEText, func(v r.Value, t xr.Type) ([]byte, bool) {
+		return []byte("stringer"), true
+	})
+	bundle = ir.EvalRender(`T{7}`)[0]
+	if string(bundle[MIMEHTML]) != "<b>7</b>" || string(bundle[MIMEJSON]) != `{"X":7}` {
+		t.Errorf("rendering T{7}: unexpected bundle %q", bundle)
+	}
+	bundle = ir.EvalRender(`time.Duration(1)`)[0]
+	if string(bundle[MIMEText]) != "stringer" {
+		t.Errorf("rendering fmt.Stringer: unexpected bundle %q", bundle)
+	}
+	ir.RegisterRenderer(tstringer, MIMEText, nil)
+	bundle = ir.EvalRender(`time.Duration(1)`)[0]
+	if string(bundle[MIMEText]) != "1ns" {
+		t.Errorf("rendering fmt.Stringer after removing renderer: unexpected bundle %q", bundle)
+	}
+
+	data, err := bundle.MarshalJSON()
+	if err != nil || string(data) != `{"text/plain":"1ns"}` {
+		t.Errorf("MIMEBundle.MarshalJSON: unexpected result %s, %v", data, err)
+	}
+
+	// the hook for servers returns errors instead of panicking
+	bundles, err := ir.EvalRenderContext(context.Background(), `T{8}`)
+	if err != nil || len(bundles) != 1 || string(bundles[0][MIMEHTML]) != "<b>8</b>" {
+		t.Errorf("EvalRenderContext: unexpected result %q, %v", bundles, err)
+	}
+	var perr *PanicError
+	if _, err = ir.EvalRenderContext(context.Background(), `panic("x")`); !errors.As(err, &perr) {
+		t.Errorf("EvalRenderContext: expecting *PanicError, found %v <%T>", err, err)
+	}
+	ir.RegisterRenderer(tt, MIMEHTML, func(v r.Value, t xr.Type) ([]byte, bool) {
+		panic("broken renderer")
+	})
+	if _, err = ir.EvalRenderContext(context.Background(), `T{9}`); err == nil || err.Error() != "broken renderer" {
+		t.Errorf("EvalRenderContext: expecting error from renderer, found %v", err)
+	}
+}
+
+func TestSetStdio(t *testing.T) {
+	var out1, out2, err1 bytes.Buffer
+	ir1, ir2 := New(), New()
+	ir1.SetStdio(strings.NewReader("42"), &out1, &err1)
+	ir2.SetStdio(nil, &out2, nil)
+	for _, ir := range []*Interp{ir1, ir2} {
+		ir.Eval(`import ("fmt"; "log"; "os")`)
+	}
+	ir1.Eval(`fmt.Println("one"); println("builtin")`)
+	ir2.Eval(`fmt.Printf("%s\n", "two")`)
+	if out1.String() != "one\n" || out2.String() != "two\n" {
+		t.Errorf("fmt.Print*: expecting separate outputs %q and %q, found %q and %q", "one\n", "two\n", out1.String(), out2.String())
+	}
+	ir1.Eval(`log.Println("logged")`)
+	if s := err1.String(); !strings.HasPrefix(s, "builtin\n") || !strings.HasSuffix(s, "logged\n") {
+		t.Errorf("println and log.Println: unexpected stderr %q", s)
+	}
+	ir1.Eval(`var n int; fmt.Scan(&n)`)
+	isEval(t, ir1, "n", 42)
+
+	// streams that are not *os.File cannot replace os.Stdout
+	if v, _ := ir1.Eval1(`os.Stdout`); v.Interface() != os.Stdout {
+		t.Errorf("os.Stdout: expecting the process' stdout when SetStdio() receives a bytes.Buffer")
+	}
+	// *os.File streams replace os.Stdout in code compiled afterwards
+	f, err := ioutil.TempFile("", "stdio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	ir2.SetStdio(nil, f, nil)
+	ir2.Eval(`fmt.Fprint(os.Stdout, "file")`)
+	if data, _ := ioutil.ReadFile(f.Name()); string(data) != "file" {
+		t.Errorf("os.Stdout: expecting output %q written to *os.File, found %q", "file", data)
+	}
+}
@@ -712,6 +712,11 @@ func (p *parser) parseArrayType() ast.Expr {
 		len = p.parseRhs()
 	}
 	p.exprLev--
+	return p.parseArrayTypeFrom(lbrack, len)
+}
+
+// patch: parse the remainder of an array type, after its '[' and length
+func (p *parser) parseArrayTypeFrom(lbrack token.Pos, len ast.Expr) ast.Expr {
 	p.expect(token.RBRACK)
 	elt := p.parseType()
 
@@ -744,15 +749,20 @@ func (p *parser) parseFieldDecl(scope *ast.Scope) *ast.Field {
 	// 1st FieldDecl
 	// A type name used as an anonymous field looks like a field identifier.
 	var list []ast.Expr
+	var typ ast.Expr
 	for {
-		list = append(list, p.parseVarType(false))
-		if p.tok != token.COMMA {
+		var x ast.Expr
+		x, typ = p.parseVarTypeOrArrayField(false)
+		list = append(list, x)
+		if typ != nil || p.tok != token.COMMA {
 			break
 		}
 		p.next()
 	}
 
-	typ := p.tryVarType(false)
+	if typ == nil {
+		typ = p.tryVarType(false)
+	}
 
 	// analyze case
 	var idents []*ast.Ident
@@ -862,9 +872,12 @@ func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params [
 	// 1st ParameterDecl
 	// A list of identifiers looks like a list of type names.
 	var list []ast.Expr
+	var typ ast.Expr
 	for {
-		list = append(list, p.parseVarType(ellipsisOk))
-		if p.tok != token.COMMA {
+		var x ast.Expr
+		x, typ = p.parseVarTypeOrArrayField(ellipsisOk)
+		list = append(list, x)
+		if typ != nil || p.tok != token.COMMA {
 			break
 		}
 		p.next()
@@ -874,7 +887,10 @@ func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params [
 	}
 
 	// analyze case
-	if typ := p.tryVarType(ellipsisOk); typ != nil {
+	if typ == nil {
+		typ = p.tryVarType(ellipsisOk)
+	}
+	if typ != nil {
 		// IdentifierList Type
 		idents := p.makeIdentList(list)
 		field := &ast.Field{Names: idents, Type: typ}
@@ -978,6 +994,12 @@ func (p *parser) parseMethodSpec(scope *ast.Scope) *ast.Field {
 	doc := p.leadComment
 	var idents []*ast.Ident
 	var typ ast.Expr
+	if p.tok != token.IDENT {
+		// patch: type set element of a constraint, as ~int | ~string
+		typ = p.parseConstraint()
+		p.expectSemi() // call before accessing p.linecomment
+		return &ast.Field{Doc: doc, Type: typ, Comment: p.lineComment}
+	}
 	x := p.parseTypeName()
 	if ident, isIdent := x.(*ast.Ident); isIdent && p.tok == token.LPAREN {
 		// method
@@ -986,9 +1008,13 @@ func (p *parser) parseMethodSpec(scope *ast.Scope) *ast.Field {
 		params, results := p.parseSignature(scope)
 		typ = &ast.FuncType{Func: token.NoPos, Params: params, Results: results}
 	} else {
-		// embedded interface
+		// embedded interface, or type set element of a constraint
 		typ = x
 		p.resolve(typ)
+		if p.tok == token.LBRACK {
+			typ = p.parseTypeInstance(typ)
+		}
+		typ = p.parseConstraintFrom(typ)
 	}
 	p.expectSemi() // call before accessing p.linecomment
 
@@ -1007,7 +1033,7 @@ func (p *parser) parseInterfaceType() *ast.InterfaceType {
 	lbrace := p.expect(token.LBRACE)
 	scope := ast.NewScope(nil) // interface scope
 	var list []*ast.Field
-	for p.tok == token.IDENT {
+	for p.tok == token.IDENT || p.tok == token.TILDE || p.tok == token.MUL || p.tok == token.LBRACK || p.tok == token.MAP || p.tok == token.CHAN {
 		list = append(list, p.parseMethodSpec(scope))
 	}
 	rbrace := p.expect(token.RBRACE)
@@ -1065,12 +1091,16 @@ func (p *parser) parseChanType() *ast.ChanType {
 func (p *parser) tryIdentOrType() ast.Expr {
 	switch p.tok {
 	case token.IDENT:
-		ident := p.parseTypeName()
-		if p.tok != mt.HASH {
-			return ident
+		typ := p.parseTypeName()
+		switch p.tok {
+		case mt.HASH:
+			// parse Foo#[T1,T2...]
+			return p.parseHash(typ)
+		case token.LBRACK:
+			// parse Foo[T1,T2...]
+			return p.parseTypeInstance(typ)
 		}
-		// parse Foo#[T1,T2...]
-		return p.parseHash(ident)
+		return typ
 	case token.LBRACK:
 		return p.parseArrayType()
 	case token.STRUCT:
@@ -1549,7 +1579,11 @@ func (p *parser) parsePrimaryExpr(lhs bool) ast.Expr {
 		defer un(trace(p, "PrimaryExpr"))
 	}
 
-	x := p.parseOperand(lhs)
+	return p.parsePrimaryExprFrom(p.parseOperand(lhs), lhs)
+}
+
+// patch: parse the remainder of a primary expression, after its operand x
+func (p *parser) parsePrimaryExprFrom(x ast.Expr, lhs bool) ast.Expr {
 L:
 	for {
 		switch p.tok {
@@ -1682,7 +1716,11 @@ func (p *parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
 		defer un(trace(p, "BinaryExpr"))
 	}
 
-	x := p.parseUnaryExpr(lhs)
+	return p.parseBinaryExprFrom(p.parseUnaryExpr(lhs), lhs, prec1)
+}
+
+// patch: parse the remainder of a binary expression, after its first operand x
+func (p *parser) parseBinaryExprFrom(x ast.Expr, lhs bool, prec1 int) ast.Expr {
 	for {
 		op, oprec := p.tokPrec()
 		if oprec < prec1 {
@@ -2437,14 +2475,30 @@ func (p *parser) parseTypeSpec(doc *ast.CommentGroup, _ token.Token, _ int) ast.
 	spec := &ast.TypeSpec{Doc: doc, Name: ident}
 	p.declare(spec, nil, p.topScope, ast.Typ, ident)
 
-	if p.tok == token.ASSIGN {
-		spec.Assign = p.pos
+	if p.tok == token.LBRACK {
+		// patch: either an array type or a generic type
+		lbrack := p.pos
 		p.next()
+		if name, len := p.parseTypeParamOrArrayLen(); name != nil {
+			spec.TypeParams = p.parseTypeParamsFrom(lbrack, name, ast.NewScope(p.topScope))
+		} else {
+			spec.Type = p.parseArrayTypeFrom(lbrack, len)
+		}
+	}
+	if spec.Type == nil {
+		if p.tok == token.ASSIGN {
+			spec.Assign = p.pos
+			p.next()
+		}
+		spec.Type = p.parseType()
 	}
-	spec.Type = p.parseType()
 	p.expectSemi() // call before accessing p.linecomment
 	spec.Comment = p.lineComment
 
+	if spec.TypeParams != nil {
+		templateTypeSpec(typeParamNames(spec.TypeParams), spec)
+	}
+
 	return spec
 }
 
@@ -2519,6 +2573,12 @@ func (p *parser) parseFuncOrMacroDecl(tok token.Token) *ast.FuncDecl {
 
 	ident := p.parseIdent()
 
+	// patch: generic function
+	var tparams *ast.FieldList
+	if tok != mt.MACRO && recv == nil && p.tok == token.LBRACK {
+		tparams = p.parseTypeParams(scope)
+	}
+
 	params, results := p.parseSignature(scope)
 
 	var body *ast.BlockStmt
@@ -2532,12 +2592,16 @@ func (p *parser) parseFuncOrMacroDecl(tok token.Token) *ast.FuncDecl {
 		Recv: recv,
 		Name: ident,
 		Type: &ast.FuncType{
-			Func:    pos,
-			Params:  params,
-			Results: results,
+			Func:       pos,
+			TypeParams: tparams,
+			Params:     params,
+			Results:    results,
 		},
 		Body: body,
 	}
+	if tparams != nil {
+		templateFuncDecl(typeParamNames(tparams), decl)
+	}
 	if recv == nil {
 		// Go spec: The scope of an identifier denoting a constant, type,
 		// variable, or function (but not method) declared at top level
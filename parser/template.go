@@ -98,19 +98,23 @@ func (p *parser) parseTemplateParams() *ast.CompositeLit {
 func templateTypeDecl(params *ast.CompositeLit, decl *ast.GenDecl) *ast.GenDecl {
 	for _, spec := range decl.Specs {
 		if typespec, ok := spec.(*ast.TypeSpec); ok {
-			// hack: store template params in *ast.CompositeLit.
-			// it is never used inside *ast.TypeSpec and has exacly the required fields
-			typespec.Type = &ast.CompositeLit{
-				Type:   typespec.Type,
-				Lbrace: params.Lbrace,
-				Elts:   params.Elts,
-				Rbrace: params.Rbrace,
-			}
+			templateTypeSpec(params, typespec)
 		}
 	}
 	return decl
 }
 
+func templateTypeSpec(params *ast.CompositeLit, spec *ast.TypeSpec) {
+	// hack: store template params in *ast.CompositeLit.
+	// it is never used inside *ast.TypeSpec and has exacly the required fields
+	spec.Type = &ast.CompositeLit{
+		Type:   spec.Type,
+		Lbrace: params.Lbrace,
+		Elts:   params.Elts,
+		Rbrace: params.Rbrace,
+	}
+}
+
 func templateFuncDecl(params *ast.CompositeLit, decl *ast.FuncDecl) *ast.FuncDecl {
 	// hack: store template types as second function receiver.
 	// it's never used for functions and macros.
@@ -130,3 +134,207 @@ func templateFuncDecl(params *ast.CompositeLit, decl *ast.FuncDecl) *ast.FuncDec
 	recv.List = list
 	return decl
 }
+
+// ---------------------------- generics -------------------------------------
+//
+// Go generics are lowered onto templates:
+// func F[T any, U comparable](...) is parsed as template[T, U] func F(...)
+// and type List[T any] ... is parsed as template[T] type List ...
+// The type parameters are also stored in ast.FuncType.TypeParams
+// and ast.TypeSpec.TypeParams, for constraint checking.
+
+// parse the type parameters [T1, T2 C1, T3 C2...] of a generic function
+func (p *parser) parseTypeParams(scope *ast.Scope) *ast.FieldList {
+	if p.trace {
+		defer un(trace(p, "TypeParams"))
+	}
+	lbrack := p.expect(token.LBRACK)
+	return p.parseTypeParamsFrom(lbrack, p.parseIdent(), scope)
+}
+
+// parse the remainder of the type parameters of a generic function or type,
+// after the '[' and the first parameter name
+func (p *parser) parseTypeParamsFrom(lbrack token.Pos, name *ast.Ident, scope *ast.Scope) *ast.FieldList {
+	var list []*ast.Field
+	idents := []*ast.Ident{name}
+	for p.tok != token.RBRACK && p.tok != token.EOF {
+		if p.tok == token.COMMA {
+			p.next()
+			if p.tok == token.RBRACK {
+				break
+			}
+			idents = append(idents, p.parseIdent())
+			continue
+		}
+		// constraint of the parameters collected so far
+		field := &ast.Field{Names: idents, Type: p.parseConstraint()}
+		p.declare(field, nil, scope, ast.Typ, idents...)
+		list = append(list, field)
+		idents = nil
+		if p.tok != token.COMMA {
+			break
+		}
+	}
+	if len(idents) != 0 {
+		p.errorExpected(p.pos, "type constraint")
+		list = append(list, &ast.Field{Names: idents, Type: &ast.BadExpr{From: p.pos, To: p.pos}})
+	}
+	rbrack := p.expect(token.RBRACK)
+	return &ast.FieldList{Opening: lbrack, List: list, Closing: rbrack}
+}
+
+// parse a type constraint: a type, an approximation element ~T,
+// or a union of them as int | ~string
+func (p *parser) parseConstraint() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "Constraint"))
+	}
+	return p.parseConstraintFrom(p.parseConstraintTerm())
+}
+
+// parse the remainder of a union, after its first term x
+func (p *parser) parseConstraintFrom(x ast.Expr) ast.Expr {
+	for p.tok == token.OR {
+		pos := p.pos
+		p.next()
+		y := p.parseConstraintTerm()
+		x = &ast.BinaryExpr{X: x, OpPos: pos, Op: token.OR, Y: y}
+	}
+	return x
+}
+
+func (p *parser) parseConstraintTerm() ast.Expr {
+	if p.tok != token.TILDE {
+		return p.parseType()
+	}
+	pos := p.pos
+	p.next()
+	return &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: p.parseType()}
+}
+
+// after 'type Name [' parse either the first parameter name of a generic type,
+// or the length of an array type. A nil length means a slice type
+func (p *parser) parseTypeParamOrArrayLen() (*ast.Ident, ast.Expr) {
+	switch p.tok {
+	case token.RBRACK:
+		return nil, nil
+	case token.ELLIPSIS:
+		len := &ast.Ellipsis{Ellipsis: p.pos}
+		p.next()
+		return nil, len
+	case token.IDENT:
+		break
+	default:
+		p.exprLev++
+		len := p.parseRhs()
+		p.exprLev--
+		return nil, len
+	}
+	name := p.parseIdent()
+	switch p.tok {
+	case token.COMMA, token.IDENT, token.TILDE, token.INTERFACE, token.MAP, token.CHAN, token.FUNC, token.STRUCT:
+		// name is followed by another parameter name or by a constraint
+		return name, nil
+	}
+	// name is the beginning of the array length
+	p.resolve(name)
+	p.exprLev++
+	old := p.inRhs
+	p.inRhs = true
+	len := p.parseBinaryExprFrom(p.parsePrimaryExprFrom(name, false), false, token.LowestPrec+1)
+	p.inRhs = old
+	p.exprLev--
+	return nil, p.checkExpr(len)
+}
+
+// in parameter and field lists, parse either a type or a name followed by an array type:
+// 'a [N]T' declares a of type [N]T, while 'List[T]' instantiates the generic type List.
+// Returns the name or type, and the array type if found
+func (p *parser) parseVarTypeOrArrayField(isParam bool) (ast.Expr, ast.Expr) {
+	if p.tok != token.IDENT {
+		return p.parseVarType(isParam), nil
+	}
+	x := p.parseTypeName()
+	switch p.tok {
+	case mt.HASH:
+		// parse Foo#[T1,T2...]
+		return p.parseHash(x), nil
+	case token.LBRACK:
+		break
+	default:
+		return x, nil
+	}
+	lbrack := p.expect(token.LBRACK)
+	switch p.tok {
+	case token.RBRACK:
+		return x, p.parseArrayTypeFrom(lbrack, nil)
+	case token.ELLIPSIS:
+		len := &ast.Ellipsis{Ellipsis: p.pos}
+		p.next()
+		return x, p.parseArrayTypeFrom(lbrack, len)
+	}
+	p.exprLev++
+	args := p.parseTypeArgs()
+	p.exprLev--
+	rbrack := p.expect(token.RBRACK)
+	if len(args) == 1 {
+		if elt := p.tryType(); elt != nil {
+			return x, &ast.ArrayType{Lbrack: lbrack, Len: args[0], Elt: elt}
+		}
+	}
+	return typeInstance(x, lbrack, args, rbrack), nil
+}
+
+// parse [T1, T2...] after the type name x, i.e. the instantiation x[T1, T2...] of a generic type
+func (p *parser) parseTypeInstance(x ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "TypeInstance"))
+	}
+	lbrack := p.expect(token.LBRACK)
+	p.exprLev++
+	args := p.parseTypeArgs()
+	p.exprLev--
+	rbrack := p.expect(token.RBRACK)
+	return typeInstance(x, lbrack, args, rbrack)
+}
+
+// parse T1, T2... inside [] of a generic type or function instantiation
+func (p *parser) parseTypeArgs() []ast.Expr {
+	var list []ast.Expr
+	if p.tok == token.RBRACK {
+		p.errorExpected(p.pos, "type argument")
+		return append(list, &ast.BadExpr{From: p.pos, To: p.pos})
+	}
+	list = append(list, p.parseRhsOrType())
+	for p.tok == token.COMMA {
+		p.next()
+		if p.tok == token.RBRACK {
+			break
+		}
+		list = append(list, p.parseRhsOrType())
+	}
+	return list
+}
+
+// use the same representation as parseIndexOrSlice()
+// for the expressions x[T] and x[T1, T2...]
+func typeInstance(x ast.Expr, lbrack token.Pos, args []ast.Expr, rbrack token.Pos) *ast.IndexExpr {
+	var index ast.Expr
+	if len(args) == 1 {
+		index = args[0]
+	} else {
+		index = &ast.CompositeLit{Lbrace: lbrack, Elts: args, Rbrace: rbrack}
+	}
+	return &ast.IndexExpr{X: x, Lbrack: lbrack, Index: index, Rbrack: rbrack}
+}
+
+// convert the type parameters of a generic function or type to template parameters
+func typeParamNames(tparams *ast.FieldList) *ast.CompositeLit {
+	var names []ast.Expr
+	for _, field := range tparams.List {
+		for _, name := range field.Names {
+			names = append(names, name)
+		}
+	}
+	return &ast.CompositeLit{Lbrace: tparams.Opening, Elts: names, Rbrace: tparams.Closing}
+}
@@ -282,6 +282,19 @@ func (s *Scanner) scanIdentifier() string {
 	return string(s.src[offs:s.offset])
 }
 
+// patch: return the ASCII identifier starting at the current character, without consuming it
+func (s *Scanner) peekIdentifier() string {
+	end := s.offset
+	for end < len(s.src) {
+		ch := s.src[end]
+		if !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || '0' <= ch && ch <= '9') {
+			break
+		}
+		end++
+	}
+	return string(s.src[s.offset:end])
+}
+
 func digitVal(ch rune) int {
 	switch {
 	case '0' <= ch && ch <= '9':
@@ -797,6 +810,11 @@ scanAgain:
 					tok = mt.UNQUOTE
 				}
 			default:
+				if s.macroChar == '~' && mt.LookupSpecial(s.peekIdentifier()) == token.ILLEGAL {
+					// patch: ~T approximation element in a type constraint
+					tok = token.TILDE
+					break
+				}
 				lit = s.scanIdentifier()
 				tok = mt.LookupSpecial(lit)
 				if tok == token.ILLEGAL {
@@ -804,6 +822,9 @@ scanAgain:
 					insertSemi = s.insertSemi // preserve insertSemi info
 				}
 			}
+		case '~':
+			// patch: ~T approximation element in a type constraint, when s.macroChar is not '~'
+			tok = token.TILDE
 		default:
 			// next reports unexpected BOMs - don't repeat
 			if ch != bom {
@@ -95,6 +95,7 @@ func (f *File) SetSourceForContent(content []byte) {
 //
 type FileSet struct {
 	token.FileSet
+	mutex   sync.RWMutex // protects filemap: forked interpreters share the FileSet
 	filemap map[*token.File]*File
 }
 
@@ -110,7 +111,9 @@ func NewFileSet() *FileSet {
 func (s *FileSet) AddFile(filename string, base, size, line int) *File {
 	innerf := s.FileSet.AddFile(filename, base, size)
 	f := &File{File: innerf, line: line}
+	s.mutex.Lock()
 	s.filemap[innerf] = f
+	s.mutex.Unlock()
 	return f
 }
 
@@ -121,7 +124,9 @@ func (s *FileSet) AddFile(filename string, base, size, line int) *File {
 func (s *FileSet) File(p token.Pos) (f *File) {
 	if p != token.NoPos {
 		innerf := s.FileSet.File(p)
+		s.mutex.RLock()
 		f = s.filemap[innerf]
+		s.mutex.RUnlock()
 	}
 	return
 }
@@ -28,8 +28,20 @@ func IsEmulatedInterface(t Type) bool {
 	return xt.kind == reflect.Interface && xt.rtype.Kind() == reflect.Ptr
 }
 
-// extract the concrete value and type contained in an emulated interface
+// IsEmulatedInterfaceReflectType returns true if rtype is the reflect.Type of an emulated interface
+func IsEmulatedInterfaceReflectType(rtype reflect.Type) bool {
+	return rtype.Kind() == reflect.Ptr && isReflectInterfaceStruct(rtype.Elem())
+}
+
+// extract the concrete value and type contained in an emulated interface.
+// returns the zero reflect.Value if the emulated interface is nil
 func FromEmulatedInterface(v reflect.Value) (reflect.Value, Type) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Value{}, nil
+	}
 	h := v.Elem().Field(0).Interface().(InterfaceHeader)
 	return h.val, h.typ
 }
@@ -80,6 +80,15 @@ func (t *xtype) SetUnderlying(underlying Type) {
 		gtype.SetUnderlying(gunderlying)
 		// debugf("SetUnderlying: updated <%v> reflect Type from <%v> to <%v>", gtype, t.rtype, underlying.ReflectType())
 		t.rtype = underlying.ReflectType()
+		if t.kind == reflect.Struct {
+			// replace xreflect.Forward with references to the type itself, if possible
+			if rtype := v.recursiveStructOf(t); rtype != nil {
+				t.rtype = rtype
+				if !tunderlying.Named() {
+					tunderlying.rtype = rtype
+				}
+			}
+		}
 		if t.kind == reflect.Interface {
 			// propagate methodvalues from underlying interface to named type
 			t.methodvalues = tunderlying.methodvalues
@@ -138,7 +147,9 @@ func (t *xtype) AddMethod(name string, signature Type) int {
 	for len(t.methodvalues) < n2 {
 		t.methodvalues = append(t.methodvalues, nilv)
 	}
-	t.methodvalues[index] = nilv
+	// the method value is set later, when the method declaration is executed.
+	// until then, a nil function of the right type allows compiling calls to the method
+	t.methodvalues[index] = reflect.Zero(signature.ReflectType())
 	if n1 == n2 {
 		// an existing method was overwritten.
 		// it may be cached in some other type's method cache.
@@ -167,11 +178,20 @@ func (t *xtype) RemoveMethods(names []string, pkgpath string) {
 	}
 }
 
-// internal representation of go/types.Named
-type unsafeNamed struct {
-	obj        *types.TypeName
-	underlying types.Type
-	methods    []*types.Func
+// offset of the unexported field go/types.Named.methods:
+// its position changes across Go versions, so find it with reflection
+var unsafeNamedMethodsOffset = func() uintptr {
+	field, ok := reflect.TypeOf(types.Named{}).FieldByName("methods")
+	if !ok || field.Type != reflect.TypeOf([]*types.Func{}) {
+		panic("xreflect: unsupported go/types.Named layout, cannot find field 'methods []*types.Func'")
+	}
+	return field.Offset
+}()
+
+// return the address of the unexported field gtype.methods
+func unsafeNamedMethods(gtype *types.Named) *[]*types.Func {
+	gtype.NumMethods() // resolve lazily loaded methods
+	return (*[]*types.Func)(unsafe.Pointer(uintptr(unsafe.Pointer(gtype)) + unsafeNamedMethodsOffset))
 }
 
 // patched version of go/types.Named.AddMethod() that *overwrites* matching methods
@@ -180,28 +200,28 @@ func unsafeAddMethod(gtype *types.Named, gfun *types.Func) int {
 	if gfun.Name() == "_" {
 		return -1
 	}
-	gt := (*unsafeNamed)(unsafe.Pointer(gtype))
+	methods := unsafeNamedMethods(gtype)
 	qname := QNameGo(gfun)
-	for i, m := range gt.methods {
+	for i, m := range *methods {
 		if qname == QNameGo(m) {
-			gt.methods[i] = gfun
+			(*methods)[i] = gfun
 			return i
 		}
 	}
-	gt.methods = append(gt.methods, gfun)
-	return len(gt.methods) - 1
+	*methods = append(*methods, gfun)
+	return len(*methods) - 1
 }
 
 func unsafeRemoveMethods(gtype *types.Named, names []string, pkgpath string) {
 	names = append([]string{}, names...) // make a copy
 	sort.Strings(names)                  // and sort it
 
-	gt := (*unsafeNamed)(unsafe.Pointer(gtype))
+	methods := unsafeNamedMethods(gtype)
 
-	n1 := len(gt.methods)
+	n1 := len(*methods)
 	n2 := n1
 	for i, j := 0, 0; i < n1; i++ {
-		m := gt.methods[i]
+		m := (*methods)[i]
 		name := m.Name()
 		pos := sort.SearchStrings(names, name)
 		if pos < len(names) && names[pos] == name && (m.Exported() || m.Pkg().Path() == pkgpath) {
@@ -210,12 +230,12 @@ func unsafeRemoveMethods(gtype *types.Named, names []string, pkgpath string) {
 			continue
 		}
 		if i != j {
-			gt.methods[j] = gt.methods[i]
+			(*methods)[j] = (*methods)[i]
 		}
 		j++
 	}
 	if n1 != n2 {
-		gt.methods = gt.methods[:n2]
+		*methods = (*methods)[:n2]
 	}
 }
 
@@ -0,0 +1,313 @@
+/*
+ * gomacro - A Go interpreter with Lisp-like macros
+ *
+ * Copyright (C) 2017-2018 Massimiliano Ghilardi
+ *
+ *     This Source Code Form is subject to the terms of the Mozilla Public
+ *     License, v. 2.0. If a copy of the MPL was not distributed with this
+ *     file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ *
+ *
+ * recursive.go
+ *
+ *  Created on: Oct 19, 2026
+ *      Author: Massimiliano Ghilardi
+ */
+
+package xreflect
+
+import (
+	"encoding/binary"
+	"go/types"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// reflect.StructOf() cannot create self-referencing types,
+// as for example type List struct { Elem int; Rest *List }
+//
+// We create them in two steps: first, reflect.StructOf() is invoked
+// replacing the references to the type itself with references to a placeholder.
+// Then the fields containing the placeholder are patched in place
+// to point to the struct type just created.
+// This is possible only because the placeholder appears behind pointers,
+// slices, maps or channels, whose memory layout does not depend on the element type.
+//
+// Patching requires knowing the memory layout of reflect internal types,
+// which is verified once at startup: if it does not match,
+// self-referencing types fall back to xreflect.Forward
+//
+// The string of the struct type, as shown by reflect.Type.String() and fmt "%T",
+// is computed by reflect.StructOf() and would mention the placeholder:
+// it is replaced too, before creating any other type that contains the struct
+
+type placeholder struct{}
+
+var rTypeOfPlaceholder = reflect.TypeOf(placeholder{})
+
+// layout of reflect internal struct types:
+// type structType struct { rtype; pkgPath name; fields []structField }
+// type structField struct { name name; typ *rtype; offset uintptr }
+const (
+	ptrSize            = unsafe.Sizeof(uintptr(0))
+	structFieldSize    = 3 * ptrSize
+	structFieldTypeOff = ptrSize
+)
+
+var structFieldsOff = reflect.TypeOf(reflect.TypeOf(0)).Elem().Size() + ptrSize
+
+// layout of reflect internal types:
+// type rtype struct { size, ptrdata uintptr; hash uint32; tflag, align, fieldAlign, kind uint8;
+//                     equal func(...) bool; gcdata *byte; str nameOff; ptrToThis typeOff }
+const rtypeStrOff = 4*ptrSize + 8
+
+// registers a pointer in the runtime and returns the offset that refers to it
+//
+//go:linkname addReflectOff reflect.addReflectOff
+func addReflectOff(ptr unsafe.Pointer) int32
+
+// newReflectName returns the offset of a new reflect internal name containing str
+func newReflectName(str string) int32 {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(str)))
+	b := make([]byte, 1+n+len(str)) // flags, varint length, bytes
+	copy(b[1:], buf[:n])
+	copy(b[1+n:], str)
+	return addReflectOff(unsafe.Pointer(&b[0]))
+}
+
+// setTypeString changes the string of a type created by reflect, as a struct type from reflect.StructOf().
+// returns false if the layout of reflect internal types is not the expected one
+func setTypeString(rtype reflect.Type, str string) bool {
+	p := (*int32)(unsafe.Pointer(uintptr(rtypePointer(rtype)) + rtypeStrOff))
+	old := *p
+	*p = newReflectName(str)
+	if rtype.String() != str {
+		*p = old
+		return false
+	}
+	return true
+}
+
+// return the address of reflect internal type descriptor
+func rtypePointer(rtype reflect.Type) unsafe.Pointer {
+	return (*[2]unsafe.Pointer)(unsafe.Pointer(&rtype))[1]
+}
+
+// return the address of the type of i-th field in reflect internal struct type descriptor.
+// returns nil if the layout is not the expected one
+func unsafeStructFieldType(rtype reflect.Type, i int) *unsafe.Pointer {
+	fields := (*struct {
+		data     unsafe.Pointer
+		len, cap int
+	})(unsafe.Pointer(uintptr(rtypePointer(rtype)) + structFieldsOff))
+	if fields.len != rtype.NumField() || i < 0 || i >= fields.len {
+		return nil
+	}
+	p := (*unsafe.Pointer)(unsafe.Pointer(uintptr(fields.data) + uintptr(i)*structFieldSize + structFieldTypeOff))
+	if *p != rtypePointer(rtype.Field(i).Type) {
+		return nil
+	}
+	return p
+}
+
+// true if the memory layout of reflect internal struct types is the expected one
+var canPatchStructs = func() bool {
+	rtype := reflect.StructOf([]reflect.StructField{
+		{Name: "A", Type: reflect.TypeOf(int8(0))},
+		{Name: "B", Type: reflect.TypeOf((*string)(nil))},
+	})
+	return unsafeStructFieldType(rtype, 0) != nil && unsafeStructFieldType(rtype, 1) != nil
+}()
+
+// recursiveStructOf returns a self-referencing reflect.Type for the named struct type t,
+// or nil if t does not reference itself or if creating such reflect.Type is not possible
+func (v *Universe) recursiveStructOf(t *xtype) (ret reflect.Type) {
+	gnamed, ok := t.gtype.(*types.Named)
+	if !ok || !canPatchStructs || t.kind != reflect.Struct || t.rtype.Kind() != reflect.Struct ||
+		len(t.rtype.Name()) != 0 {
+		// not a struct, or a compiled named type: its reflect.Type is already correct
+		return nil
+	}
+	defer func() {
+		// reflect.StructOf() may panic on anonymous fields
+		if recover() != nil {
+			ret = nil
+		}
+	}()
+	gstruct := gnamed.Underlying().(*types.Struct)
+	n := gstruct.NumFields()
+	if n != t.rtype.NumField() {
+		return nil
+	}
+	rfields := make([]reflect.StructField, n)
+	patch := make([]bool, n)
+	found := false
+	for i := range rfields {
+		rfields[i] = t.rtype.Field(i)
+		gfield := gstruct.Field(i).Type()
+		if !containsType(gfield, gnamed) {
+			continue
+		}
+		rtype := v.placeholderOf(gfield, gnamed, false)
+		if rtype == nil {
+			return nil
+		}
+		rfields[i].Type = rtype
+		patch[i] = true
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	rstruct := reflect.StructOf(rfields)
+	// also types created below from rstruct, as *rstruct, copy its string
+	if !setTypeString(rstruct, strings.Replace(rstruct.String(), rTypeOfPlaceholder.String(), qualifiedName(gnamed), -1)) {
+		return nil
+	}
+	ptypes := make([]*unsafe.Pointer, n)
+	rtypes := make([]reflect.Type, n)
+	for i := range rfields {
+		if !patch[i] {
+			continue
+		}
+		rtype := replacePlaceholder(rfields[i].Type, rstruct)
+		p := unsafeStructFieldType(rstruct, i)
+		if rtype == nil || p == nil || rtype.Size() != rfields[i].Type.Size() || rtype.Kind() != rfields[i].Type.Kind() {
+			return nil
+		}
+		ptypes[i], rtypes[i] = p, rtype
+	}
+	// patch only after all checks succeeded
+	for i, p := range ptypes {
+		if p != nil {
+			*p = rtypePointer(rtypes[i])
+		}
+	}
+	return rstruct
+}
+
+// return the name of gnamed as shown by reflect, as main.List
+func qualifiedName(gnamed *types.Named) string {
+	obj := gnamed.Obj()
+	if pkg := obj.Pkg(); pkg != nil {
+		return pkg.Name() + "." + obj.Name()
+	}
+	return obj.Name()
+}
+
+// return true if gtype contains gnamed
+func containsType(gtype types.Type, gnamed *types.Named) bool {
+	return containsType2(gtype, gnamed, 0)
+}
+
+func containsType2(gtype types.Type, gnamed *types.Named, depth int) bool {
+	if depth > 64 {
+		return false
+	}
+	depth++
+	switch g := gtype.(type) {
+	case *types.Named:
+		return g == gnamed
+	case *types.Pointer:
+		return containsType2(g.Elem(), gnamed, depth)
+	case *types.Slice:
+		return containsType2(g.Elem(), gnamed, depth)
+	case *types.Array:
+		return containsType2(g.Elem(), gnamed, depth)
+	case *types.Chan:
+		return containsType2(g.Elem(), gnamed, depth)
+	case *types.Map:
+		return containsType2(g.Key(), gnamed, depth) || containsType2(g.Elem(), gnamed, depth)
+	case *types.Struct:
+		for i, n := 0, g.NumFields(); i < n; i++ {
+			if containsType2(g.Field(i).Type(), gnamed, depth) {
+				return true
+			}
+		}
+	case *types.Signature:
+		for _, tuple := range [...]*types.Tuple{g.Params(), g.Results()} {
+			for i, n := 0, tuple.Len(); i < n; i++ {
+				if containsType2(tuple.At(i).Type(), gnamed, depth) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// return the reflect.Type of gtype, where gnamed is replaced by placeholder.
+// returns nil if gnamed appears without indirection, or in positions not supported
+func (v *Universe) placeholderOf(gtype types.Type, gnamed *types.Named, indirect bool) reflect.Type {
+	if gtype == gnamed {
+		if !indirect {
+			return nil
+		}
+		return rTypeOfPlaceholder
+	}
+	if !containsType(gtype, gnamed) {
+		if t, ok := v.gmap.At(gtype).(Type); ok && t.ReflectType() != rTypeOfForward {
+			return t.ReflectType()
+		}
+		return nil
+	}
+	var elem, key reflect.Type
+	switch g := gtype.(type) {
+	case *types.Pointer:
+		if elem = v.placeholderOf(g.Elem(), gnamed, true); elem != nil {
+			return reflect.PtrTo(elem)
+		}
+	case *types.Slice:
+		if elem = v.placeholderOf(g.Elem(), gnamed, true); elem != nil {
+			return reflect.SliceOf(elem)
+		}
+	case *types.Array:
+		if elem = v.placeholderOf(g.Elem(), gnamed, indirect); elem != nil {
+			return reflect.ArrayOf(int(g.Len()), elem)
+		}
+	case *types.Chan:
+		if elem = v.placeholderOf(g.Elem(), gnamed, true); elem != nil {
+			return reflect.ChanOf(gdirToDir(g.Dir()), elem)
+		}
+	case *types.Map:
+		key = v.placeholderOf(g.Key(), gnamed, true)
+		elem = v.placeholderOf(g.Elem(), gnamed, true)
+		if key != nil && elem != nil {
+			return reflect.MapOf(key, elem)
+		}
+	}
+	return nil
+}
+
+// return rtype where placeholder is replaced by rstruct.
+// returns nil if the result is not a valid type, as a map with non-comparable keys
+func replacePlaceholder(rtype reflect.Type, rstruct reflect.Type) (ret reflect.Type) {
+	defer func() {
+		if recover() != nil {
+			ret = nil
+		}
+	}()
+	return replacePlaceholder2(rtype, rstruct)
+}
+
+func replacePlaceholder2(rtype reflect.Type, rstruct reflect.Type) reflect.Type {
+	switch rtype.Kind() {
+	case reflect.Ptr:
+		return reflect.PtrTo(replacePlaceholder2(rtype.Elem(), rstruct))
+	case reflect.Slice:
+		return reflect.SliceOf(replacePlaceholder2(rtype.Elem(), rstruct))
+	case reflect.Array:
+		return reflect.ArrayOf(rtype.Len(), replacePlaceholder2(rtype.Elem(), rstruct))
+	case reflect.Chan:
+		return reflect.ChanOf(rtype.ChanDir(), replacePlaceholder2(rtype.Elem(), rstruct))
+	case reflect.Map:
+		return reflect.MapOf(replacePlaceholder2(rtype.Key(), rstruct), replacePlaceholder2(rtype.Elem(), rstruct))
+	case reflect.Struct:
+		if rtype == rTypeOfPlaceholder {
+			return rstruct
+		}
+	}
+	return rtype
+}
@@ -22,6 +22,7 @@ import (
 	"go/token"
 	"go/types"
 	"reflect"
+	"strings"
 )
 
 // Field returns a struct type's i'th field.
@@ -72,7 +73,7 @@ func (t *xtype) field(i int) StructField {
 		Name:      va.Name(),
 		Pkg:       (*Package)(va.Pkg()),
 		Type:      t.universe.maketype(va.Type(), rf.Type), // lock already held
-		Tag:       rf.Tag,
+		Tag:       reflect.StructTag(gtype.Tag(i)),         // rf.Tag may contain json:"-" added by toReflectField()
 		Offset:    rf.Offset,
 		Index:     rf.Index,
 		Anonymous: va.Anonymous(),
@@ -95,14 +96,20 @@ func (field *StructField) toReflectField(forceExported bool) reflect.StructField
 		pkgpath = pkg.Path()
 	}
 	name := field.Name
+	tag := field.Tag
 	if forceExported {
 		name = toExportedFieldName(name, field.Type, field.Anonymous)
+		if strings.HasPrefix(name, StrGensymPrivate) || strings.HasPrefix(name, StrGensymAnonymous) {
+			// field is exported only because reflect.StructOf() requires it:
+			// hide it from encoding/json, which ignores unexported fields
+			tag = hideFromJSON(tag)
+		}
 	}
 	return reflect.StructField{
 		Name:    name,
 		PkgPath: pkgpath,
 		Type:    field.Type.ReflectType(),
-		Tag:     field.Tag,
+		Tag:     tag,
 		Offset:  field.Offset,
 		Index:   field.Index,
 		// reflect.StructOf() has very limited support for anonymous fields,
@@ -111,6 +118,15 @@ func (field *StructField) toReflectField(forceExported bool) reflect.StructField
 	}
 }
 
+// prepend json:"-" to tag. reflect.StructTag.Get() returns the first match,
+// so it also overrides any json key already present
+func hideFromJSON(tag reflect.StructTag) reflect.StructTag {
+	if len(tag) == 0 {
+		return `json:"-"`
+	}
+	return `json:"-" ` + tag
+}
+
 func toReflectFields(fields []StructField, forceExported bool) []reflect.StructField {
 	rfields := make([]reflect.StructField, len(fields))
 	for i := range fields {
@@ -123,11 +139,7 @@ func (field *StructField) sanitize(i int) {
 	if len(field.Name) != 0 {
 		return
 	}
-	t := field.Type
-	name := t.Name()
-	if len(name) == 0 && t.Kind() == reflect.Ptr {
-		name = t.elem().Name()
-	}
+	name := embeddedName(field.Type)
 	if len(name) == 0 {
 		name = fmt.Sprintf("%s%d", StrGensymAnonymous, i)
 	}
@@ -160,11 +172,22 @@ func toTags(fields []StructField) []string {
 	return tags
 }
 
+// return the name of an embedded field of type t or *t.
+// For template instances as List#[int], it is the template name List
+func embeddedName(t Type) string {
+	name := t.Name()
+	if len(name) == 0 && t.Kind() == reflect.Ptr {
+		name = t.elem().Name()
+	}
+	if i := strings.IndexByte(name, '#'); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
 func toExportedFieldName(name string, t Type, anonymous bool) string {
 	if len(name) == 0 && unwrap(t) != nil {
-		if name = t.Name(); len(name) == 0 && t.Kind() == reflect.Ptr {
-			name = t.elem().Name()
-		}
+		name = embeddedName(t)
 	}
 	if !ast.IsExported(name) {
 		if anonymous {
@@ -52,9 +52,8 @@ type Universe struct {
 }
 
 func lock(v *Universe) *Universe {
-	if v.debugmutex != 0 {
-		errorf(nil, "deadlocking universe %p", v)
-	}
+	// do not check v.debugmutex before locking: if ThreadSafe,
+	// other goroutines may legitimately hold the lock
 	v.mutex.Lock()
 	v.debugmutex++
 	return v
@@ -62,8 +61,8 @@ func lock(v *Universe) *Universe {
 
 func un(v *Universe) {
 	// debugf("unlocking universe %p", v)
-	v.mutex.Unlock()
 	v.debugmutex--
+	v.mutex.Unlock()
 }
 
 func (v *Universe) rebuild() bool {
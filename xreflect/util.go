@@ -62,6 +62,19 @@ func dirToGdir(dir reflect.ChanDir) types.ChanDir {
 	return gdir
 }
 
+func gdirToDir(gdir types.ChanDir) reflect.ChanDir {
+	var dir reflect.ChanDir
+	switch gdir {
+	case types.RecvOnly:
+		dir = reflect.RecvDir
+	case types.SendOnly:
+		dir = reflect.SendDir
+	case types.SendRecv:
+		dir = reflect.BothDir
+	}
+	return dir
+}
+
 func gtypeToKind(t *xtype, gtype types.Type) reflect.Kind {
 	gtype = gtype.Underlying()
 	var kind reflect.Kind